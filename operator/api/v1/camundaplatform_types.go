@@ -0,0 +1,150 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CamundaPlatformSpec defines the desired state of CamundaPlatform. Every
+// section is turned into a resource of its own, named after the platform, which
+// the operator keeps in line with the section.
+type CamundaPlatformSpec struct {
+	// Version of the platform. Components whose section names no image tag run
+	// this version, except Optimize, which runs the version that can import the
+	// records of the brokers. Defaults to the image tag of the brokers.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Zeebe cluster of the platform
+	Zeebe ZeebeSpec `json:"zeebe"`
+
+	// Elasticsearch or OpenSearch of the platform. An exporter to it is added to
+	// the brokers, unless they already have an Elasticsearch or OpenSearch
+	// exporter.
+	// +optional
+	SecondaryStorage *SecondaryStorageSpec `json:"secondaryStorage,omitempty"`
+
+	// Identity of the platform. The gateway, Operate, Tasklist and Optimize
+	// authenticate their users with it.
+	// +optional
+	Identity *IdentitySpec `json:"identity,omitempty"`
+
+	// Operate of the platform, not deployed if unset
+	// +optional
+	Operate *PlatformWebAppSpec `json:"operate,omitempty"`
+
+	// Tasklist of the platform, not deployed if unset
+	// +optional
+	Tasklist *PlatformWebAppSpec `json:"tasklist,omitempty"`
+
+	// Optimize of the platform, not deployed if unset
+	// +optional
+	Optimize *PlatformWebAppSpec `json:"optimize,omitempty"`
+}
+
+// PlatformWebAppSpec configures a web app of the platform, which connects to
+// the Zeebe cluster and the Identity of the platform
+type PlatformWebAppSpec struct {
+	// Name of the Elasticsearch or OpenSearch exporter of the brokers whose
+	// records the web app imports. Defaults to the first such exporter.
+	// +optional
+	Exporter string `json:"exporter,omitempty"`
+
+	// Image, replicas, resources and environment of the web app
+	// +optional
+	Backend BackendSpec `json:"backend,omitempty"`
+
+	// Exposes the web app through an Ingress, if set
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+// PlatformPhase is a simple, high-level summary of all components
+// +kubebuilder:validation:Enum=Pending;Running;Degraded
+type PlatformPhase string
+
+const (
+	// PlatformPhasePending means some components are not ready yet
+	PlatformPhasePending PlatformPhase = "Pending"
+	// PlatformPhaseRunning means all components are ready
+	PlatformPhaseRunning PlatformPhase = "Running"
+	// PlatformPhaseDegraded means a component or the platform itself could not
+	// be reconciled
+	PlatformPhaseDegraded PlatformPhase = "Degraded"
+)
+
+// Condition types of a CamundaPlatform. Every component adds a condition of
+// its own, named after its kind, like ZeebeReady.
+const (
+	// PlatformConditionReady is true once all components are ready
+	PlatformConditionReady = "Ready"
+	// PlatformConditionDegraded is true if a component is degraded
+	PlatformConditionDegraded = "Degraded"
+)
+
+// CamundaPlatformStatus defines the observed state of CamundaPlatform
+type CamundaPlatformStatus struct {
+	// Generation of the spec the status was computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	Phase PlatformPhase `json:"phase,omitempty"`
+
+	// Version the components are asked to run
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Ready and degraded state of the platform, and the readiness of every
+	// component taken from its own status
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CamundaPlatform is the Schema for the camundaplatforms API. It composes a
+// Zeebe cluster with the components around it into one resource.
+type CamundaPlatform struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CamundaPlatformSpec   `json:"spec,omitempty"`
+	Status CamundaPlatformStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CamundaPlatformList contains a list of CamundaPlatform
+type CamundaPlatformList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CamundaPlatform `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CamundaPlatform{}, &CamundaPlatformList{})
+}
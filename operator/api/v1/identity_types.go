@@ -0,0 +1,110 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// IdentitySpec defines the desired state of Identity
+type IdentitySpec struct {
+	// Keycloak which holds the users and the OAuth clients of the components
+	// +optional
+	Keycloak KeycloakSpec `json:"keycloak,omitempty"`
+
+	// Image, replicas, resources and environment of Identity. The image defaults
+	// to camunda/identity.
+	// +optional
+	Backend BackendSpec `json:"backend,omitempty"`
+
+	// Exposes the web app through an Ingress, if set
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+// KeycloakSpec defines the Keycloak Identity sets up the realm and the OAuth
+// clients of the components in
+type KeycloakSpec struct {
+	// URL of an existing Keycloak including its context path, like
+	// http://keycloak/auth. The operator runs a Keycloak in development mode,
+	// which does not persist its data, if unset.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// URL the browsers of the users reach Keycloak at, which is part of the
+	// issuer URL of the tokens. Defaults to the Ingress of the Keycloak run by the
+	// operator, or to the URL otherwise.
+	// +optional
+	PublicURL string `json:"publicUrl,omitempty"`
+
+	// Secret keys holding the Keycloak admin user Identity sets up Keycloak with.
+	// Required for an existing Keycloak. The operator generates the admin user of
+	// the Keycloak it runs if unset.
+	// +optional
+	Admin *BasicAuthentication `json:"admin,omitempty"`
+
+	// Image of the Keycloak run by the operator
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Exposes the Keycloak run by the operator through an Ingress, if set
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+// IdentityStatus defines the observed state of Identity
+type IdentityStatus struct {
+	WebAppStatus `json:",inline"`
+
+	// Issuer URL of the tokens of the users and the components
+	// +optional
+	IssuerURL string `json:"issuerUrl,omitempty"`
+
+	// Name of the generated Secret holding the secrets of the OAuth clients,
+	// keyed by component
+	// +optional
+	ClientSecretName string `json:"clientSecretName,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Issuer",type=string,JSONPath=`.status.issuerUrl`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Identity is the Schema for the identities API
+type Identity struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IdentitySpec   `json:"spec,omitempty"`
+	Status IdentityStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IdentityList contains a list of Identity
+type IdentityList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Identity `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Identity{}, &IdentityList{})
+}
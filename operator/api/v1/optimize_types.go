@@ -0,0 +1,103 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OptimizeSpec defines the desired state of Optimize
+type OptimizeSpec struct {
+	// Name of the Zeebe cluster in the same namespace whose records Optimize
+	// imports
+	// +kubebuilder:validation:MinLength=1
+	ZeebeRef string `json:"zeebeRef"`
+
+	// Name of the Elasticsearch or OpenSearch exporter of the Zeebe cluster whose
+	// records Optimize imports. Optimize keeps its own indices in the same
+	// Elasticsearch or OpenSearch. Defaults to the first such exporter.
+	// +optional
+	Exporter string `json:"exporter,omitempty"`
+
+	// Image, replicas, resources and environment of Optimize. The image defaults
+	// to camunda/optimize with the first release of the minor version which is
+	// compatible with the brokers. An image tag which is a release version must
+	// be compatible with the brokers as well.
+	// +optional
+	Backend BackendSpec `json:"backend,omitempty"`
+
+	// Identity provider users log in with, if set
+	// +optional
+	Identity *OptimizeIdentity `json:"identity,omitempty"`
+
+	// Exposes the web app through an Ingress, if set
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+// OptimizeIdentity defines the OAuth client Optimize authenticates users with
+type OptimizeIdentity struct {
+	// URL of the token issuer as seen by the browsers of the users
+	// +kubebuilder:validation:MinLength=1
+	IssuerURL string `json:"issuerUrl"`
+
+	// URL of the token issuer as seen by Optimize, defaults to the issuer URL
+	// +optional
+	IssuerBackendURL string `json:"issuerBackendUrl,omitempty"`
+
+	// Id of the OAuth client of Optimize
+	// +kubebuilder:validation:MinLength=1
+	ClientID string `json:"clientId"`
+
+	// Secret key holding the secret of the OAuth client
+	ClientSecret v1.SecretKeySelector `json:"clientSecret"`
+
+	// Audience of the tokens Optimize accepts
+	// +optional
+	Audience string `json:"audience,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Zeebe",type=string,JSONPath=`.spec.zeebeRef`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Optimize is the Schema for the optimizes API
+type Optimize struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   OptimizeSpec `json:"spec,omitempty"`
+	Status WebAppStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// OptimizeList contains a list of Optimize
+type OptimizeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Optimize `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Optimize{}, &OptimizeList{})
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecondaryStorageType is the search engine of a secondary storage
+// +kubebuilder:validation:Enum=Elasticsearch;OpenSearch
+type SecondaryStorageType string
+
+const (
+	SecondaryStorageElasticsearch SecondaryStorageType = "Elasticsearch"
+	SecondaryStorageOpenSearch    SecondaryStorageType = "OpenSearch"
+)
+
+// SecondaryStorageSpec defines the desired state of SecondaryStorage
+type SecondaryStorageSpec struct {
+	// Search engine of the storage
+	Type SecondaryStorageType `json:"type"`
+
+	// Existing cluster to publish. The operator runs the storage itself if unset.
+	// +optional
+	External *ExternalSecondaryStorage `json:"external,omitempty"`
+
+	// Image, replicas, resources and environment of the nodes run by the
+	// operator. The image defaults to the official image of the search engine.
+	// +optional
+	Backend BackendSpec `json:"backend,omitempty"`
+
+	// Data volumes of the nodes run by the operator
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+}
+
+// ExternalSecondaryStorage is an Elasticsearch or OpenSearch cluster which runs
+// outside of the operator
+type ExternalSecondaryStorage struct {
+	// URL of the cluster, like http://elasticsearch:9200
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// Credentials for basic authentication, no authentication is used if not set
+	// +optional
+	Authentication *BasicAuthentication `json:"authentication,omitempty"`
+
+	// Secret key holding the CA certificate in PEM format which signed the
+	// certificate of the cluster
+	// +optional
+	CACertificate *v1.SecretKeySelector `json:"caCertificate,omitempty"`
+}
+
+// SecondaryStoragePhase is a simple, high-level summary of the storage
+// +kubebuilder:validation:Enum=Pending;Running;Degraded
+type SecondaryStoragePhase string
+
+const (
+	// SecondaryStoragePhasePending means the nodes are not all ready yet
+	SecondaryStoragePhasePending SecondaryStoragePhase = "Pending"
+	// SecondaryStoragePhaseRunning means the endpoint is published and serves
+	SecondaryStoragePhaseRunning SecondaryStoragePhase = "Running"
+	// SecondaryStoragePhaseDegraded means the storage could not be reconciled
+	SecondaryStoragePhaseDegraded SecondaryStoragePhase = "Degraded"
+)
+
+// Condition types of a SecondaryStorage
+const (
+	// SecondaryStorageConditionReady is true once all nodes are ready
+	SecondaryStorageConditionReady = "Ready"
+	// SecondaryStorageConditionDegraded is true if the storage could not be
+	// reconciled
+	SecondaryStorageConditionDegraded = "Degraded"
+)
+
+// SecondaryStorageStatus defines the observed state of SecondaryStorage. The
+// endpoint and credentials are published as soon as they are known, so the
+// resources referencing the storage can be configured while it starts.
+type SecondaryStorageStatus struct {
+	// Generation of the spec the status was computed for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// +optional
+	Phase SecondaryStoragePhase `json:"phase,omitempty"`
+
+	// Number of ready nodes run by the operator
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// URL the storage is reached at
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Secret keys holding the credentials of the storage, if it requires
+	// authentication
+	// +optional
+	Authentication *BasicAuthentication `json:"authentication,omitempty"`
+
+	// Secret key holding the CA certificate which signed the certificate of the
+	// storage, if it does not use a publicly trusted one
+	// +optional
+	CACertificate *v1.SecretKeySelector `json:"caCertificate,omitempty"`
+
+	// Latest observations of the storage state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+//+kubebuilder:printcolumn:name="URL",type=string,JSONPath=`.status.url`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// SecondaryStorage is the Schema for the secondarystorages API. It runs an
+// Elasticsearch or OpenSearch cluster, or publishes an existing one, which the
+// exporters of Zeebe clusters refer to by name.
+type SecondaryStorage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SecondaryStorageSpec   `json:"spec,omitempty"`
+	Status SecondaryStorageStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// SecondaryStorageList contains a list of SecondaryStorage
+type SecondaryStorageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SecondaryStorage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SecondaryStorage{}, &SecondaryStorageList{})
+}
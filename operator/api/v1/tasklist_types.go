@@ -0,0 +1,79 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TasklistSpec defines the desired state of Tasklist
+type TasklistSpec struct {
+	// Name of the Zeebe cluster in the same namespace Tasklist connects to. Tasklist
+	// talks to its gateway and imports the records its exporter writes.
+	// +kubebuilder:validation:MinLength=1
+	ZeebeRef string `json:"zeebeRef"`
+
+	// Name of the Elasticsearch or OpenSearch exporter of the Zeebe cluster whose
+	// records Tasklist imports. Tasklist keeps its own indices in the same
+	// Elasticsearch or OpenSearch. Defaults to the first such exporter.
+	// +optional
+	Exporter string `json:"exporter,omitempty"`
+
+	// Image, replicas, resources and environment of Tasklist. The image defaults
+	// to camunda/tasklist with the image tag of the brokers.
+	// +optional
+	Backend BackendSpec `json:"backend,omitempty"`
+
+	// Name of the Identity in the same namespace users log in with. Tasklist gets
+	// an OAuth client of the Identity, which it also authenticates with at the
+	// gateway. Users are not authenticated if unset.
+	// +optional
+	IdentityRef string `json:"identityRef,omitempty"`
+
+	// Exposes the web app through an Ingress, if set
+	// +optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Zeebe",type=string,JSONPath=`.spec.zeebeRef`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// Tasklist is the Schema for the tasklists API
+type Tasklist struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TasklistSpec `json:"spec,omitempty"`
+	Status WebAppStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TasklistList contains a list of Tasklist
+type TasklistList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Tasklist `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Tasklist{}, &TasklistList{})
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Types shared by the web apps which run next to a Zeebe cluster, like Operate,
+// Tasklist and Optimize
+
+// IngressSpec defines how a web app is exposed outside of the cluster
+type IngressSpec struct {
+	// Host the web app is reachable at
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+
+	// Path the web app is reachable at, defaults to /
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// Name of the IngressClass, defaults to the default class of the cluster
+	// +optional
+	ClassName *string `json:"className,omitempty"`
+
+	// Annotations of the Ingress, for example to configure the ingress controller
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Name of the Secret with the TLS certificate of the host. The Ingress only
+	// serves plain HTTP if unset.
+	// +optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// WebAppPhase is a short summary of the state of a web app
+type WebAppPhase string
+
+const (
+	// the web app is being rolled out or not all replicas are ready yet
+	WebAppPhasePending WebAppPhase = "Pending"
+	// all replicas are ready
+	WebAppPhaseRunning WebAppPhase = "Running"
+	// the Zeebe cluster or its exporter is missing or the web app could not be reconciled
+	WebAppPhaseDegraded WebAppPhase = "Degraded"
+)
+
+// Condition types reported in WebAppStatus.Conditions
+const (
+	// all replicas of the web app are ready and run the current spec
+	WebAppConditionReady = "Ready"
+	// the Zeebe cluster or its exporter is missing or the web app could not be reconciled
+	WebAppConditionDegraded = "Degraded"
+)
+
+// WebAppStatus defines the observed state of a web app
+type WebAppStatus struct {
+	// The generation of the resource which was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Short summary of the web app state, derived from the conditions
+	// +optional
+	Phase WebAppPhase `json:"phase,omitempty"`
+
+	// How many replicas are ready, taken from the Deployment
+	// +optional
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+
+	// Image tag all replicas are running, only updated once a rollout completed
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Address of the Zeebe gateway the web app connects to
+	// +optional
+	GatewayAddress string `json:"gatewayAddress,omitempty"`
+
+	// URL of the Elasticsearch or OpenSearch the web app imports from
+	// +optional
+	DatabaseURL string `json:"databaseUrl,omitempty"`
+
+	// Latest observations of the web app state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
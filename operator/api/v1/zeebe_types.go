@@ -18,6 +18,7 @@ package v1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -31,19 +32,406 @@ type ZeebeSpec struct {
 
 	// Gateway configurations
 	Gateway GatewaySpec `json:"gateway,omitempty"`
+
+	// What happens to the broker data volumes and the backups of the cluster
+	// once the Zeebe resource is deleted. Defaults to Retain.
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
+// DeletionPolicy decides what is cleaned up when a Zeebe resource is deleted
+// +kubebuilder:validation:Enum=Retain;Delete;SnapshotThenDelete
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyRetain keeps the broker data volumes and all backups
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicyDelete deletes the broker data volumes and removes the
+	// backups of the cluster from the backup store
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicySnapshotThenDelete takes a final backup and deletes the
+	// broker data volumes once it completed. All backups are kept.
+	DeletionPolicySnapshotThenDelete DeletionPolicy = "SnapshotThenDelete"
+)
+
 type BrokerSpec struct {
 	Partitions PartitionsSpec `json:"partitions,omitempty"`
-	Backend    BackendSpec    `json:"backend,omitempty"`
+
+	// Image and replicas of the brokers. The defaults only apply if no backend
+	// is set at all, the defaulting webhook fills in whatever is missing from a
+	// partial backend.
+	// +kubebuilder:default={imageName: "camunda/zeebe", imageTag: "8.7.0", replicas: 3}
+	Backend BackendSpec `json:"backend,omitempty"`
+
+	// Broker settings rendered into the application.yaml of the brokers.
+	// Settings which are not set fall back to the defaults of Zeebe.
+	// +optional
+	Config BrokerConfigSpec `json:"config,omitempty"`
+
+	// Persistent storage of the broker data. The size defaults to 1Gi for new
+	// clusters, existing clusters without a size keep the size of their volumes.
+	// +optional
+	Storage StorageSpec `json:"storage,omitempty"`
+
+	// Store the brokers write backups to, backups are disabled if not set
+	// +optional
+	Backup *BackupStoreSpec `json:"backup,omitempty"`
+
+	// Exporters the brokers export records to
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Exporters []ExporterSpec `json:"exporters,omitempty"`
+}
+
+// ExporterType is the kind of an exporter
+// +kubebuilder:validation:Enum=Elasticsearch;OpenSearch;Generic
+type ExporterType string
+
+const (
+	ExporterElasticsearch ExporterType = "Elasticsearch"
+	ExporterOpenSearch    ExporterType = "OpenSearch"
+	ExporterGeneric       ExporterType = "Generic"
+)
+
+// ExporterSpec configures an exporter of the brokers. Only the section matching
+// the type is used.
+type ExporterSpec struct {
+	// Id of the exporter in the broker configuration. The brokers keep track of
+	// the exported records per id, so renaming an exporter exports all records
+	// again.
+	// +kubebuilder:validation:Pattern=`^[a-z][a-z0-9]*$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Kind of exporter
+	Type ExporterType `json:"type"`
+
+	// +optional
+	Elasticsearch *SearchExporter `json:"elasticsearch,omitempty"`
+	// +optional
+	OpenSearch *SearchExporter `json:"opensearch,omitempty"`
+	// +optional
+	Generic *GenericExporter `json:"generic,omitempty"`
+}
+
+// SearchExporter exports records to Elasticsearch or OpenSearch with the
+// exporter which ships with the brokers
+type SearchExporter struct {
+	// URL of the cluster, like http://elasticsearch:9200. Either the URL or a
+	// storageRef must be set.
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// Name of a SecondaryStorage in the same namespace to export to, which
+	// provides the URL, credentials and CA certificate of the cluster
+	// +optional
+	StorageRef string `json:"storageRef,omitempty"`
+
+	// Prefix of the indices the records are written to, defaults to zeebe-record
+	// +optional
+	IndexPrefix string `json:"indexPrefix,omitempty"`
+
+	// How records are batched into bulk requests
+	// +optional
+	Bulk BulkConfig `json:"bulk,omitempty"`
+
+	// Credentials for basic authentication, no authentication is used if not set
+	// +optional
+	Authentication *BasicAuthentication `json:"authentication,omitempty"`
+
+	// Secret key holding the CA certificate in PEM format which signed the
+	// certificate of the cluster, the default truststore of the brokers is used
+	// if not set
+	// +optional
+	CACertificate *v1.SecretKeySelector `json:"caCertificate,omitempty"`
+}
+
+type BulkConfig struct {
+	// how many records are collected before a bulk request is sent
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Size *int32 `json:"size,omitempty"`
+
+	// how many seconds records are collected at most before a bulk request is sent
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Delay *int32 `json:"delay,omitempty"`
+}
+
+// BasicAuthentication refers to the Secret keys holding username and password
+type BasicAuthentication struct {
+	// Secret key holding the username
+	Username v1.SecretKeySelector `json:"username"`
+
+	// Secret key holding the password
+	Password v1.SecretKeySelector `json:"password"`
+}
+
+// GenericExporter loads a custom exporter into the brokers
+type GenericExporter struct {
+	// Fully qualified name of the exporter class
+	// +kubebuilder:validation:MinLength=1
+	ClassName string `json:"className"`
+
+	// Path of the jar with the exporter within the broker container, the class
+	// is loaded from the classpath of the broker if neither the path nor a jar
+	// source is set
+	// +optional
+	JarPath string `json:"jarPath,omitempty"`
+
+	// Where to get the jar with the exporter from. An init container copies the
+	// jar into the exporters volume of the brokers, which keeps the brokers on
+	// the stock Zeebe image. Must not be set together with jarPath.
+	// +optional
+	Jar *ExporterJarSource `json:"jar,omitempty"`
+
+	// Arguments passed to the exporter in YAML
+	// +optional
+	Args string `json:"args,omitempty"`
+}
+
+// ExporterJarSource is where the jar of an exporter is taken from. Exactly one
+// source must be set.
+type ExporterJarSource struct {
+	// URL the jar is downloaded from
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// SHA-256 checksum of the downloaded jar in hex, required with url
+	// +kubebuilder:validation:Pattern=`^[a-f0-9]{64}$`
+	// +optional
+	SHA256 string `json:"sha256,omitempty"`
+
+	// ConfigMap key holding the jar as binary data. ConfigMaps are limited to
+	// 1MiB, larger jars can be downloaded or taken from an image instead.
+	// +optional
+	ConfigMap *v1.ConfigMapKeySelector `json:"configMap,omitempty"`
+
+	// Secret key holding the jar
+	// +optional
+	Secret *v1.SecretKeySelector `json:"secret,omitempty"`
+
+	// Image containing the jar
+	// +optional
+	Image *ExporterJarImage `json:"image,omitempty"`
+}
+
+// ExporterJarImage is an image the jar of an exporter is copied from
+type ExporterJarImage struct {
+	// Image containing the jar, which must provide a cp command, like images
+	// based on busybox
+	// +kubebuilder:validation:MinLength=1
+	Image string `json:"image"`
+
+	// Path of the jar within the image
+	// +kubebuilder:validation:MinLength=1
+	Path string `json:"path"`
+}
+
+// BackupStoreType is the kind of object storage backups are written to
+// +kubebuilder:validation:Enum=S3;GCS;Azure
+type BackupStoreType string
+
+const (
+	BackupStoreS3    BackupStoreType = "S3"
+	BackupStoreGCS   BackupStoreType = "GCS"
+	BackupStoreAzure BackupStoreType = "Azure"
+)
+
+// BackupStoreSpec configures the object storage for backups. Only the section
+// matching the store is used.
+type BackupStoreSpec struct {
+	// Kind of object storage
+	Store BackupStoreType `json:"store"`
+
+	// +optional
+	S3 *S3BackupStore `json:"s3,omitempty"`
+	// +optional
+	GCS *GCSBackupStore `json:"gcs,omitempty"`
+	// +optional
+	Azure *AzureBackupStore `json:"azure,omitempty"`
+}
+
+// S3BackupStore writes backups to an S3 compatible object storage, like AWS S3
+// or MinIO
+type S3BackupStore struct {
+	// Name of the bucket
+	// +kubebuilder:validation:MinLength=1
+	BucketName string `json:"bucketName"`
+
+	// Prefix of all backup objects within the bucket
+	// +optional
+	BasePath string `json:"basePath,omitempty"`
+
+	// Region of the bucket, taken from the environment of the brokers if not set
+	// +optional
+	Region string `json:"region,omitempty"`
+
+	// Endpoint of a custom S3 compatible storage, like http://minio:9000
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Address buckets by path instead of by host, which most S3 compatible
+	// storages require
+	// +optional
+	ForcePathStyleAccess *bool `json:"forcePathStyleAccess,omitempty"`
+
+	// Secret key holding the access key id, the default credentials of the
+	// environment are used if not set
+	// +optional
+	AccessKey *v1.SecretKeySelector `json:"accessKey,omitempty"`
+
+	// Secret key holding the secret access key
+	// +optional
+	SecretKey *v1.SecretKeySelector `json:"secretKey,omitempty"`
+}
+
+// GCSBackupStore writes backups to Google Cloud Storage
+type GCSBackupStore struct {
+	// Name of the bucket
+	// +kubebuilder:validation:MinLength=1
+	BucketName string `json:"bucketName"`
+
+	// Prefix of all backup objects within the bucket
+	// +optional
+	BasePath string `json:"basePath,omitempty"`
+
+	// Secret key holding a service account key in JSON format, the default
+	// credentials of the environment are used if not set
+	// +optional
+	Credentials *v1.SecretKeySelector `json:"credentials,omitempty"`
+}
+
+// AzureBackupStore writes backups to Azure Blob Storage
+type AzureBackupStore struct {
+	// Name of the blob container
+	// +kubebuilder:validation:MinLength=1
+	BasePath string `json:"basePath"`
+
+	// Endpoint of the storage account, used together with the default
+	// credentials of the environment
+	// +optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Secret key holding a connection string of the storage account
+	// +optional
+	ConnectionString *v1.SecretKeySelector `json:"connectionString,omitempty"`
+}
+
+type StorageSpec struct {
+	// Name of the StorageClass for the data volumes, the default class of the
+	// cluster is used if not set
+	// +optional
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// Size of the data volume of every broker. Growing the size expands the
+	// existing volumes, which requires a StorageClass allowing volume expansion.
+	// +optional
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// Access modes of the data volumes, defaults to ReadWriteOnce
+	// +optional
+	AccessModes []v1.PersistentVolumeAccessMode `json:"accessModes,omitempty"`
+
+	// Label query over the volumes to consider for binding
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+type BrokerConfigSpec struct {
+	// +optional
+	Threads ThreadsConfig `json:"threads,omitempty"`
+	// +optional
+	Data DataConfig `json:"data,omitempty"`
+	// +optional
+	Backpressure BackpressureConfig `json:"backpressure,omitempty"`
+	// +optional
+	Network NetworkConfig `json:"network,omitempty"`
+
+	// Free-form broker configuration in YAML, using the same structure as the
+	// application.yaml of Zeebe. It is merged over the typed settings, so values
+	// given here take precedence.
+	// +optional
+	RawConfig string `json:"rawConfig,omitempty"`
+}
+
+type ThreadsConfig struct {
+	// how many threads are used to process records
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	CpuThreadCount *int32 `json:"cpuThreadCount,omitempty"`
+
+	// how many threads are used for exporting and disk access
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	IoThreadCount *int32 `json:"ioThreadCount,omitempty"`
+}
+
+type DataConfig struct {
+	// how often snapshots are taken, e.g. 5m
+	// +kubebuilder:validation:Pattern=`^[0-9]+(ms|s|m|h|d)$`
+	// +optional
+	SnapshotPeriod string `json:"snapshotPeriod,omitempty"`
+
+	// size of a single log segment, e.g. 128MB
+	// +kubebuilder:validation:Pattern=`^[0-9]+(B|KB|MB|GB)$`
+	// +optional
+	LogSegmentSize string `json:"logSegmentSize,omitempty"`
+
+	// whether the broker monitors its disk usage and rejects commands when full
+	// +optional
+	DiskUsageMonitoringEnabled *bool `json:"diskUsageMonitoringEnabled,omitempty"`
+
+	// fraction of used disk above which new commands are rejected, e.g. "0.97"
+	// +kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	// +optional
+	DiskUsageCommandWatermark string `json:"diskUsageCommandWatermark,omitempty"`
+
+	// fraction of used disk above which replication is paused, e.g. "0.99"
+	// +kubebuilder:validation:Pattern=`^(0(\.[0-9]+)?|1(\.0+)?)$`
+	// +optional
+	DiskUsageReplicationWatermark string `json:"diskUsageReplicationWatermark,omitempty"`
+}
+
+type BackpressureConfig struct {
+	// whether requests are rejected when the broker is overloaded
+	// +optional
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// algorithm used to compute the request limit
+	// +kubebuilder:validation:Enum=vegas;aimd;fixed;gradient;gradient2
+	// +optional
+	Algorithm string `json:"algorithm,omitempty"`
+}
+
+type NetworkConfig struct {
+	// maximum size of a message between brokers and gateways, e.g. 4MB
+	// +kubebuilder:validation:Pattern=`^[0-9]+(B|KB|MB|GB)$`
+	// +optional
+	MaxMessageSize string `json:"maxMessageSize,omitempty"`
+
+	// size of the socket send buffer, e.g. 1MB
+	// +kubebuilder:validation:Pattern=`^[0-9]+(B|KB|MB|GB)$`
+	// +optional
+	SocketSendBuffer string `json:"socketSendBuffer,omitempty"`
+
+	// size of the socket receive buffer, e.g. 1MB
+	// +kubebuilder:validation:Pattern=`^[0-9]+(B|KB|MB|GB)$`
+	// +optional
+	SocketReceiveBuffer string `json:"socketReceiveBuffer,omitempty"`
 }
 
 type PartitionsSpec struct {
 	// how many partitions the cluster should have
+	// +kubebuilder:default=3
+	// +kubebuilder:validation:Minimum=1
 	Count *int32 `json:"count,omitempty"`
 
-	// how often a partition should be replicated
-	Replication *int `json:"replication,omitempty"`
+	// how often a partition should be replicated, the defaulting webhook limits
+	// the default to the number of brokers
+	// +kubebuilder:validation:Minimum=1
+	Replication *int32 `json:"replication,omitempty"`
 }
 
 type GatewaySpec struct {
@@ -53,6 +441,10 @@ type GatewaySpec struct {
 	// Optional, only necessary if the gateway is standalone
 	// +optional
 	Backend BackendSpec `json:"backend,omitempty"`
+	// Name of the Identity in the same namespace whose tokens clients have to
+	// present to the gateway. Clients are not authenticated if unset.
+	// +optional
+	IdentityRef string `json:"identityRef,omitempty"`
 }
 
 type BackendSpec struct {
@@ -71,20 +463,209 @@ type BackendSpec struct {
 	OverrideEnv []v1.EnvVar `json:"overrideEnv,omitempty"`
 
 	// The replication count for the component
+	// +kubebuilder:validation:Minimum=1
 	Replicas *int32 `json:"replicas,omitempty"`
 }
 
+// ZeebePhase is a short summary of the state of a Zeebe cluster
+type ZeebePhase string
+
+const (
+	// the cluster is being created or not all brokers are ready yet
+	ZeebePhasePending ZeebePhase = "Pending"
+	// all brokers are ready and run the requested version
+	ZeebePhaseRunning ZeebePhase = "Running"
+	// the brokers are rolled to a new version
+	ZeebePhaseUpgrading ZeebePhase = "Upgrading"
+	// brokers are added to or removed from the cluster
+	ZeebePhaseScaling ZeebePhase = "Scaling"
+	// the brokers are stopped while their data is restored from a backup
+	ZeebePhaseRestoring ZeebePhase = "Restoring"
+	// the cluster could not be reconciled or brokers are missing
+	ZeebePhaseDegraded ZeebePhase = "Degraded"
+	// the resource was deleted and its data is being cleaned up
+	ZeebePhaseDeleting ZeebePhase = "Deleting"
+)
+
+// Condition types reported in ZeebeStatus.Conditions
+const (
+	// all brokers are ready and run the requested configuration
+	ZeebeConditionReady = "Ready"
+	// a change of the spec is being rolled out to the brokers
+	ZeebeConditionProgressing = "Progressing"
+	// the cluster could not be reconciled or has fewer ready brokers than desired
+	ZeebeConditionDegraded = "Degraded"
+	// the brokers are rolled to a new image version
+	ZeebeConditionUpgrading = "Upgrading"
+)
+
 // ZeebeStatus defines the observed state of Zeebe
 type ZeebeStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// The generation of the Zeebe resource which was last reconciled
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Short summary of the cluster state, derived from the conditions
+	// +optional
+	Phase ZeebePhase `json:"phase,omitempty"`
+
+	// How many brokers the spec asks for
+	// +optional
+	DesiredBrokers int32 `json:"desiredBrokers,omitempty"`
+
+	// How many brokers are ready, taken from the broker StatefulSet
+	// +optional
+	ReadyBrokers int32 `json:"readyBrokers,omitempty"`
+
+	// How many brokers the cluster was bootstrapped with. Brokers keep starting
+	// with this cluster size, since later changes of the cluster topology are
+	// made through the cluster management API of the brokers.
+	// +optional
+	InitialClusterSize int32 `json:"initialClusterSize,omitempty"`
+
+	// Progress of a broker scaling operation, unset if none is in progress
+	// +optional
+	Scaling *ScalingStatus `json:"scaling,omitempty"`
+
+	// Progress of a version upgrade, unset if none is in progress
+	// +optional
+	Upgrade *UpgradeStatus `json:"upgrade,omitempty"`
 
-	// PodName of the active node.
-	Active string `json:"active"`
+	// Name of the ZeebeRestore which holds the brokers stopped, unset if no
+	// restore is in progress
+	// +optional
+	Restore string `json:"restore,omitempty"`
+
+	// Id of the backup taken before the broker data is deleted, set once the
+	// resource was deleted with the SnapshotThenDelete policy
+	// +optional
+	FinalBackupID int64 `json:"finalBackupID,omitempty"`
+
+	// Image tag all brokers are running, only updated once a rollout completed
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Hash of the rendered configuration and referenced Secrets and ConfigMaps
+	// the brokers should run with
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// Observed state of the individual brokers
+	// +optional
+	Brokers []BrokerStatus `json:"brokers,omitempty"`
+
+	// Observed state of the broker data volumes
+	// +optional
+	Volumes []VolumeStatus `json:"volumes,omitempty"`
+
+	// Latest observations of the cluster state
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+}
+
+// BrokerStatus defines the observed state of a single broker
+type BrokerStatus struct {
+	// Name of the broker pod
+	Name string `json:"name"`
+
+	// Node id of the broker, which is the ordinal of its pod
+	NodeID int32 `json:"nodeId"`
+
+	// Hash of the configuration the broker pod was started with
+	// +optional
+	ConfigHash string `json:"configHash,omitempty"`
+
+	// Whether the broker pod is ready
+	Ready bool `json:"ready"`
+}
+
+// ScalingStep is a step of a broker scaling operation
+type ScalingStep string
+
+const (
+	// new brokers are started and waited for until they are ready
+	ScalingStepAddingBrokers ScalingStep = "AddingBrokers"
+	// the partitions are reassigned to the new set of brokers
+	ScalingStepRedistributingPartitions ScalingStep = "RedistributingPartitions"
+	// brokers which no longer own partitions are stopped
+	ScalingStepRemovingBrokers ScalingStep = "RemovingBrokers"
+)
+
+// ScalingStatus defines the observed state of a broker scaling operation
+type ScalingStatus struct {
+	// Step the scaling operation is in
+	Step ScalingStep `json:"step"`
+
+	// Number of brokers before scaling
+	FromBrokers int32 `json:"fromBrokers"`
+
+	// Number of brokers after scaling
+	ToBrokers int32 `json:"toBrokers"`
+
+	// Id of the topology change which redistributes the partitions, once it
+	// was submitted to the brokers
+	// +optional
+	ChangeID *int64 `json:"changeId,omitempty"`
+
+	// When the scaling operation started
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// UpgradeStatus defines the observed state of a version upgrade. Brokers are
+// upgraded one at a time, starting with the highest node id.
+type UpgradeStatus struct {
+	// Image tag the brokers are upgraded from
+	FromVersion string `json:"fromVersion"`
+
+	// Image tag the brokers are upgraded to
+	ToVersion string `json:"toVersion"`
+
+	// Node id of the broker which is being upgraded
+	Broker int32 `json:"broker"`
+
+	// When the upgrade of the current broker started
+	BrokerStartTime metav1.Time `json:"brokerStartTime"`
+
+	// Whether the upgrade stopped, because the current broker did not rejoin
+	// the cluster in time. It continues once the broker is healthy again.
+	// +optional
+	Halted bool `json:"halted,omitempty"`
+
+	// Why the current broker is not upgraded yet
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// VolumeStatus defines the observed state of a broker data volume
+type VolumeStatus struct {
+	// Name of the PersistentVolumeClaim
+	Name string `json:"name"`
+
+	// Size requested by the claim
+	// +optional
+	RequestedSize string `json:"requestedSize,omitempty"`
+
+	// Size currently provisioned for the claim
+	// +optional
+	Capacity string `json:"capacity,omitempty"`
+
+	// Progress of a volume expansion, either Resizing or FileSystemResizePending.
+	// Empty if the volume is not being expanded.
+	// +optional
+	Expansion string `json:"expansion,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyBrokers`
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.status.desiredBrokers`
+//+kubebuilder:printcolumn:name="Version",type=string,JSONPath=`.status.version`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // Zeebe is the Schema for the zeebes API
 type Zeebe struct {
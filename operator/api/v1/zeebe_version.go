@@ -0,0 +1,155 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// ZeebeVersion is the release version of a Zeebe image
+type ZeebeVersion struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// versionPattern matches release tags like 8.4.2 as well as pre-releases like
+// 8.5.0-alpha1, whose suffix is ignored
+var versionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(-[0-9A-Za-z.-]+)?$`)
+
+// dynamicScalingVersion is the first version which supports changing the
+// cluster topology through the cluster management API
+var dynamicScalingVersion = ZeebeVersion{Major: 8, Minor: 4}
+
+// ParseZeebeVersion parses an image tag into a version. Tags which are no
+// release version, like latest or SNAPSHOT, cannot be parsed.
+func ParseZeebeVersion(tag string) (ZeebeVersion, error) {
+	match := versionPattern.FindStringSubmatch(tag)
+	if match == nil {
+		return ZeebeVersion{}, fmt.Errorf("image tag %q is not a release version", tag)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	return ZeebeVersion{Major: major, Minor: minor, Patch: patch}, nil
+}
+
+// Compare returns -1, 0 or 1 if the version is lower, equal or higher than the
+// other version
+func (v ZeebeVersion) Compare(other ZeebeVersion) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff < 0 {
+			return -1
+		}
+		if diff > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v ZeebeVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// SupportsDynamicScaling returns whether brokers of this version can be added
+// to or removed from a running cluster
+func (v ZeebeVersion) SupportsDynamicScaling() bool {
+	return v.Compare(dynamicScalingVersion) >= 0
+}
+
+// minor returns the first release of the minor version
+func (v ZeebeVersion) minor() ZeebeVersion {
+	return ZeebeVersion{Major: v.Major, Minor: v.Minor}
+}
+
+// majorUpgrades maps the last minor version of a major version to the minor
+// version the brokers can be upgraded to from there
+var majorUpgrades = map[ZeebeVersion]ZeebeVersion{
+	{Major: 1, Minor: 3}: {Major: 8, Minor: 0},
+}
+
+// CheckUpgrade returns an error if brokers cannot be upgraded from one version
+// to the other. Brokers can only be upgraded to the next minor version, or
+// from the last minor version of a major version to the next major version.
+func CheckUpgrade(from, to ZeebeVersion) error {
+	if to.Compare(from) < 0 {
+		return fmt.Errorf("brokers cannot be downgraded from %s to %s", from, to)
+	}
+
+	if to.Major == from.Major {
+		if to.Minor-from.Minor > 1 {
+			return fmt.Errorf("upgrading from %s to %s skips minor versions, upgrade to %d.%d first",
+				from, to, from.Major, from.Minor+1)
+		}
+		return nil
+	}
+
+	next, ok := majorUpgrades[from.minor()]
+	if !ok {
+		return fmt.Errorf("brokers cannot be upgraded from %s to %s, upgrade to the last minor version of %d first",
+			from, to, from.Major)
+	}
+	if to.minor() != next {
+		return fmt.Errorf("upgrading from %s to %s skips minor versions, upgrade to %d.%d first",
+			from, to, next.Major, next.Minor)
+	}
+	return nil
+}
+
+// optimizeVersions maps the minor versions of Zeebe to the minor version of
+// Optimize which imports their records. From 8.3 on, Optimize is released with
+// the version numbers of Zeebe.
+var optimizeVersions = map[ZeebeVersion]ZeebeVersion{
+	{Major: 1, Minor: 3}: {Major: 3, Minor: 7},
+	{Major: 8, Minor: 0}: {Major: 3, Minor: 8},
+	{Major: 8, Minor: 1}: {Major: 3, Minor: 9},
+	{Major: 8, Minor: 2}: {Major: 3, Minor: 10},
+}
+
+// firstAlignedOptimizeVersion is the first Zeebe version Optimize is released
+// with the same version for
+var firstAlignedOptimizeVersion = ZeebeVersion{Major: 8, Minor: 3}
+
+// OptimizeVersion returns the first release of the minor version of Optimize
+// which imports the records of brokers of this version
+func (v ZeebeVersion) OptimizeVersion() (ZeebeVersion, error) {
+	if v.Compare(firstAlignedOptimizeVersion) >= 0 {
+		return v.minor(), nil
+	}
+	optimize, ok := optimizeVersions[v.minor()]
+	if !ok {
+		return ZeebeVersion{}, fmt.Errorf("Optimize cannot import the records of Zeebe %s", v)
+	}
+	return optimize, nil
+}
+
+// CheckOptimizeVersion returns an error if Optimize of the given version cannot
+// import the records of brokers of the given version
+func CheckOptimizeVersion(zeebe, optimize ZeebeVersion) error {
+	compatible, err := zeebe.OptimizeVersion()
+	if err != nil {
+		return err
+	}
+	if optimize.minor() != compatible {
+		return fmt.Errorf("Optimize %s cannot import the records of Zeebe %s, use Optimize %d.%d",
+			optimize, zeebe, compatible.Major, compatible.Minor)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+func mustParseVersion(tag string) ZeebeVersion {
+	version, err := ParseZeebeVersion(tag)
+	Expect(err).NotTo(HaveOccurred())
+	return version
+}
+
+var _ = Describe("Zeebe versions", func() {
+	It("should parse release and pre-release tags", func() {
+		Expect(mustParseVersion("8.4.2")).To(Equal(ZeebeVersion{Major: 8, Minor: 4, Patch: 2}))
+		Expect(mustParseVersion("8.5.0-alpha1")).To(Equal(ZeebeVersion{Major: 8, Minor: 5}))
+	})
+
+	It("should not parse custom tags", func() {
+		for _, tag := range []string{"latest", "SNAPSHOT", "8.4", ""} {
+			_, err := ParseZeebeVersion(tag)
+			Expect(err).To(HaveOccurred(), tag)
+		}
+	})
+
+	DescribeTable("upgrade paths",
+		func(from, to string, supported bool) {
+			err := CheckUpgrade(mustParseVersion(from), mustParseVersion(to))
+			if supported {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("patch release", "8.2.3", "8.2.5", true),
+		Entry("next minor version", "8.2.5", "8.3.0", true),
+		Entry("skipped minor version", "8.2.5", "8.4.0", false),
+		Entry("downgrade", "8.3.0", "8.2.9", false),
+		Entry("patch downgrade", "8.3.2", "8.3.1", false),
+		Entry("next major version", "1.3.14", "8.0.0", true),
+		Entry("major version from an older minor version", "1.2.9", "8.0.0", false),
+		Entry("major version skipping minor versions", "1.3.14", "8.1.0", false),
+	)
+
+	DescribeTable("Optimize versions",
+		func(zeebe, optimize string, compatible bool) {
+			err := CheckOptimizeVersion(mustParseVersion(zeebe), mustParseVersion(optimize))
+			if compatible {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("Optimize 3 for Zeebe 8.2", "8.2.5", "3.10.3", true),
+		Entry("Optimize 3 of another minor version", "8.2.5", "3.9.0", false),
+		Entry("same minor version from 8.3 on", "8.4.2", "8.4.0", true),
+		Entry("other minor version from 8.3 on", "8.4.2", "8.3.0", false),
+		Entry("Zeebe without Optimize support", "1.2.6", "3.7.0", false),
+	)
+
+	Context("when updating a Zeebe resource", func() {
+		It("should forbid skipping minor versions", func() {
+			old := validZeebe()
+			old.Spec.Broker.Backend.ImageTag = "8.2.5"
+			zeebe := old.DeepCopy()
+			zeebe.Spec.Broker.Backend.ImageTag = "8.4.0"
+
+			err := zeebe.ValidateUpdate(old)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("upgrade to 8.3 first"))
+		})
+
+		It("should accept custom tags", func() {
+			old := validZeebe()
+			zeebe := old.DeepCopy()
+			zeebe.Spec.Broker.Backend.ImageTag = "SNAPSHOT"
+
+			Expect(zeebe.ValidateUpdate(old)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,440 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/yaml"
+)
+
+// log is for logging in this package.
+var zeebelog = logf.Log.WithName("zeebe-resource")
+
+// Defaults for a Zeebe resource which only sets a name. The image and the
+// broker count are repeated in the default marker of BrokerSpec.Backend.
+const (
+	DefaultZeebeImageName    = "camunda/zeebe"
+	DefaultZeebeVersion      = "8.7.0"
+	DefaultBrokerCount       = 3
+	DefaultPartitionCount    = 3
+	DefaultReplicationFactor = 3
+	DefaultGatewayCount      = 1
+	DefaultStorageSize       = "1Gi"
+)
+
+// maxZeebeNameLength keeps the names of the broker pods and the revision labels
+// of the broker StatefulSet, which is named <name>-broker, within 63 characters
+const maxZeebeNameLength = 45
+
+func (r *Zeebe) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-camunda-cloud-io-camunda-v1-zeebe,mutating=true,failurePolicy=fail,sideEffects=None,groups=camunda-cloud.io.camunda,resources=zeebes,verbs=create;update,versions=v1,name=mzeebe.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Zeebe{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type
+func (r *Zeebe) Default() {
+	zeebelog.Info("default", "name", r.Name)
+
+	broker := &r.Spec.Broker
+	if broker.Backend.ImageName == "" {
+		broker.Backend.ImageName = DefaultZeebeImageName
+	}
+	if broker.Backend.ImageTag == "" {
+		broker.Backend.ImageTag = DefaultZeebeVersion
+	}
+	if broker.Backend.Replicas == nil {
+		broker.Backend.Replicas = int32Ptr(DefaultBrokerCount)
+	}
+	if broker.Partitions.Count == nil {
+		broker.Partitions.Count = int32Ptr(DefaultPartitionCount)
+	}
+	if broker.Partitions.Replication == nil {
+		// small clusters cannot replicate more often than they have brokers
+		replication := int32(DefaultReplicationFactor)
+		if *broker.Backend.Replicas < replication {
+			replication = *broker.Backend.Replicas
+		}
+		broker.Partitions.Replication = int32Ptr(replication)
+	}
+	if broker.Storage.Size == nil && r.CreationTimestamp.IsZero() {
+		// only new clusters get the default size, existing clusters without a
+		// size keep the size of their volumes
+		size := resource.MustParse(DefaultStorageSize)
+		broker.Storage.Size = &size
+	}
+
+	gateway := &r.Spec.Gateway
+	if gateway.Standalone && gateway.Backend.Replicas == nil {
+		gateway.Backend.Replicas = int32Ptr(DefaultGatewayCount)
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-camunda-cloud-io-camunda-v1-zeebe,mutating=false,failurePolicy=fail,sideEffects=None,groups=camunda-cloud.io.camunda,resources=zeebes,verbs=create;update,versions=v1,name=vzeebe.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Zeebe{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Zeebe) ValidateCreate() error {
+	zeebelog.Info("validate create", "name", r.Name)
+
+	return r.ValidateSpec()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Zeebe) ValidateUpdate(old runtime.Object) error {
+	zeebelog.Info("validate update", "name", r.Name)
+
+	allErrs := r.validateZeebe()
+	if oldZeebe, ok := old.(*Zeebe); ok {
+		allErrs = append(allErrs, r.validateImmutableFields(oldZeebe)...)
+	}
+	return r.toInvalidError(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Zeebe) ValidateDelete() error {
+	// deletion is always allowed
+	return nil
+}
+
+// ValidateSpec checks the resource without comparing it to a previous version.
+// The controller uses it as well, since the webhook might not be installed.
+func (r *Zeebe) ValidateSpec() error {
+	return r.toInvalidError(r.validateZeebe())
+}
+
+func (r *Zeebe) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(
+		schema.GroupKind{Group: GroupVersion.Group, Kind: "Zeebe"},
+		r.Name, allErrs)
+}
+
+func (r *Zeebe) validateZeebe() field.ErrorList {
+	var allErrs field.ErrorList
+
+	if len(r.Name) > maxZeebeNameLength {
+		allErrs = append(allErrs, field.TooLong(field.NewPath("metadata").Child("name"), r.Name, maxZeebeNameLength))
+	}
+
+	brokerPath := field.NewPath("spec").Child("broker")
+	broker := r.Spec.Broker
+
+	replicasPath := brokerPath.Child("backend", "replicas")
+	replicas := broker.Backend.Replicas
+	if replicas == nil {
+		allErrs = append(allErrs, field.Required(replicasPath, "the number of brokers must be set"))
+	} else if *replicas < 1 {
+		allErrs = append(allErrs, field.Invalid(replicasPath, *replicas, "at least one broker is required"))
+	}
+
+	allErrs = append(allErrs, validateImage(brokerPath.Child("backend"), broker.Backend)...)
+
+	partitionsPath := brokerPath.Child("partitions")
+	if broker.Partitions.Count == nil {
+		allErrs = append(allErrs, field.Required(partitionsPath.Child("count"), "the number of partitions must be set"))
+	} else if *broker.Partitions.Count < 1 {
+		allErrs = append(allErrs, field.Invalid(partitionsPath.Child("count"), *broker.Partitions.Count, "at least one partition is required"))
+	}
+
+	replicationPath := partitionsPath.Child("replication")
+	replication := broker.Partitions.Replication
+	if replication == nil {
+		allErrs = append(allErrs, field.Required(replicationPath, "the replication factor must be set"))
+	} else if *replication < 1 {
+		allErrs = append(allErrs, field.Invalid(replicationPath, *replication, "the replication factor must be at least one"))
+	} else if replicas != nil && *replication > *replicas {
+		allErrs = append(allErrs, field.Invalid(replicationPath, *replication, "the replication factor must not be larger than the number of brokers"))
+	}
+
+	if rawConfig := broker.Config.RawConfig; rawConfig != "" {
+		var parsed map[string]interface{}
+		if err := yaml.Unmarshal([]byte(rawConfig), &parsed); err != nil {
+			allErrs = append(allErrs, field.Invalid(brokerPath.Child("config", "rawConfig"), rawConfig, err.Error()))
+		}
+	}
+
+	if size := broker.Storage.Size; size != nil && size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(brokerPath.Child("storage", "size"), size.String(), "must be greater than zero"))
+	}
+
+	if backup := broker.Backup; backup != nil {
+		allErrs = append(allErrs, validateBackupStore(brokerPath.Child("backup"), backup)...)
+	}
+
+	if r.Spec.DeletionPolicy == DeletionPolicySnapshotThenDelete && broker.Backup == nil {
+		allErrs = append(allErrs, field.Required(brokerPath.Child("backup"), "a backup store is required to take a snapshot before deletion"))
+	}
+
+	allErrs = append(allErrs, validateExporters(brokerPath.Child("exporters"), broker.Exporters)...)
+
+	gatewayPath := field.NewPath("spec").Child("gateway")
+	if gatewayReplicas := r.Spec.Gateway.Backend.Replicas; r.Spec.Gateway.Standalone && gatewayReplicas != nil && *gatewayReplicas < 1 {
+		allErrs = append(allErrs, field.Invalid(gatewayPath.Child("backend", "replicas"), *gatewayReplicas, "at least one gateway is required"))
+	}
+
+	return allErrs
+}
+
+func validateImage(path *field.Path, backend BackendSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	if backend.ImageName == "" {
+		allErrs = append(allErrs, field.Required(path.Child("imageName"), "the image must be set"))
+	}
+	if backend.ImageTag == "" {
+		allErrs = append(allErrs, field.Required(path.Child("imageTag"), "the image tag must be set"))
+	}
+	return allErrs
+}
+
+// validateBackupStore requires the section of the selected store and the
+// fields Zeebe needs to reach it
+func validateBackupStore(path *field.Path, backup *BackupStoreSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	switch backup.Store {
+	case BackupStoreS3:
+		if backup.S3 == nil {
+			return append(allErrs, field.Required(path.Child("s3"), "the S3 store must be configured"))
+		}
+		if backup.S3.BucketName == "" {
+			allErrs = append(allErrs, field.Required(path.Child("s3", "bucketName"), "the bucket must be set"))
+		}
+		if (backup.S3.AccessKey == nil) != (backup.S3.SecretKey == nil) {
+			allErrs = append(allErrs, field.Required(path.Child("s3"), "access key and secret key must be set together"))
+		}
+	case BackupStoreGCS:
+		if backup.GCS == nil {
+			return append(allErrs, field.Required(path.Child("gcs"), "the GCS store must be configured"))
+		}
+		if backup.GCS.BucketName == "" {
+			allErrs = append(allErrs, field.Required(path.Child("gcs", "bucketName"), "the bucket must be set"))
+		}
+	case BackupStoreAzure:
+		if backup.Azure == nil {
+			return append(allErrs, field.Required(path.Child("azure"), "the Azure store must be configured"))
+		}
+		if backup.Azure.BasePath == "" {
+			allErrs = append(allErrs, field.Required(path.Child("azure", "basePath"), "the container must be set"))
+		}
+		if backup.Azure.Endpoint == "" && backup.Azure.ConnectionString == nil {
+			allErrs = append(allErrs, field.Required(path.Child("azure"), "either the endpoint or a connection string must be set"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("store"), backup.Store,
+			[]string{string(BackupStoreS3), string(BackupStoreGCS), string(BackupStoreAzure)}))
+	}
+	return allErrs
+}
+
+// exporterNamePattern restricts exporter ids to names which can be referred to
+// from the environment of the brokers, like ZEEBE_BROKER_EXPORTERS_<ID>_ARGS_URL
+var exporterNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// validateExporters requires unique exporter ids, the section of the selected
+// exporter type and the fields the exporter needs
+func validateExporters(path *field.Path, exporters []ExporterSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	names := map[string]bool{}
+	for i, exporter := range exporters {
+		exporterPath := path.Index(i)
+		if !exporterNamePattern.MatchString(exporter.Name) {
+			allErrs = append(allErrs, field.Invalid(exporterPath.Child("name"), exporter.Name, "must consist of lower case letters and digits, starting with a letter"))
+		} else if names[exporter.Name] {
+			allErrs = append(allErrs, field.Duplicate(exporterPath.Child("name"), exporter.Name))
+		}
+		names[exporter.Name] = true
+
+		switch exporter.Type {
+		case ExporterElasticsearch:
+			allErrs = append(allErrs, validateSearchExporter(exporterPath.Child("elasticsearch"), exporter.Elasticsearch)...)
+		case ExporterOpenSearch:
+			allErrs = append(allErrs, validateSearchExporter(exporterPath.Child("opensearch"), exporter.OpenSearch)...)
+		case ExporterGeneric:
+			generic := exporter.Generic
+			if generic == nil {
+				allErrs = append(allErrs, field.Required(exporterPath.Child("generic"), "the generic exporter must be configured"))
+				continue
+			}
+			if generic.ClassName == "" {
+				allErrs = append(allErrs, field.Required(exporterPath.Child("generic", "className"), "the exporter class must be set"))
+			}
+			if generic.Jar != nil {
+				if generic.JarPath != "" {
+					allErrs = append(allErrs, field.Forbidden(exporterPath.Child("generic", "jarPath"), "must not be set together with a jar source"))
+				}
+				allErrs = append(allErrs, validateExporterJar(exporterPath.Child("generic", "jar"), generic.Jar)...)
+			}
+			if generic.Args != "" {
+				var parsed map[string]interface{}
+				if err := yaml.Unmarshal([]byte(generic.Args), &parsed); err != nil {
+					allErrs = append(allErrs, field.Invalid(exporterPath.Child("generic", "args"), generic.Args, err.Error()))
+				}
+			}
+		default:
+			allErrs = append(allErrs, field.NotSupported(exporterPath.Child("type"), exporter.Type,
+				[]string{string(ExporterElasticsearch), string(ExporterOpenSearch), string(ExporterGeneric)}))
+		}
+	}
+	return allErrs
+}
+
+// exporterChecksumPattern matches a SHA-256 checksum in hex
+var exporterChecksumPattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+func validateExporterJar(path *field.Path, jar *ExporterJarSource) field.ErrorList {
+	var allErrs field.ErrorList
+	sources := 0
+	if jar.URL != "" {
+		sources++
+		if parsed, err := url.Parse(jar.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			allErrs = append(allErrs, field.Invalid(path.Child("url"), jar.URL, "must be an http or https URL"))
+		}
+		if !exporterChecksumPattern.MatchString(jar.SHA256) {
+			allErrs = append(allErrs, field.Invalid(path.Child("sha256"), jar.SHA256, "must be the SHA-256 checksum of the jar in hex"))
+		}
+	} else if jar.SHA256 != "" {
+		allErrs = append(allErrs, field.Forbidden(path.Child("sha256"), "may only be set together with url"))
+	}
+	if jar.ConfigMap != nil {
+		sources++
+	}
+	if jar.Secret != nil {
+		sources++
+	}
+	if jar.Image != nil {
+		sources++
+		if jar.Image.Image == "" {
+			allErrs = append(allErrs, field.Required(path.Child("image", "image"), "the image must be set"))
+		}
+		if jar.Image.Path == "" {
+			allErrs = append(allErrs, field.Required(path.Child("image", "path"), "the path of the jar must be set"))
+		}
+	}
+	if sources == 0 {
+		allErrs = append(allErrs, field.Required(path, "one of url, configMap, secret and image must be set"))
+	} else if sources > 1 {
+		allErrs = append(allErrs, field.Forbidden(path, "only one of url, configMap, secret and image may be set"))
+	}
+	return allErrs
+}
+
+func validateSearchExporter(path *field.Path, exporter *SearchExporter) field.ErrorList {
+	var allErrs field.ErrorList
+	if exporter == nil {
+		return append(allErrs, field.Required(path, "the exporter must be configured"))
+	}
+	if exporter.StorageRef != "" {
+		if exporter.URL != "" {
+			allErrs = append(allErrs, field.Forbidden(path.Child("url"), "must not be set together with a storageRef"))
+		}
+		if exporter.Authentication != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("authentication"), "must not be set together with a storageRef"))
+		}
+		if exporter.CACertificate != nil {
+			allErrs = append(allErrs, field.Forbidden(path.Child("caCertificate"), "must not be set together with a storageRef"))
+		}
+		return allErrs
+	}
+	if exporter.URL == "" {
+		return append(allErrs, field.Required(path.Child("url"), "either the URL or a storageRef must be set"))
+	}
+	if parsed, err := url.Parse(exporter.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		allErrs = append(allErrs, field.Invalid(path.Child("url"), exporter.URL, "must be an http or https URL"))
+	}
+	return allErrs
+}
+
+// validateImmutableFields rejects changes which the brokers or the broker
+// StatefulSet do not support once the cluster is created
+func (r *Zeebe) validateImmutableFields(old *Zeebe) field.ErrorList {
+	var allErrs field.ErrorList
+
+	brokerPath := field.NewPath("spec").Child("broker")
+	partitions, oldPartitions := r.Spec.Broker.Partitions, old.Spec.Broker.Partitions
+
+	if oldPartitions.Count != nil && !apiequality.Semantic.DeepEqual(partitions.Count, oldPartitions.Count) {
+		allErrs = append(allErrs, field.Forbidden(brokerPath.Child("partitions", "count"), "the number of partitions cannot be changed"))
+	}
+	if oldPartitions.Replication != nil && !apiequality.Semantic.DeepEqual(partitions.Replication, oldPartitions.Replication) {
+		allErrs = append(allErrs, field.Forbidden(brokerPath.Child("partitions", "replication"), "the replication factor cannot be changed"))
+	}
+
+	replicasPath := brokerPath.Child("backend", "replicas")
+	backend, oldBackend := r.Spec.Broker.Backend, old.Spec.Broker.Backend
+	if backend.Replicas != nil && oldBackend.Replicas != nil && *backend.Replicas != *oldBackend.Replicas {
+		// the running brokers carry out the topology change, so their version counts
+		if version, err := ParseZeebeVersion(oldBackend.ImageTag); err == nil && !version.SupportsDynamicScaling() {
+			allErrs = append(allErrs, field.Forbidden(replicasPath,
+				fmt.Sprintf("brokers of version %s cannot be scaled, at least %s is required", version, dynamicScalingVersion)))
+		}
+		if backend.ImageTag != oldBackend.ImageTag {
+			allErrs = append(allErrs, field.Forbidden(replicasPath, "the brokers cannot be scaled and upgraded at the same time"))
+		}
+	}
+
+	if backend.ImageTag != oldBackend.ImageTag {
+		from, fromErr := ParseZeebeVersion(oldBackend.ImageTag)
+		to, toErr := ParseZeebeVersion(backend.ImageTag)
+		// custom tags cannot be checked
+		if fromErr == nil && toErr == nil {
+			if err := CheckUpgrade(from, to); err != nil {
+				allErrs = append(allErrs, field.Forbidden(brokerPath.Child("backend", "imageTag"), err.Error()))
+			}
+		}
+	}
+
+	storagePath := brokerPath.Child("storage")
+	storage, oldStorage := r.Spec.Broker.Storage, old.Spec.Broker.Storage
+	if size, oldSize := storage.Size, oldStorage.Size; size != nil && oldSize != nil && size.Cmp(*oldSize) < 0 {
+		allErrs = append(allErrs, field.Forbidden(storagePath.Child("size"), "broker volumes cannot be shrunk"))
+	}
+	if !apiequality.Semantic.DeepEqual(storage.StorageClassName, oldStorage.StorageClassName) {
+		allErrs = append(allErrs, field.Forbidden(storagePath.Child("storageClassName"), "the storage class cannot be changed"))
+	}
+	if !apiequality.Semantic.DeepEqual(storage.AccessModes, oldStorage.AccessModes) {
+		allErrs = append(allErrs, field.Forbidden(storagePath.Child("accessModes"), "the access modes cannot be changed"))
+	}
+	if !apiequality.Semantic.DeepEqual(storage.Selector, oldStorage.Selector) {
+		allErrs = append(allErrs, field.Forbidden(storagePath.Child("selector"), "the volume selector cannot be changed"))
+	}
+
+	return allErrs
+}
+
+func int32Ptr(val int32) *int32 {
+	return &val
+}
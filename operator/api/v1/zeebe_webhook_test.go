@@ -0,0 +1,315 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func validZeebe() *Zeebe {
+	return &Zeebe{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "zeebe-sample",
+			Namespace: "default",
+		},
+		Spec: ZeebeSpec{
+			Broker: BrokerSpec{
+				Partitions: PartitionsSpec{
+					Count:       int32Ptr(3),
+					Replication: int32Ptr(3),
+				},
+				Backend: BackendSpec{
+					ImageName: "camunda/zeebe",
+					ImageTag:  "1.2.6",
+					Replicas:  int32Ptr(3),
+				},
+			},
+		},
+	}
+}
+
+var _ = Describe("Zeebe webhook", func() {
+
+	Context("when defaulting a Zeebe resource", func() {
+		It("should fill a three broker cluster", func() {
+			zeebe := &Zeebe{ObjectMeta: metav1.ObjectMeta{Name: "zeebe-sample"}}
+			zeebe.Default()
+
+			broker := zeebe.Spec.Broker
+			Expect(broker.Backend.ImageName).To(Equal(DefaultZeebeImageName))
+			Expect(broker.Backend.ImageTag).To(Equal("8.7.0"))
+			Expect(*broker.Backend.Replicas).To(BeEquivalentTo(3))
+			Expect(*broker.Partitions.Count).To(BeEquivalentTo(3))
+			Expect(*broker.Partitions.Replication).To(BeEquivalentTo(3))
+			Expect(zeebe.ValidateCreate()).To(Succeed())
+		})
+
+		It("should keep values which are set", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backend.Replicas = int32Ptr(1)
+			zeebe.Spec.Broker.Partitions.Replication = int32Ptr(1)
+			zeebe.Default()
+
+			Expect(*zeebe.Spec.Broker.Backend.Replicas).To(BeEquivalentTo(1))
+			Expect(*zeebe.Spec.Broker.Partitions.Replication).To(BeEquivalentTo(1))
+			Expect(zeebe.Spec.Broker.Backend.ImageTag).To(Equal("1.2.6"))
+		})
+
+		It("should not replicate more often than there are brokers", func() {
+			zeebe := &Zeebe{ObjectMeta: metav1.ObjectMeta{Name: "zeebe-sample"}}
+			zeebe.Spec.Broker.Backend.Replicas = int32Ptr(1)
+			zeebe.Default()
+
+			Expect(*zeebe.Spec.Broker.Partitions.Replication).To(BeEquivalentTo(1))
+		})
+
+		It("should only size the volumes of new clusters", func() {
+			zeebe := &Zeebe{ObjectMeta: metav1.ObjectMeta{Name: "zeebe-sample"}}
+			zeebe.Default()
+			Expect(zeebe.Spec.Broker.Storage.Size.String()).To(Equal(DefaultStorageSize))
+
+			existing := &Zeebe{ObjectMeta: metav1.ObjectMeta{Name: "zeebe-sample", CreationTimestamp: metav1.Now()}}
+			existing.Default()
+			Expect(existing.Spec.Broker.Storage.Size).To(BeNil())
+		})
+	})
+
+	Context("when creating a Zeebe resource", func() {
+		It("should accept a complete resource", func() {
+			Expect(validZeebe().ValidateCreate()).To(Succeed())
+		})
+
+		It("should reject missing broker, partition and replication counts", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backend.Replicas = nil
+			zeebe.Spec.Broker.Partitions.Count = nil
+			zeebe.Spec.Broker.Partitions.Replication = nil
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.broker.backend.replicas"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.partitions.count"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.partitions.replication"))
+		})
+
+		It("should reject zero partitions", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Partitions.Count = int32Ptr(0)
+
+			Expect(zeebe.ValidateCreate()).NotTo(Succeed())
+		})
+
+		It("should reject a replication factor larger than the number of brokers", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Partitions.Replication = int32Ptr(5)
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("larger than the number of brokers"))
+		})
+
+		It("should reject an empty image", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backend.ImageName = ""
+
+			Expect(zeebe.ValidateCreate()).NotTo(Succeed())
+		})
+
+		It("should reject an invalid raw configuration", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Config.RawConfig = "zeebe: [broker"
+
+			Expect(zeebe.ValidateCreate()).NotTo(Succeed())
+		})
+
+		It("should reject names which exceed the broker pod names", func() {
+			zeebe := validZeebe()
+			zeebe.Name = strings.Repeat("z", maxZeebeNameLength+1)
+
+			Expect(zeebe.ValidateCreate()).NotTo(Succeed())
+		})
+
+		It("should reject a backup store without its settings", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backup = &BackupStoreSpec{Store: BackupStoreS3}
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.broker.backup.s3"))
+		})
+
+		It("should reject an Azure backup store without credentials", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backup = &BackupStoreSpec{
+				Store: BackupStoreAzure,
+				Azure: &AzureBackupStore{BasePath: "backups"},
+			}
+
+			Expect(zeebe.ValidateCreate()).NotTo(Succeed())
+		})
+
+		It("should require a backup store to snapshot before deletion", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.DeletionPolicy = DeletionPolicySnapshotThenDelete
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.broker.backup"))
+		})
+
+		It("should accept exporters", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Exporters = []ExporterSpec{
+				{Name: "elasticsearch", Type: ExporterElasticsearch, Elasticsearch: &SearchExporter{URL: "http://elastic:9200"}},
+				{Name: "audit", Type: ExporterGeneric, Generic: &GenericExporter{ClassName: "com.example.AuditExporter"}},
+			}
+
+			Expect(zeebe.ValidateCreate()).To(Succeed())
+		})
+
+		It("should reject exporters without their required fields", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Exporters = []ExporterSpec{
+				{Name: "elasticsearch", Type: ExporterElasticsearch, Elasticsearch: &SearchExporter{URL: "elastic:9200"}},
+				{Name: "opensearch", Type: ExporterOpenSearch},
+				{Name: "audit", Type: ExporterGeneric, Generic: &GenericExporter{Args: "topic: [audit"}},
+			}
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[0].elasticsearch.url"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[1].opensearch"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[2].generic.className"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[2].generic.args"))
+		})
+
+		It("should reject search exporters with both a URL and a storageRef", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Exporters = []ExporterSpec{
+				{Name: "elasticsearch", Type: ExporterElasticsearch, Elasticsearch: &SearchExporter{StorageRef: "search"}},
+				{Name: "opensearch", Type: ExporterOpenSearch, OpenSearch: &SearchExporter{StorageRef: "search", URL: "http://opensearch:9200"}},
+			}
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("spec.broker.exporters[0]"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[1].opensearch.url"))
+		})
+
+		It("should reject exporter jars without exactly one verifiable source", func() {
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Exporters = []ExporterSpec{
+				{Name: "kafka", Type: ExporterGeneric, Generic: &GenericExporter{
+					ClassName: "com.example.KafkaExporter",
+					Jar:       &ExporterJarSource{URL: "https://example.com/kafka.jar"},
+				}},
+				{Name: "audit", Type: ExporterGeneric, Generic: &GenericExporter{
+					ClassName: "com.example.AuditExporter",
+					JarPath:   "/audit.jar",
+					Jar: &ExporterJarSource{
+						Image:  &ExporterJarImage{Image: "example/audit-exporter:1.0", Path: "/audit.jar"},
+						Secret: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "audit"}, Key: "audit.jar"},
+					},
+				}},
+			}
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[0].generic.jar.sha256"))
+			Expect(err.Error()).To(ContainSubstring("spec.broker.exporters[1].generic.jarPath"))
+			Expect(err.Error()).To(ContainSubstring("only one of url, configMap, secret and image"))
+		})
+
+		It("should reject duplicate exporter ids", func() {
+			zeebe := validZeebe()
+			exporter := ExporterSpec{Name: "elasticsearch", Type: ExporterElasticsearch, Elasticsearch: &SearchExporter{URL: "http://elastic:9200"}}
+			zeebe.Spec.Broker.Exporters = []ExporterSpec{exporter, exporter}
+
+			err := zeebe.ValidateCreate()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Duplicate value"))
+		})
+	})
+
+	Context("when updating a Zeebe resource", func() {
+		It("should accept a new version", func() {
+			old := validZeebe()
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backend.ImageTag = "1.3.0"
+
+			Expect(zeebe.ValidateUpdate(old)).To(Succeed())
+		})
+
+		It("should accept more brokers if the brokers support scaling", func() {
+			old := validZeebe()
+			old.Spec.Broker.Backend.ImageTag = "8.4.0"
+			zeebe := old.DeepCopy()
+			zeebe.Spec.Broker.Backend.Replicas = int32Ptr(5)
+
+			Expect(zeebe.ValidateUpdate(old)).To(Succeed())
+		})
+
+		It("should forbid scaling brokers which do not support it", func() {
+			old := validZeebe()
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Backend.Replicas = int32Ptr(5)
+
+			err := zeebe.ValidateUpdate(old)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("brokers of version 1.2.6 cannot be scaled"))
+		})
+
+		It("should forbid scaling and upgrading at the same time", func() {
+			old := validZeebe()
+			old.Spec.Broker.Backend.ImageTag = "8.4.0"
+			zeebe := old.DeepCopy()
+			zeebe.Spec.Broker.Backend.Replicas = int32Ptr(5)
+			zeebe.Spec.Broker.Backend.ImageTag = "8.4.1"
+
+			err := zeebe.ValidateUpdate(old)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot be scaled and upgraded at the same time"))
+		})
+
+		It("should forbid changing the partition count", func() {
+			old := validZeebe()
+			zeebe := validZeebe()
+			zeebe.Spec.Broker.Partitions.Count = int32Ptr(1)
+
+			err := zeebe.ValidateUpdate(old)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("the number of partitions cannot be changed"))
+		})
+
+		It("should forbid shrinking the broker volumes", func() {
+			old := validZeebe()
+			oldSize := resource.MustParse("10Gi")
+			old.Spec.Broker.Storage.Size = &oldSize
+			zeebe := validZeebe()
+			size := resource.MustParse("5Gi")
+			zeebe.Spec.Broker.Storage.Size = &size
+
+			Expect(zeebe.ValidateUpdate(old)).NotTo(Succeed())
+		})
+	})
+})
@@ -0,0 +1,135 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ZeebeBackupSpec defines the desired state of ZeebeBackup
+type ZeebeBackupSpec struct {
+	// Name of the Zeebe cluster in the same namespace to back up. The cluster
+	// must have a backup store configured.
+	// +kubebuilder:validation:MinLength=1
+	ZeebeRef string `json:"zeebeRef"`
+
+	// Id of the backup, which must be larger than the ids of all previous
+	// backups of the cluster. Defaults to the creation time of the resource in
+	// seconds since the epoch, so of several backups of the cluster created
+	// within the same second without an id only the first one is taken.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BackupID *int64 `json:"backupId,omitempty"`
+}
+
+// BackupPhase is a short summary of the state of a backup
+type BackupPhase string
+
+const (
+	// the backup was not triggered yet
+	BackupPhasePending BackupPhase = "Pending"
+	// the partitions are being backed up
+	BackupPhaseInProgress BackupPhase = "InProgress"
+	// all partitions were backed up
+	BackupPhaseCompleted BackupPhase = "Completed"
+	// the backup of at least one partition failed
+	BackupPhaseFailed BackupPhase = "Failed"
+)
+
+// ZeebeBackupStatus defines the observed state of ZeebeBackup. The brokers do
+// not report the size of a backup, so it is only available from the backup
+// store itself.
+type ZeebeBackupStatus struct {
+	// Id of the backup as taken by the brokers
+	// +optional
+	BackupID int64 `json:"backupId,omitempty"`
+
+	// Number of brokers of the cluster when the backup was triggered
+	// +optional
+	Brokers int32 `json:"brokers,omitempty"`
+
+	// Short summary of the backup state
+	// +optional
+	Phase BackupPhase `json:"phase,omitempty"`
+
+	// When the backup was triggered
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// When all partitions were backed up
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Why the backup failed
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+
+	// State of the backup of every partition, as reported by the brokers
+	// +optional
+	Partitions []PartitionBackupStatus `json:"partitions,omitempty"`
+}
+
+// PartitionBackupStatus defines the observed state of the backup of a single
+// partition
+type PartitionBackupStatus struct {
+	// Id of the partition
+	PartitionID int32 `json:"partitionId"`
+
+	// State of the backup as reported by the broker, like IN_PROGRESS or COMPLETED
+	State string `json:"state"`
+
+	// Position of the log up to which the partition was backed up
+	// +optional
+	CheckpointPosition int64 `json:"checkpointPosition,omitempty"`
+
+	// Version of the broker which took the backup
+	// +optional
+	BrokerVersion string `json:"brokerVersion,omitempty"`
+
+	// Why the backup of the partition failed
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Zeebe",type=string,JSONPath=`.spec.zeebeRef`
+//+kubebuilder:printcolumn:name="Backup ID",type=integer,JSONPath=`.status.backupId`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ZeebeBackup is the Schema for the zeebebackups API
+type ZeebeBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ZeebeBackupSpec   `json:"spec,omitempty"`
+	Status ZeebeBackupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ZeebeBackupList contains a list of ZeebeBackup
+type ZeebeBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ZeebeBackup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ZeebeBackup{}, &ZeebeBackupList{})
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ZeebeBackupScheduleSpec defines the desired state of ZeebeBackupSchedule
+type ZeebeBackupScheduleSpec struct {
+	// Name of the Zeebe cluster in the same namespace to back up
+	// +kubebuilder:validation:MinLength=1
+	ZeebeRef string `json:"zeebeRef"`
+
+	// When to take backups, in Cron format
+	// +kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+
+	// Stops taking backups, backups which are in progress are not affected
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+
+	// Which backups to keep
+	// +optional
+	Retention BackupRetentionSpec `json:"retention,omitempty"`
+}
+
+// BackupRetentionSpec defines which backups of a schedule are kept. Older
+// backups are deleted from the backup store together with their ZeebeBackup.
+type BackupRetentionSpec struct {
+	// How many completed backups to keep, all are kept if not set
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	KeepLast *int32 `json:"keepLast,omitempty"`
+
+	// How many failed backups to keep, all are kept if not set
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	KeepFailed *int32 `json:"keepFailed,omitempty"`
+}
+
+// ZeebeBackupScheduleStatus defines the observed state of ZeebeBackupSchedule
+type ZeebeBackupScheduleStatus struct {
+	// When the last backup was scheduled
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// When the next backup is scheduled
+	// +optional
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+
+	// Name of the ZeebeBackup which is in progress
+	// +optional
+	Active string `json:"active,omitempty"`
+
+	// Completed backups which are kept, the most recent first
+	// +optional
+	CompletedBackups []CompletedBackup `json:"completedBackups,omitempty"`
+
+	// Why no backups can be scheduled
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CompletedBackup is a backup taken by a schedule
+type CompletedBackup struct {
+	// Name of the ZeebeBackup
+	Name string `json:"name"`
+
+	// Id of the backup
+	BackupID int64 `json:"backupId"`
+
+	// When all partitions were backed up
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Zeebe",type=string,JSONPath=`.spec.zeebeRef`
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+//+kubebuilder:printcolumn:name="Last Schedule",type=date,JSONPath=`.status.lastScheduleTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ZeebeBackupSchedule is the Schema for the zeebebackupschedules API
+type ZeebeBackupSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ZeebeBackupScheduleSpec   `json:"spec,omitempty"`
+	Status ZeebeBackupScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ZeebeBackupScheduleList contains a list of ZeebeBackupSchedule
+type ZeebeBackupScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ZeebeBackupSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ZeebeBackupSchedule{}, &ZeebeBackupScheduleList{})
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ZeebeRestoreSpec defines the desired state of ZeebeRestore
+type ZeebeRestoreSpec struct {
+	// Name of the Zeebe cluster in the same namespace to restore. All data of
+	// its brokers is replaced by the backup.
+	// +kubebuilder:validation:MinLength=1
+	ZeebeRef string `json:"zeebeRef"`
+
+	// Id of the backup to restore, which must have been taken with the same
+	// partition count and number of brokers as the cluster is configured with
+	// +kubebuilder:validation:Minimum=1
+	BackupID int64 `json:"backupId"`
+}
+
+// RestorePhase is a short summary of the state of a restore
+type RestorePhase string
+
+const (
+	// the backup was not checked against the cluster yet
+	RestorePhasePending RestorePhase = "Pending"
+	// the brokers are being stopped
+	RestorePhaseStoppingBrokers RestorePhase = "StoppingBrokers"
+	// the data of the brokers is being restored
+	RestorePhaseRestoring RestorePhase = "Restoring"
+	// the brokers are being started with the restored data
+	RestorePhaseStartingBrokers RestorePhase = "StartingBrokers"
+	// all brokers run with the restored data
+	RestorePhaseCompleted RestorePhase = "Completed"
+	// the backup does not match the cluster or restoring a broker failed, in
+	// which case the brokers stay stopped until the restore is deleted
+	RestorePhaseFailed RestorePhase = "Failed"
+)
+
+// ZeebeRestoreStatus defines the observed state of ZeebeRestore
+type ZeebeRestoreStatus struct {
+	// Short summary of the restore state
+	// +optional
+	Phase RestorePhase `json:"phase,omitempty"`
+
+	// Number of brokers whose data is restored
+	// +optional
+	Brokers int32 `json:"brokers,omitempty"`
+
+	// Number of brokers whose data was restored
+	// +optional
+	RestoredBrokers int32 `json:"restoredBrokers,omitempty"`
+
+	// When the brokers were stopped
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// When all brokers were running again
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Why the restore waits or failed
+	// +optional
+	FailureReason string `json:"failureReason,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Zeebe",type=string,JSONPath=`.spec.zeebeRef`
+//+kubebuilder:printcolumn:name="Backup ID",type=integer,JSONPath=`.spec.backupId`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ZeebeRestore is the Schema for the zeeberestores API
+type ZeebeRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ZeebeRestoreSpec   `json:"spec,omitempty"`
+	Status ZeebeRestoreStatus `json:"status,omitempty"`
+}
+
+// Active returns whether the restore holds the brokers of its cluster stopped.
+// A restore which failed after the brokers were stopped keeps holding them
+// until it is deleted, since their data may be wiped or only partly restored.
+func (r *ZeebeRestore) Active() bool {
+	switch r.Status.Phase {
+	case RestorePhaseStoppingBrokers, RestorePhaseRestoring:
+		return true
+	case RestorePhaseFailed:
+		return r.Status.Brokers > 0
+	}
+	return false
+}
+
+//+kubebuilder:object:root=true
+
+// ZeebeRestoreList contains a list of ZeebeRestore
+type ZeebeRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ZeebeRestore `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ZeebeRestore{}, &ZeebeRestoreList{})
+}
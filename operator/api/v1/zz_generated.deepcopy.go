@@ -23,9 +23,30 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureBackupStore) DeepCopyInto(out *AzureBackupStore) {
+	*out = *in
+	if in.ConnectionString != nil {
+		in, out := &in.ConnectionString, &out.ConnectionString
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureBackupStore.
+func (in *AzureBackupStore) DeepCopy() *AzureBackupStore {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureBackupStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BackendSpec) DeepCopyInto(out *BackendSpec) {
 	*out = *in
@@ -54,11 +75,136 @@ func (in *BackendSpec) DeepCopy() *BackendSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackpressureConfig) DeepCopyInto(out *BackpressureConfig) {
+	*out = *in
+	if in.Enabled != nil {
+		in, out := &in.Enabled, &out.Enabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackpressureConfig.
+func (in *BackpressureConfig) DeepCopy() *BackpressureConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BackpressureConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRetentionSpec) DeepCopyInto(out *BackupRetentionSpec) {
+	*out = *in
+	if in.KeepLast != nil {
+		in, out := &in.KeepLast, &out.KeepLast
+		*out = new(int32)
+		**out = **in
+	}
+	if in.KeepFailed != nil {
+		in, out := &in.KeepFailed, &out.KeepFailed
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetentionSpec.
+func (in *BackupRetentionSpec) DeepCopy() *BackupRetentionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRetentionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStoreSpec) DeepCopyInto(out *BackupStoreSpec) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3BackupStore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GCS != nil {
+		in, out := &in.GCS, &out.GCS
+		*out = new(GCSBackupStore)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Azure != nil {
+		in, out := &in.Azure, &out.Azure
+		*out = new(AzureBackupStore)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStoreSpec.
+func (in *BackupStoreSpec) DeepCopy() *BackupStoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BasicAuthentication) DeepCopyInto(out *BasicAuthentication) {
+	*out = *in
+	in.Username.DeepCopyInto(&out.Username)
+	in.Password.DeepCopyInto(&out.Password)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BasicAuthentication.
+func (in *BasicAuthentication) DeepCopy() *BasicAuthentication {
+	if in == nil {
+		return nil
+	}
+	out := new(BasicAuthentication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BrokerConfigSpec) DeepCopyInto(out *BrokerConfigSpec) {
+	*out = *in
+	in.Threads.DeepCopyInto(&out.Threads)
+	in.Data.DeepCopyInto(&out.Data)
+	in.Backpressure.DeepCopyInto(&out.Backpressure)
+	out.Network = in.Network
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BrokerConfigSpec.
+func (in *BrokerConfigSpec) DeepCopy() *BrokerConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BrokerConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BrokerSpec) DeepCopyInto(out *BrokerSpec) {
 	*out = *in
 	in.Partitions.DeepCopyInto(&out.Partitions)
 	in.Backend.DeepCopyInto(&out.Backend)
+	in.Config.DeepCopyInto(&out.Config)
+	in.Storage.DeepCopyInto(&out.Storage)
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupStoreSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Exporters != nil {
+		in, out := &in.Exporters, &out.Exporters
+		*out = make([]ExporterSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BrokerSpec.
@@ -72,67 +218,66 @@ func (in *BrokerSpec) DeepCopy() *BrokerSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GatewaySpec) DeepCopyInto(out *GatewaySpec) {
+func (in *BrokerStatus) DeepCopyInto(out *BrokerStatus) {
 	*out = *in
-	in.Backend.DeepCopyInto(&out.Backend)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewaySpec.
-func (in *GatewaySpec) DeepCopy() *GatewaySpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BrokerStatus.
+func (in *BrokerStatus) DeepCopy() *BrokerStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(GatewaySpec)
+	out := new(BrokerStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PartitionsSpec) DeepCopyInto(out *PartitionsSpec) {
+func (in *BulkConfig) DeepCopyInto(out *BulkConfig) {
 	*out = *in
-	if in.Count != nil {
-		in, out := &in.Count, &out.Count
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
 		*out = new(int32)
 		**out = **in
 	}
-	if in.Replication != nil {
-		in, out := &in.Replication, &out.Replication
-		*out = new(int)
+	if in.Delay != nil {
+		in, out := &in.Delay, &out.Delay
+		*out = new(int32)
 		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitionsSpec.
-func (in *PartitionsSpec) DeepCopy() *PartitionsSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BulkConfig.
+func (in *BulkConfig) DeepCopy() *BulkConfig {
 	if in == nil {
 		return nil
 	}
-	out := new(PartitionsSpec)
+	out := new(BulkConfig)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Zeebe) DeepCopyInto(out *Zeebe) {
+func (in *CamundaPlatform) DeepCopyInto(out *CamundaPlatform) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Zeebe.
-func (in *Zeebe) DeepCopy() *Zeebe {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CamundaPlatform.
+func (in *CamundaPlatform) DeepCopy() *CamundaPlatform {
 	if in == nil {
 		return nil
 	}
-	out := new(Zeebe)
+	out := new(CamundaPlatform)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Zeebe) DeepCopyObject() runtime.Object {
+func (in *CamundaPlatform) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -140,31 +285,31 @@ func (in *Zeebe) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ZeebeList) DeepCopyInto(out *ZeebeList) {
+func (in *CamundaPlatformList) DeepCopyInto(out *CamundaPlatformList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Zeebe, len(*in))
+		*out = make([]CamundaPlatform, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeList.
-func (in *ZeebeList) DeepCopy() *ZeebeList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CamundaPlatformList.
+func (in *CamundaPlatformList) DeepCopy() *CamundaPlatformList {
 	if in == nil {
 		return nil
 	}
-	out := new(ZeebeList)
+	out := new(CamundaPlatformList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ZeebeList) DeepCopyObject() runtime.Object {
+func (in *CamundaPlatformList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -172,33 +317,1490 @@ func (in *ZeebeList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ZeebeSpec) DeepCopyInto(out *ZeebeSpec) {
+func (in *CamundaPlatformSpec) DeepCopyInto(out *CamundaPlatformSpec) {
 	*out = *in
-	in.Broker.DeepCopyInto(&out.Broker)
-	in.Gateway.DeepCopyInto(&out.Gateway)
+	in.Zeebe.DeepCopyInto(&out.Zeebe)
+	if in.SecondaryStorage != nil {
+		in, out := &in.SecondaryStorage, &out.SecondaryStorage
+		*out = new(SecondaryStorageSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Identity != nil {
+		in, out := &in.Identity, &out.Identity
+		*out = new(IdentitySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Operate != nil {
+		in, out := &in.Operate, &out.Operate
+		*out = new(PlatformWebAppSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tasklist != nil {
+		in, out := &in.Tasklist, &out.Tasklist
+		*out = new(PlatformWebAppSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Optimize != nil {
+		in, out := &in.Optimize, &out.Optimize
+		*out = new(PlatformWebAppSpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeSpec.
-func (in *ZeebeSpec) DeepCopy() *ZeebeSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CamundaPlatformSpec.
+func (in *CamundaPlatformSpec) DeepCopy() *CamundaPlatformSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ZeebeSpec)
+	out := new(CamundaPlatformSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ZeebeStatus) DeepCopyInto(out *ZeebeStatus) {
+func (in *CamundaPlatformStatus) DeepCopyInto(out *CamundaPlatformStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeStatus.
-func (in *ZeebeStatus) DeepCopy() *ZeebeStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CamundaPlatformStatus.
+func (in *CamundaPlatformStatus) DeepCopy() *CamundaPlatformStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ZeebeStatus)
+	out := new(CamundaPlatformStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompletedBackup) DeepCopyInto(out *CompletedBackup) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompletedBackup.
+func (in *CompletedBackup) DeepCopy() *CompletedBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(CompletedBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataConfig) DeepCopyInto(out *DataConfig) {
+	*out = *in
+	if in.DiskUsageMonitoringEnabled != nil {
+		in, out := &in.DiskUsageMonitoringEnabled, &out.DiskUsageMonitoringEnabled
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataConfig.
+func (in *DataConfig) DeepCopy() *DataConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(DataConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterJarImage) DeepCopyInto(out *ExporterJarImage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterJarImage.
+func (in *ExporterJarImage) DeepCopy() *ExporterJarImage {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterJarImage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterJarSource) DeepCopyInto(out *ExporterJarSource) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Image != nil {
+		in, out := &in.Image, &out.Image
+		*out = new(ExporterJarImage)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterJarSource.
+func (in *ExporterJarSource) DeepCopy() *ExporterJarSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterJarSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExporterSpec) DeepCopyInto(out *ExporterSpec) {
+	*out = *in
+	if in.Elasticsearch != nil {
+		in, out := &in.Elasticsearch, &out.Elasticsearch
+		*out = new(SearchExporter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OpenSearch != nil {
+		in, out := &in.OpenSearch, &out.OpenSearch
+		*out = new(SearchExporter)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Generic != nil {
+		in, out := &in.Generic, &out.Generic
+		*out = new(GenericExporter)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExporterSpec.
+func (in *ExporterSpec) DeepCopy() *ExporterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ExporterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalSecondaryStorage) DeepCopyInto(out *ExternalSecondaryStorage) {
+	*out = *in
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(BasicAuthentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CACertificate != nil {
+		in, out := &in.CACertificate, &out.CACertificate
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalSecondaryStorage.
+func (in *ExternalSecondaryStorage) DeepCopy() *ExternalSecondaryStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalSecondaryStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GCSBackupStore) DeepCopyInto(out *GCSBackupStore) {
+	*out = *in
+	if in.Credentials != nil {
+		in, out := &in.Credentials, &out.Credentials
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GCSBackupStore.
+func (in *GCSBackupStore) DeepCopy() *GCSBackupStore {
+	if in == nil {
+		return nil
+	}
+	out := new(GCSBackupStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GatewaySpec) DeepCopyInto(out *GatewaySpec) {
+	*out = *in
+	in.Backend.DeepCopyInto(&out.Backend)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GatewaySpec.
+func (in *GatewaySpec) DeepCopy() *GatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GenericExporter) DeepCopyInto(out *GenericExporter) {
+	*out = *in
+	if in.Jar != nil {
+		in, out := &in.Jar, &out.Jar
+		*out = new(ExporterJarSource)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GenericExporter.
+func (in *GenericExporter) DeepCopy() *GenericExporter {
+	if in == nil {
+		return nil
+	}
+	out := new(GenericExporter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Identity) DeepCopyInto(out *Identity) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Identity.
+func (in *Identity) DeepCopy() *Identity {
+	if in == nil {
+		return nil
+	}
+	out := new(Identity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Identity) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityList) DeepCopyInto(out *IdentityList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Identity, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityList.
+func (in *IdentityList) DeepCopy() *IdentityList {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IdentityList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentitySpec) DeepCopyInto(out *IdentitySpec) {
+	*out = *in
+	in.Keycloak.DeepCopyInto(&out.Keycloak)
+	in.Backend.DeepCopyInto(&out.Backend)
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentitySpec.
+func (in *IdentitySpec) DeepCopy() *IdentitySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentitySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdentityStatus) DeepCopyInto(out *IdentityStatus) {
+	*out = *in
+	in.WebAppStatus.DeepCopyInto(&out.WebAppStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdentityStatus.
+func (in *IdentityStatus) DeepCopy() *IdentityStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IdentityStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IngressSpec) DeepCopyInto(out *IngressSpec) {
+	*out = *in
+	if in.ClassName != nil {
+		in, out := &in.ClassName, &out.ClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IngressSpec.
+func (in *IngressSpec) DeepCopy() *IngressSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IngressSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KeycloakSpec) DeepCopyInto(out *KeycloakSpec) {
+	*out = *in
+	if in.Admin != nil {
+		in, out := &in.Admin, &out.Admin
+		*out = new(BasicAuthentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KeycloakSpec.
+func (in *KeycloakSpec) DeepCopy() *KeycloakSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KeycloakSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkConfig) DeepCopyInto(out *NetworkConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfig.
+func (in *NetworkConfig) DeepCopy() *NetworkConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Operate) DeepCopyInto(out *Operate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Operate.
+func (in *Operate) DeepCopy() *Operate {
+	if in == nil {
+		return nil
+	}
+	out := new(Operate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Operate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperateList) DeepCopyInto(out *OperateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Operate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperateList.
+func (in *OperateList) DeepCopy() *OperateList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperateSpec) DeepCopyInto(out *OperateSpec) {
+	*out = *in
+	in.Backend.DeepCopyInto(&out.Backend)
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperateSpec.
+func (in *OperateSpec) DeepCopy() *OperateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Optimize) DeepCopyInto(out *Optimize) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Optimize.
+func (in *Optimize) DeepCopy() *Optimize {
+	if in == nil {
+		return nil
+	}
+	out := new(Optimize)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Optimize) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizeIdentity) DeepCopyInto(out *OptimizeIdentity) {
+	*out = *in
+	in.ClientSecret.DeepCopyInto(&out.ClientSecret)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizeIdentity.
+func (in *OptimizeIdentity) DeepCopy() *OptimizeIdentity {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizeIdentity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizeList) DeepCopyInto(out *OptimizeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Optimize, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizeList.
+func (in *OptimizeList) DeepCopy() *OptimizeList {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OptimizeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OptimizeSpec) DeepCopyInto(out *OptimizeSpec) {
+	*out = *in
+	in.Backend.DeepCopyInto(&out.Backend)
+	if in.Identity != nil {
+		in, out := &in.Identity, &out.Identity
+		*out = new(OptimizeIdentity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OptimizeSpec.
+func (in *OptimizeSpec) DeepCopy() *OptimizeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OptimizeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitionBackupStatus) DeepCopyInto(out *PartitionBackupStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitionBackupStatus.
+func (in *PartitionBackupStatus) DeepCopy() *PartitionBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitionBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PartitionsSpec) DeepCopyInto(out *PartitionsSpec) {
+	*out = *in
+	if in.Count != nil {
+		in, out := &in.Count, &out.Count
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Replication != nil {
+		in, out := &in.Replication, &out.Replication
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PartitionsSpec.
+func (in *PartitionsSpec) DeepCopy() *PartitionsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PartitionsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlatformWebAppSpec) DeepCopyInto(out *PlatformWebAppSpec) {
+	*out = *in
+	in.Backend.DeepCopyInto(&out.Backend)
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlatformWebAppSpec.
+func (in *PlatformWebAppSpec) DeepCopy() *PlatformWebAppSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlatformWebAppSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3BackupStore) DeepCopyInto(out *S3BackupStore) {
+	*out = *in
+	if in.ForcePathStyleAccess != nil {
+		in, out := &in.ForcePathStyleAccess, &out.ForcePathStyleAccess
+		*out = new(bool)
+		**out = **in
+	}
+	if in.AccessKey != nil {
+		in, out := &in.AccessKey, &out.AccessKey
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKey != nil {
+		in, out := &in.SecretKey, &out.SecretKey
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3BackupStore.
+func (in *S3BackupStore) DeepCopy() *S3BackupStore {
+	if in == nil {
+		return nil
+	}
+	out := new(S3BackupStore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScalingStatus) DeepCopyInto(out *ScalingStatus) {
+	*out = *in
+	if in.ChangeID != nil {
+		in, out := &in.ChangeID, &out.ChangeID
+		*out = new(int64)
+		**out = **in
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScalingStatus.
+func (in *ScalingStatus) DeepCopy() *ScalingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ScalingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SearchExporter) DeepCopyInto(out *SearchExporter) {
+	*out = *in
+	in.Bulk.DeepCopyInto(&out.Bulk)
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(BasicAuthentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CACertificate != nil {
+		in, out := &in.CACertificate, &out.CACertificate
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SearchExporter.
+func (in *SearchExporter) DeepCopy() *SearchExporter {
+	if in == nil {
+		return nil
+	}
+	out := new(SearchExporter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryStorage) DeepCopyInto(out *SecondaryStorage) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryStorage.
+func (in *SecondaryStorage) DeepCopy() *SecondaryStorage {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryStorage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecondaryStorage) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryStorageList) DeepCopyInto(out *SecondaryStorageList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SecondaryStorage, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryStorageList.
+func (in *SecondaryStorageList) DeepCopy() *SecondaryStorageList {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryStorageList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *SecondaryStorageList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryStorageSpec) DeepCopyInto(out *SecondaryStorageSpec) {
+	*out = *in
+	if in.External != nil {
+		in, out := &in.External, &out.External
+		*out = new(ExternalSecondaryStorage)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Backend.DeepCopyInto(&out.Backend)
+	in.Storage.DeepCopyInto(&out.Storage)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryStorageSpec.
+func (in *SecondaryStorageSpec) DeepCopy() *SecondaryStorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryStorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecondaryStorageStatus) DeepCopyInto(out *SecondaryStorageStatus) {
+	*out = *in
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(BasicAuthentication)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CACertificate != nil {
+		in, out := &in.CACertificate, &out.CACertificate
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecondaryStorageStatus.
+func (in *SecondaryStorageStatus) DeepCopy() *SecondaryStorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SecondaryStorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StorageSpec) DeepCopyInto(out *StorageSpec) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Size != nil {
+		in, out := &in.Size, &out.Size
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.AccessModes != nil {
+		in, out := &in.AccessModes, &out.AccessModes
+		*out = make([]corev1.PersistentVolumeAccessMode, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageSpec.
+func (in *StorageSpec) DeepCopy() *StorageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StorageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Tasklist) DeepCopyInto(out *Tasklist) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Tasklist.
+func (in *Tasklist) DeepCopy() *Tasklist {
+	if in == nil {
+		return nil
+	}
+	out := new(Tasklist)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Tasklist) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TasklistList) DeepCopyInto(out *TasklistList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Tasklist, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TasklistList.
+func (in *TasklistList) DeepCopy() *TasklistList {
+	if in == nil {
+		return nil
+	}
+	out := new(TasklistList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *TasklistList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TasklistSpec) DeepCopyInto(out *TasklistSpec) {
+	*out = *in
+	in.Backend.DeepCopyInto(&out.Backend)
+	if in.Ingress != nil {
+		in, out := &in.Ingress, &out.Ingress
+		*out = new(IngressSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TasklistSpec.
+func (in *TasklistSpec) DeepCopy() *TasklistSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TasklistSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ThreadsConfig) DeepCopyInto(out *ThreadsConfig) {
+	*out = *in
+	if in.CpuThreadCount != nil {
+		in, out := &in.CpuThreadCount, &out.CpuThreadCount
+		*out = new(int32)
+		**out = **in
+	}
+	if in.IoThreadCount != nil {
+		in, out := &in.IoThreadCount, &out.IoThreadCount
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ThreadsConfig.
+func (in *ThreadsConfig) DeepCopy() *ThreadsConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ThreadsConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	in.BrokerStartTime.DeepCopyInto(&out.BrokerStartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeStatus) DeepCopyInto(out *VolumeStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeStatus.
+func (in *VolumeStatus) DeepCopy() *VolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebAppStatus) DeepCopyInto(out *WebAppStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebAppStatus.
+func (in *WebAppStatus) DeepCopy() *WebAppStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WebAppStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Zeebe) DeepCopyInto(out *Zeebe) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Zeebe.
+func (in *Zeebe) DeepCopy() *Zeebe {
+	if in == nil {
+		return nil
+	}
+	out := new(Zeebe)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Zeebe) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackup) DeepCopyInto(out *ZeebeBackup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackup.
+func (in *ZeebeBackup) DeepCopy() *ZeebeBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeBackup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupList) DeepCopyInto(out *ZeebeBackupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ZeebeBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupList.
+func (in *ZeebeBackupList) DeepCopy() *ZeebeBackupList {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeBackupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupSchedule) DeepCopyInto(out *ZeebeBackupSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupSchedule.
+func (in *ZeebeBackupSchedule) DeepCopy() *ZeebeBackupSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeBackupSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupScheduleList) DeepCopyInto(out *ZeebeBackupScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ZeebeBackupSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupScheduleList.
+func (in *ZeebeBackupScheduleList) DeepCopy() *ZeebeBackupScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeBackupScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupScheduleSpec) DeepCopyInto(out *ZeebeBackupScheduleSpec) {
+	*out = *in
+	in.Retention.DeepCopyInto(&out.Retention)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupScheduleSpec.
+func (in *ZeebeBackupScheduleSpec) DeepCopy() *ZeebeBackupScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupScheduleStatus) DeepCopyInto(out *ZeebeBackupScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletedBackups != nil {
+		in, out := &in.CompletedBackups, &out.CompletedBackups
+		*out = make([]CompletedBackup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupScheduleStatus.
+func (in *ZeebeBackupScheduleStatus) DeepCopy() *ZeebeBackupScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupSpec) DeepCopyInto(out *ZeebeBackupSpec) {
+	*out = *in
+	if in.BackupID != nil {
+		in, out := &in.BackupID, &out.BackupID
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupSpec.
+func (in *ZeebeBackupSpec) DeepCopy() *ZeebeBackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeBackupStatus) DeepCopyInto(out *ZeebeBackupStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Partitions != nil {
+		in, out := &in.Partitions, &out.Partitions
+		*out = make([]PartitionBackupStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeBackupStatus.
+func (in *ZeebeBackupStatus) DeepCopy() *ZeebeBackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeBackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeList) DeepCopyInto(out *ZeebeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Zeebe, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeList.
+func (in *ZeebeList) DeepCopy() *ZeebeList {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeRestore) DeepCopyInto(out *ZeebeRestore) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeRestore.
+func (in *ZeebeRestore) DeepCopy() *ZeebeRestore {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeRestore)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeRestore) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeRestoreList) DeepCopyInto(out *ZeebeRestoreList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ZeebeRestore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeRestoreList.
+func (in *ZeebeRestoreList) DeepCopy() *ZeebeRestoreList {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeRestoreList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ZeebeRestoreList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeRestoreSpec) DeepCopyInto(out *ZeebeRestoreSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeRestoreSpec.
+func (in *ZeebeRestoreSpec) DeepCopy() *ZeebeRestoreSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeRestoreSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeRestoreStatus) DeepCopyInto(out *ZeebeRestoreStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeRestoreStatus.
+func (in *ZeebeRestoreStatus) DeepCopy() *ZeebeRestoreStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeRestoreStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeSpec) DeepCopyInto(out *ZeebeSpec) {
+	*out = *in
+	in.Broker.DeepCopyInto(&out.Broker)
+	in.Gateway.DeepCopyInto(&out.Gateway)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeSpec.
+func (in *ZeebeSpec) DeepCopy() *ZeebeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeStatus) DeepCopyInto(out *ZeebeStatus) {
+	*out = *in
+	if in.Scaling != nil {
+		in, out := &in.Scaling, &out.Scaling
+		*out = new(ScalingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Brokers != nil {
+		in, out := &in.Brokers, &out.Brokers
+		*out = make([]BrokerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeStatus.
+func (in *ZeebeStatus) DeepCopy() *ZeebeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZeebeVersion) DeepCopyInto(out *ZeebeVersion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZeebeVersion.
+func (in *ZeebeVersion) DeepCopy() *ZeebeVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(ZeebeVersion)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,371 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// CamundaPlatformReconciler reconciles a CamundaPlatform object
+type CamundaPlatformReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// platformComponent is a resource the operator creates for a section of a
+// CamundaPlatform
+type platformComponent struct {
+	// kind of the resource, which prefixes its condition in the platform status
+	kind string
+	// desired resource, nil if the section is not set
+	desired client.Object
+	// resource with only name and namespace set, to fetch and delete it
+	existing client.Object
+}
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=camundaplatforms,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=camundaplatforms/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=camundaplatforms/finalizers,verbs=update
+
+// Reconcile applies a resource for every section of the platform and removes
+// the resources of sections which were unset. The readiness of the resources
+// is aggregated into the status of the platform.
+func (r *CamundaPlatformReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var platform camundacloudv1.CamundaPlatform
+	if err := r.Get(ctx, req.NamespacedName, &platform); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	components := platformComponents(&platform)
+	err := r.reconcileComponents(ctx, &platform, components)
+	setPlatformStatus(&platform, components, err)
+	if statusErr := r.Status().Update(ctx, &platform); statusErr != nil {
+		logger.Error(statusErr, "unable to update status of CamundaPlatform")
+		if err == nil {
+			err = statusErr
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if platform.Status.Phase != camundacloudv1.PlatformPhaseRunning {
+		// check back until all components are ready
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileComponents applies the desired components, which leaves them with
+// their current status, and deletes the components of unset sections which
+// the platform created
+func (r *CamundaPlatformReconciler) reconcileComponents(ctx context.Context, platform *camundacloudv1.CamundaPlatform, components []platformComponent) error {
+	logger := log.FromContext(ctx)
+
+	for _, component := range components {
+		if component.desired == nil {
+			obj := component.existing
+			err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			if client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			// a resource of the same name the platform did not create is left alone
+			if err == nil && metav1.IsControlledBy(obj, platform) {
+				if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+					logger.Error(err, "unable to delete component", "kind", component.kind)
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := ctrl.SetControllerReference(platform, component.desired, r.Scheme); err != nil {
+			logger.Error(err, "unable to construct component", "kind", component.kind)
+			return err
+		}
+		result, err := applyObject(ctx, r.Client, r.Scheme, component.desired)
+		if err != nil {
+			logger.Error(err, "unable to apply component", "kind", component.kind)
+			return err
+		}
+		recordDrift(r.Recorder, platform, platform.Status.ObservedGeneration, component.desired, result)
+		logger.V(1).Info("applied component", "kind", component.kind, "name", component.desired.GetName())
+	}
+	return nil
+}
+
+// platformComponents returns the components of the platform in the order they
+// depend on each other. All of them are named after the platform.
+func platformComponents(platform *camundacloudv1.CamundaPlatform) []platformComponent {
+	objectMeta := metav1.ObjectMeta{Name: platform.Name, Namespace: platform.Namespace}
+	components := []platformComponent{
+		{kind: "SecondaryStorage", existing: &camundacloudv1.SecondaryStorage{ObjectMeta: objectMeta}},
+		{kind: "Identity", existing: &camundacloudv1.Identity{ObjectMeta: objectMeta}},
+		{kind: "Zeebe", existing: &camundacloudv1.Zeebe{ObjectMeta: objectMeta}},
+		{kind: "Operate", existing: &camundacloudv1.Operate{ObjectMeta: objectMeta}},
+		{kind: "Tasklist", existing: &camundacloudv1.Tasklist{ObjectMeta: objectMeta}},
+		{kind: "Optimize", existing: &camundacloudv1.Optimize{ObjectMeta: objectMeta}},
+	}
+
+	spec := platform.Spec
+	identity := createPlatformIdentity(platform)
+	if spec.SecondaryStorage != nil {
+		components[0].desired = &camundacloudv1.SecondaryStorage{ObjectMeta: objectMeta, Spec: *spec.SecondaryStorage.DeepCopy()}
+	}
+	if identity != nil {
+		components[1].desired = identity
+	}
+	components[2].desired = createPlatformZeebe(platform)
+	if spec.Operate != nil {
+		operate := &camundacloudv1.Operate{ObjectMeta: objectMeta}
+		operate.Spec.ZeebeRef = platform.Name
+		operate.Spec.Exporter = spec.Operate.Exporter
+		operate.Spec.Backend = platformBackend(platform, spec.Operate.Backend)
+		operate.Spec.Ingress = spec.Operate.Ingress.DeepCopy()
+		if identity != nil {
+			operate.Spec.IdentityRef = identity.Name
+		}
+		components[3].desired = operate
+	}
+	if spec.Tasklist != nil {
+		tasklist := &camundacloudv1.Tasklist{ObjectMeta: objectMeta}
+		tasklist.Spec.ZeebeRef = platform.Name
+		tasklist.Spec.Exporter = spec.Tasklist.Exporter
+		tasklist.Spec.Backend = platformBackend(platform, spec.Tasklist.Backend)
+		tasklist.Spec.Ingress = spec.Tasklist.Ingress.DeepCopy()
+		if identity != nil {
+			tasklist.Spec.IdentityRef = identity.Name
+		}
+		components[4].desired = tasklist
+	}
+	if spec.Optimize != nil {
+		components[5].desired = createPlatformOptimize(platform, identity)
+	}
+	return components
+}
+
+// createPlatformZeebe returns the Zeebe cluster of the platform. The brokers run
+// the platform version and export to the secondary storage of the platform,
+// and the gateway authenticates clients with the Identity of the platform.
+func createPlatformZeebe(platform *camundacloudv1.CamundaPlatform) *camundacloudv1.Zeebe {
+	zeebe := &camundacloudv1.Zeebe{
+		ObjectMeta: metav1.ObjectMeta{Name: platform.Name, Namespace: platform.Namespace},
+		Spec:       *platform.Spec.Zeebe.DeepCopy(),
+	}
+	broker := &zeebe.Spec.Broker
+	if broker.Backend.ImageTag == "" {
+		broker.Backend.ImageTag = platform.Spec.Version
+	}
+	if platform.Spec.Identity != nil && zeebe.Spec.Gateway.IdentityRef == "" {
+		zeebe.Spec.Gateway.IdentityRef = platform.Name
+	}
+
+	if storage := platform.Spec.SecondaryStorage; storage != nil {
+		for i := range broker.Exporters {
+			if searchExporter(&broker.Exporters[i]) != nil {
+				return zeebe
+			}
+		}
+		exporter := camundacloudv1.ExporterSpec{Name: "elasticsearch", Type: camundacloudv1.ExporterElasticsearch}
+		search := &camundacloudv1.SearchExporter{StorageRef: platform.Name}
+		if storage.Type == camundacloudv1.SecondaryStorageOpenSearch {
+			exporter.Name, exporter.Type, exporter.OpenSearch = "opensearch", camundacloudv1.ExporterOpenSearch, search
+		} else {
+			exporter.Elasticsearch = search
+		}
+		broker.Exporters = append(broker.Exporters, exporter)
+	}
+	return zeebe
+}
+
+// createPlatformIdentity returns the Identity of the platform, or nil if the
+// platform has none. Identity is released together with the platform, so it
+// runs the platform version.
+func createPlatformIdentity(platform *camundacloudv1.CamundaPlatform) *camundacloudv1.Identity {
+	if platform.Spec.Identity == nil {
+		return nil
+	}
+	identity := &camundacloudv1.Identity{
+		ObjectMeta: metav1.ObjectMeta{Name: platform.Name, Namespace: platform.Namespace},
+		Spec:       *platform.Spec.Identity.DeepCopy(),
+	}
+	identity.Spec.Backend = platformBackend(platform, identity.Spec.Backend)
+	return identity
+}
+
+// createPlatformOptimize returns the Optimize of the platform. Its version is
+// left to Optimize, which derives it from the brokers. Users log in with the
+// OAuth client Identity sets up for Optimize.
+func createPlatformOptimize(platform *camundacloudv1.CamundaPlatform, identity *camundacloudv1.Identity) *camundacloudv1.Optimize {
+	section := platform.Spec.Optimize
+	optimize := &camundacloudv1.Optimize{ObjectMeta: metav1.ObjectMeta{Name: platform.Name, Namespace: platform.Namespace}}
+	optimize.Spec.ZeebeRef = platform.Name
+	optimize.Spec.Exporter = section.Exporter
+	optimize.Spec.Backend = *section.Backend.DeepCopy()
+	optimize.Spec.Ingress = section.Ingress.DeepCopy()
+	if identity != nil {
+		optimize.Spec.Identity = &camundacloudv1.OptimizeIdentity{
+			IssuerURL:        identityIssuerURL(identity),
+			IssuerBackendURL: identityIssuerBackendURL(identity),
+			ClientID:         "optimize",
+			ClientSecret:     identityClientSecret(identity, "optimize"),
+			Audience:         "optimize-api",
+		}
+	}
+	return optimize
+}
+
+// platformBackend returns a copy of the backend of a section which runs the
+// platform version unless it names an image tag
+func platformBackend(platform *camundacloudv1.CamundaPlatform, backend camundacloudv1.BackendSpec) camundacloudv1.BackendSpec {
+	backend = *backend.DeepCopy()
+	if backend.ImageTag == "" {
+		backend.ImageTag = platform.Spec.Version
+	}
+	return backend
+}
+
+// platformVersion returns the version the components are asked to run
+func platformVersion(platform *camundacloudv1.CamundaPlatform) string {
+	if platform.Spec.Version != "" {
+		return platform.Spec.Version
+	}
+	if tag := platform.Spec.Zeebe.Broker.Backend.ImageTag; tag != "" {
+		return tag
+	}
+	return camundacloudv1.DefaultZeebeVersion
+}
+
+// componentState returns the generation a component last reconciled and its
+// conditions. All components share the Ready and Degraded condition types.
+func componentState(obj client.Object) (int64, []metav1.Condition) {
+	switch component := obj.(type) {
+	case *camundacloudv1.Zeebe:
+		return component.Status.ObservedGeneration, component.Status.Conditions
+	case *camundacloudv1.SecondaryStorage:
+		return component.Status.ObservedGeneration, component.Status.Conditions
+	case *camundacloudv1.Identity:
+		return component.Status.ObservedGeneration, component.Status.Conditions
+	case *camundacloudv1.Operate:
+		return component.Status.ObservedGeneration, component.Status.Conditions
+	case *camundacloudv1.Tasklist:
+		return component.Status.ObservedGeneration, component.Status.Conditions
+	case *camundacloudv1.Optimize:
+		return component.Status.ObservedGeneration, component.Status.Conditions
+	}
+	return 0, nil
+}
+
+// setPlatformStatus adds a condition per component with its readiness and
+// derives the readiness and phase of the platform. A component counts as not
+// ready until it reconciled its latest spec. A failed reconciliation is
+// reported as Degraded.
+func setPlatformStatus(platform *camundacloudv1.CamundaPlatform, components []platformComponent, reconcileErr error) {
+	status := &platform.Status
+	status.ObservedGeneration = platform.Generation
+	status.Version = platformVersion(platform)
+
+	if reconcileErr != nil {
+		setPlatformCondition(platform, camundacloudv1.PlatformConditionDegraded, metav1.ConditionTrue, "ReconcileFailed", reconcileErr.Error())
+		setPlatformCondition(platform, camundacloudv1.PlatformConditionReady, metav1.ConditionFalse, "ReconcileFailed", "The platform could not be reconciled")
+		status.Phase = camundacloudv1.PlatformPhaseDegraded
+		return
+	}
+
+	var notReady, degraded []string
+	for _, component := range components {
+		conditionType := component.kind + "Ready"
+		if component.desired == nil {
+			meta.RemoveStatusCondition(&status.Conditions, conditionType)
+			continue
+		}
+
+		observedGeneration, conditions := componentState(component.desired)
+		ready := meta.FindStatusCondition(conditions, "Ready")
+		switch {
+		case observedGeneration < component.desired.GetGeneration() || ready == nil:
+			setPlatformCondition(platform, conditionType, metav1.ConditionUnknown, "Reconciling", fmt.Sprintf("The %s has not reconciled its latest spec yet", component.kind))
+		default:
+			setPlatformCondition(platform, conditionType, ready.Status, ready.Reason, ready.Message)
+		}
+		if !meta.IsStatusConditionTrue(status.Conditions, conditionType) {
+			notReady = append(notReady, component.kind)
+		}
+		if condition := meta.FindStatusCondition(conditions, "Degraded"); condition != nil && condition.Status == metav1.ConditionTrue {
+			degraded = append(degraded, fmt.Sprintf("%s: %s", component.kind, condition.Message))
+		}
+	}
+
+	if len(degraded) > 0 {
+		setPlatformCondition(platform, camundacloudv1.PlatformConditionDegraded, metav1.ConditionTrue, "ComponentDegraded", strings.Join(degraded, "; "))
+	} else {
+		setPlatformCondition(platform, camundacloudv1.PlatformConditionDegraded, metav1.ConditionFalse, "Reconciled", "All components are reconciled")
+	}
+	if len(notReady) > 0 {
+		setPlatformCondition(platform, camundacloudv1.PlatformConditionReady, metav1.ConditionFalse, "ComponentsNotReady", "Waiting for "+strings.Join(notReady, ", "))
+	} else {
+		setPlatformCondition(platform, camundacloudv1.PlatformConditionReady, metav1.ConditionTrue, "ComponentsReady", "All components are ready")
+	}
+
+	switch {
+	case len(degraded) > 0:
+		status.Phase = camundacloudv1.PlatformPhaseDegraded
+	case len(notReady) > 0:
+		status.Phase = camundacloudv1.PlatformPhasePending
+	default:
+		status.Phase = camundacloudv1.PlatformPhaseRunning
+	}
+}
+
+func setPlatformCondition(platform *camundacloudv1.CamundaPlatform, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&platform.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: platform.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager. Changes of the
+// components, including their status, reconcile the platform they belong to.
+func (r *CamundaPlatformReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.CamundaPlatform{}).
+		Owns(&camundacloudv1.Zeebe{}).
+		Owns(&camundacloudv1.SecondaryStorage{}).
+		Owns(&camundacloudv1.Identity{}).
+		Owns(&camundacloudv1.Operate{}).
+		Owns(&camundacloudv1.Tasklist{}).
+		Owns(&camundacloudv1.Optimize{}).
+		Complete(r)
+}
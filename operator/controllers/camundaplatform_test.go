@@ -0,0 +1,172 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("CamundaPlatform", func() {
+	var platform *camundacloudv1.CamundaPlatform
+
+	BeforeEach(func() {
+		platform = &camundacloudv1.CamundaPlatform{
+			ObjectMeta: metav1.ObjectMeta{Name: "dev", Namespace: "team-1", UID: "platform-uid"},
+			Spec: camundacloudv1.CamundaPlatformSpec{
+				Version: "8.2.0",
+				Zeebe:   testZeebe("dev", 3).Spec,
+			},
+		}
+		platform.Spec.Zeebe.Broker.Backend.ImageTag = ""
+	})
+
+	// component returns the desired resource of a component
+	component := func(kind string) client.Object {
+		for _, component := range platformComponents(platform) {
+			if component.kind == kind {
+				return component.desired
+			}
+		}
+		return nil
+	}
+
+	It("only creates the Zeebe cluster without further sections", func() {
+		zeebe := component("Zeebe").(*camundacloudv1.Zeebe)
+		Expect(zeebe.Name).To(Equal("dev"))
+		Expect(zeebe.Spec.Broker.Backend.ImageTag).To(Equal("8.2.0"))
+		Expect(zeebe.Spec.Gateway.IdentityRef).To(BeEmpty())
+		Expect(zeebe.Spec.Broker.Exporters).To(BeEmpty())
+
+		for _, kind := range []string{"SecondaryStorage", "Identity", "Operate", "Tasklist", "Optimize"} {
+			Expect(component(kind)).To(BeNil(), kind)
+		}
+	})
+
+	It("keeps the versions of the components aligned", func() {
+		platform.Spec.Identity = &camundacloudv1.IdentitySpec{}
+		platform.Spec.Operate = &camundacloudv1.PlatformWebAppSpec{}
+		platform.Spec.Tasklist = &camundacloudv1.PlatformWebAppSpec{Backend: camundacloudv1.BackendSpec{ImageTag: "8.2.1"}}
+		platform.Spec.Optimize = &camundacloudv1.PlatformWebAppSpec{}
+
+		Expect(component("Zeebe").(*camundacloudv1.Zeebe).Spec.Broker.Backend.ImageTag).To(Equal("8.2.0"))
+		Expect(component("Identity").(*camundacloudv1.Identity).Spec.Backend.ImageTag).To(Equal("8.2.0"))
+		Expect(component("Operate").(*camundacloudv1.Operate).Spec.Backend.ImageTag).To(Equal("8.2.0"))
+		Expect(component("Tasklist").(*camundacloudv1.Tasklist).Spec.Backend.ImageTag).To(Equal("8.2.1"))
+		// Optimize derives its version from the brokers
+		Expect(component("Optimize").(*camundacloudv1.Optimize).Spec.Backend.ImageTag).To(BeEmpty())
+		Expect(platform.Spec.Operate.Backend.ImageTag).To(BeEmpty())
+	})
+
+	It("connects the components to the Zeebe cluster, storage and Identity of the platform", func() {
+		platform.Spec.SecondaryStorage = &camundacloudv1.SecondaryStorageSpec{Type: camundacloudv1.SecondaryStorageOpenSearch}
+		platform.Spec.Identity = &camundacloudv1.IdentitySpec{}
+		platform.Spec.Operate = &camundacloudv1.PlatformWebAppSpec{Ingress: &camundacloudv1.IngressSpec{Host: "operate.example.com"}}
+		platform.Spec.Optimize = &camundacloudv1.PlatformWebAppSpec{}
+
+		zeebe := component("Zeebe").(*camundacloudv1.Zeebe)
+		Expect(zeebe.Spec.Gateway.IdentityRef).To(Equal("dev"))
+		Expect(zeebe.Spec.Broker.Exporters).To(HaveLen(1))
+		Expect(zeebe.Spec.Broker.Exporters[0].Type).To(Equal(camundacloudv1.ExporterOpenSearch))
+		Expect(zeebe.Spec.Broker.Exporters[0].OpenSearch.StorageRef).To(Equal("dev"))
+		Expect(platform.Spec.Zeebe.Broker.Exporters).To(BeEmpty())
+
+		Expect(component("SecondaryStorage").(*camundacloudv1.SecondaryStorage).Spec.Type).To(Equal(camundacloudv1.SecondaryStorageOpenSearch))
+
+		operate := component("Operate").(*camundacloudv1.Operate)
+		Expect(operate.Spec.ZeebeRef).To(Equal("dev"))
+		Expect(operate.Spec.IdentityRef).To(Equal("dev"))
+		Expect(operate.Spec.Ingress.Host).To(Equal("operate.example.com"))
+
+		identity := component("Optimize").(*camundacloudv1.Optimize).Spec.Identity
+		Expect(identity.ClientID).To(Equal("optimize"))
+		Expect(identity.ClientSecret).To(Equal(secretKey("dev-identity-clients", "optimize")))
+		Expect(identity.IssuerBackendURL).To(Equal("http://dev-keycloak.team-1.svc.cluster.local:80/auth/realms/camunda-platform"))
+	})
+
+	It("keeps the search exporters of the brokers", func() {
+		platform.Spec.SecondaryStorage = &camundacloudv1.SecondaryStorageSpec{Type: camundacloudv1.SecondaryStorageElasticsearch}
+		platform.Spec.Zeebe.Broker.Exporters = []camundacloudv1.ExporterSpec{
+			{
+				Name:          "archive",
+				Type:          camundacloudv1.ExporterElasticsearch,
+				Elasticsearch: &camundacloudv1.SearchExporter{URL: "http://archive:9200"},
+			},
+		}
+
+		Expect(component("Zeebe").(*camundacloudv1.Zeebe).Spec.Broker.Exporters).To(Equal(platform.Spec.Zeebe.Broker.Exporters))
+	})
+
+	It("aggregates the conditions of the components", func() {
+		platform.Spec.Operate = &camundacloudv1.PlatformWebAppSpec{}
+		components := platformComponents(platform)
+		zeebe := components[2].desired.(*camundacloudv1.Zeebe)
+		operate := components[3].desired.(*camundacloudv1.Operate)
+
+		setPlatformStatus(platform, components, nil)
+		Expect(platform.Status.Phase).To(Equal(camundacloudv1.PlatformPhasePending))
+		Expect(platform.Status.Version).To(Equal("8.2.0"))
+		Expect(meta.FindStatusCondition(platform.Status.Conditions, "ZeebeReady").Reason).To(Equal("Reconciling"))
+		Expect(meta.FindStatusCondition(platform.Status.Conditions, "TasklistReady")).To(BeNil())
+
+		zeebe.Status.Conditions = []metav1.Condition{{Type: camundacloudv1.ZeebeConditionReady, Status: metav1.ConditionTrue, Reason: "BrokersReady"}}
+		operate.Status.Conditions = []metav1.Condition{
+			{Type: camundacloudv1.WebAppConditionReady, Status: metav1.ConditionFalse, Reason: "ExporterNotFound"},
+			{Type: camundacloudv1.WebAppConditionDegraded, Status: metav1.ConditionTrue, Reason: "ExporterNotFound", Message: "no exporter"},
+		}
+		setPlatformStatus(platform, components, nil)
+		Expect(platform.Status.Phase).To(Equal(camundacloudv1.PlatformPhaseDegraded))
+		Expect(meta.IsStatusConditionTrue(platform.Status.Conditions, "ZeebeReady")).To(BeTrue())
+		Expect(meta.FindStatusCondition(platform.Status.Conditions, "OperateReady").Reason).To(Equal("ExporterNotFound"))
+		Expect(meta.FindStatusCondition(platform.Status.Conditions, camundacloudv1.PlatformConditionDegraded).Message).To(Equal("Operate: no exporter"))
+
+		operate.Status.Conditions = []metav1.Condition{{Type: camundacloudv1.WebAppConditionReady, Status: metav1.ConditionTrue, Reason: "ReplicasReady"}}
+		setPlatformStatus(platform, components, nil)
+		Expect(platform.Status.Phase).To(Equal(camundacloudv1.PlatformPhaseRunning))
+		Expect(meta.IsStatusConditionTrue(platform.Status.Conditions, camundacloudv1.PlatformConditionReady)).To(BeTrue())
+	})
+
+	It("only removes components it created", func() {
+		owned := &camundacloudv1.Operate{ObjectMeta: metav1.ObjectMeta{Name: "dev", Namespace: "team-1"}}
+		foreign := &camundacloudv1.Tasklist{ObjectMeta: metav1.ObjectMeta{Name: "dev", Namespace: "team-1"}}
+
+		ctx := context.Background()
+		s := testScheme()
+		Expect(ctrl.SetControllerReference(platform, owned, s)).To(Succeed())
+		reconciler := &CamundaPlatformReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(owned, foreign).Build(),
+			Scheme: s,
+		}
+
+		components := platformComponents(platform)
+		// the Zeebe cluster is always desired, which the fake client cannot apply
+		Expect(reconciler.reconcileComponents(ctx, platform, components[:2])).To(Succeed())
+		Expect(reconciler.reconcileComponents(ctx, platform, components[3:])).To(Succeed())
+
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(owned), &camundacloudv1.Operate{})).NotTo(Succeed())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(foreign), &camundacloudv1.Tasklist{})).To(Succeed())
+	})
+})
@@ -0,0 +1,116 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// applyResult tells how applying an object changed it
+type applyResult struct {
+	// kind of the applied object
+	kind string
+	// whether the object did not exist before
+	created bool
+	// whether applying changed an existing object
+	updated bool
+	// fields other field managers had changed, which the operator took back,
+	// empty if there were none
+	drift []string
+}
+
+// applyObject server-side applies the given object as the operator, so every
+// reconciler owns the same fields of the objects it creates. Fields someone
+// else changed in the meantime conflict with the operator and are taken back.
+func applyObject(ctx context.Context, c client.Client, scheme *runtime.Scheme, obj client.Object) (applyResult, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return applyResult{}, err
+	}
+	result := applyResult{kind: gvk.Kind}
+
+	existing, err := scheme.New(gvk)
+	if err != nil {
+		return result, err
+	}
+	err = c.Get(ctx, client.ObjectKeyFromObject(obj), existing.(client.Object))
+	if client.IgnoreNotFound(err) != nil {
+		return result, err
+	}
+	result.created = err != nil
+	resourceVersion := existing.(client.Object).GetResourceVersion()
+
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetManagedFields(nil)
+
+	err = c.Patch(ctx, obj, client.Apply, fieldOwner)
+	if errors.IsConflict(err) {
+		result.drift = driftedFields(err)
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+		err = c.Patch(ctx, obj, client.Apply, fieldOwner, client.ForceOwnership)
+	}
+	result.updated = !result.created && obj.GetResourceVersion() != resourceVersion
+	return result, err
+}
+
+// driftedFields lists the fields of an apply conflict with the field managers
+// which changed them
+func driftedFields(err error) []string {
+	var fields []string
+	if status, ok := err.(errors.APIStatus); ok && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			if cause.Type != metav1.CauseTypeFieldManagerConflict {
+				continue
+			}
+			fields = append(fields, fmt.Sprintf("%s changed by %s", cause.Field, strings.TrimPrefix(cause.Message, "conflict with ")))
+		}
+	}
+	if len(fields) == 0 {
+		fields = append(fields, err.Error())
+	}
+	return fields
+}
+
+// recordDrift reports out of band changes of an object which applying it
+// reverted. An object which is missing although the owner was already
+// reconciled in its current generation was deleted by hand. Returns whether
+// there was drift to report.
+func recordDrift(recorder record.EventRecorder, owner client.Object, observedGeneration int64, obj client.Object, result applyResult) bool {
+	var message string
+	switch {
+	case result.created && observedGeneration == owner.GetGeneration():
+		message = fmt.Sprintf("Recreated deleted %s %s", result.kind, obj.GetName())
+	case len(result.drift) > 0:
+		message = fmt.Sprintf("Restored %s %s: %s", result.kind, obj.GetName(), strings.Join(result.drift, ", "))
+	default:
+		return false
+	}
+	if recorder != nil {
+		recorder.Event(owner, v12.EventTypeWarning, "DriftCorrected", message)
+	}
+	return true
+}
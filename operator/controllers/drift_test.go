@@ -0,0 +1,132 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// applyClient stands in for server-side apply, which the fake client does not
+// support. Applying without force conflicts on the configured fields.
+type applyClient struct {
+	client.Client
+	conflicts []metav1.StatusCause
+	forced    bool
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	patchOptions := &client.PatchOptions{}
+	patchOptions.ApplyOptions(opts)
+	if patchOptions.Force != nil && *patchOptions.Force {
+		c.forced = true
+		return nil
+	}
+	if len(c.conflicts) > 0 {
+		return errors.NewApplyConflict(c.conflicts, "Apply failed")
+	}
+	return nil
+}
+
+// upsertClient stands in for server-side apply by creating or replacing the
+// applied object, which lets whole reconciliations run against the fake client
+type upsertClient struct {
+	client.Client
+}
+
+func (c *upsertClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch != client.Apply {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	existing := obj.DeepCopyObject().(client.Object)
+	err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+	if errors.IsNotFound(err) {
+		return c.Create(ctx, obj)
+	}
+	if err != nil {
+		return err
+	}
+	obj.SetResourceVersion(existing.GetResourceVersion())
+	return c.Update(ctx, obj)
+}
+
+var _ = Describe("Drift", func() {
+	var (
+		ctx      context.Context
+		service  *v12.Service
+		recorder *record.FakeRecorder
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		service = &v12.Service{ObjectMeta: metav1.ObjectMeta{Name: "cluster-1-gateway", Namespace: "team-1"}}
+		recorder = record.NewFakeRecorder(10)
+	})
+
+	It("takes back fields other field managers changed", func() {
+		c := &applyClient{
+			Client: fake.NewClientBuilder().WithObjects(service.DeepCopy()).Build(),
+			conflicts: []metav1.StatusCause{{
+				Type:    metav1.CauseTypeFieldManagerConflict,
+				Field:   ".spec.type",
+				Message: `conflict with "kubectl-edit" using v1`,
+			}},
+		}
+
+		result, err := applyObject(ctx, c, testScheme(), service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.forced).To(BeTrue())
+		Expect(result.created).To(BeFalse())
+		Expect(result.drift).To(Equal([]string{`.spec.type changed by "kubectl-edit" using v1`}))
+
+		zeebe := testZeebe("cluster-1", 3)
+		recordDrift(recorder, zeebe, zeebe.Status.ObservedGeneration, service, result)
+		Expect(recorder.Events).To(Receive(Equal(`Warning DriftCorrected Restored Service cluster-1-gateway: .spec.type changed by "kubectl-edit" using v1`)))
+	})
+
+	It("does not force unchanged objects", func() {
+		c := &applyClient{Client: fake.NewClientBuilder().WithObjects(service.DeepCopy()).Build()}
+
+		result, err := applyObject(ctx, c, testScheme(), service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.forced).To(BeFalse())
+		Expect(result.drift).To(BeEmpty())
+	})
+
+	It("reports objects deleted after the owner was reconciled", func() {
+		c := &applyClient{Client: fake.NewClientBuilder().Build()}
+		result, err := applyObject(ctx, c, testScheme(), service)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.created).To(BeTrue())
+
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Generation = 2
+		recordDrift(recorder, zeebe, 1, service, result)
+		Expect(recorder.Events).NotTo(Receive())
+
+		recordDrift(recorder, zeebe, 2, service, result)
+		Expect(recorder.Events).To(Receive(Equal("Warning DriftCorrected Recreated deleted Service cluster-1-gateway")))
+	})
+})
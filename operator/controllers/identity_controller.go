@@ -0,0 +1,583 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// IdentityReconciler reconciles a Identity object
+type IdentityReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Images Identity and Keycloak run if the spec does not name them
+const (
+	identityImageName      = "camunda/identity"
+	defaultIdentityVersion = "8.4.0"
+	defaultKeycloakImage   = "quay.io/keycloak/keycloak:19.0.3"
+)
+
+// identityRealm is the Keycloak realm Identity sets up for the components
+const identityRealm = "camunda-platform"
+
+// identityClients are the components Identity sets up OAuth clients for. Their
+// secrets are generated into the client Secret of the Identity.
+var identityClients = []string{"zeebe", "operate", "tasklist", "optimize"}
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=identities,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=identities/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=identities/finalizers,verbs=update
+
+// Generate the client secrets and the Keycloak admin user
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=create;update
+
+// Reconcile applies the Deployment, Service and optional Ingress of Identity and,
+// unless an existing Keycloak is given, of a Keycloak. The secrets of the OAuth
+// clients are generated once and kept in a Secret, which Identity sets up the
+// clients with and the components authenticate with.
+func (r *IdentityReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var identity camundacloudv1.Identity
+	if err := r.Get(ctx, req.NamespacedName, &identity); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	app := identityApp(&identity)
+	keycloak := &identity.Spec.Keycloak
+	if keycloak.URL != "" && keycloak.Admin == nil {
+		return degradeWebApp(ctx, r.Client, app, "KeycloakAdminMissing", "The admin user of the existing Keycloak is required")
+	}
+
+	rootURLs, err := r.clientRootURLs(ctx, &identity)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	deployment, err := r.reconcileIdentity(ctx, &identity, rootURLs)
+	setWebAppStatus(app, deployment, identityVersion(&identity), err)
+	identity.Status.IssuerURL = identityIssuerURL(&identity)
+	identity.Status.ClientSecretName = identityClientSecretName(&identity)
+	if statusErr := r.Status().Update(ctx, &identity); statusErr != nil {
+		logger.Error(statusErr, "unable to update status of Identity")
+		if err == nil {
+			err = statusErr
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if identity.Status.Phase != camundacloudv1.WebAppPhaseRunning {
+		// check back until Identity and Keycloak are rolled out and ready
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileIdentity generates the secrets, applies the objects of Keycloak and
+// Identity and returns the Identity Deployment as seen by the API server.
+// Keycloak is only reported ready through Identity, which cannot start without it.
+func (r *IdentityReconciler) reconcileIdentity(ctx context.Context, identity *camundacloudv1.Identity, rootURLs map[string]string) (*v1.Deployment, error) {
+	logger := log.FromContext(ctx)
+
+	if err := generateSecret(ctx, r.Client, r.Scheme, identity, identityClientSecretName(identity), nil, identityClients); err != nil {
+		logger.Error(err, "unable to generate client secrets of Identity")
+		return nil, err
+	}
+
+	keycloak := keycloakApp(identity)
+	if identity.Spec.Keycloak.URL == "" {
+		if identity.Spec.Keycloak.Admin == nil {
+			if err := generateSecret(ctx, r.Client, r.Scheme, identity, keycloakAdminSecretName(identity), map[string]string{"username": "admin"}, []string{"password"}); err != nil {
+				logger.Error(err, "unable to generate Keycloak admin user")
+				return nil, err
+			}
+		}
+		if _, err := applyWebApp(ctx, r.Client, r.Scheme, r.Recorder, keycloak, createKeycloakDeployment(identity)); err != nil {
+			return nil, err
+		}
+	} else {
+		// an existing Keycloak replaces the one the operator ran before
+		for _, obj := range []client.Object{createKeycloakDeployment(identity), createWebAppService(keycloak), createWebAppIngress(keycloak)} {
+			err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			if client.IgnoreNotFound(err) != nil {
+				return nil, err
+			}
+			// an object of the same name the Identity did not create is left alone
+			if err != nil || !metav1.IsControlledBy(obj, identity) {
+				continue
+			}
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "unable to delete Keycloak object", "name", obj.GetName())
+				return nil, err
+			}
+		}
+	}
+
+	return applyWebApp(ctx, r.Client, r.Scheme, r.Recorder, identityApp(identity), createIdentityDeployment(identity, rootURLs))
+}
+
+// generateSecret creates a Secret owned by the given resource with the given
+// values and a random value for each of the given keys, and adds the keys which
+// are missing in an existing Secret. Existing values are never changed, since
+// they are in use.
+func generateSecret(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, name string, values map[string]string, keys []string) error {
+	var secret v12.Secret
+	err := c.Get(ctx, client.ObjectKey{Namespace: owner.GetNamespace(), Name: name}, &secret)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if !exists {
+		secret = v12.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: owner.GetNamespace()}}
+		if err := ctrl.SetControllerReference(owner, &secret, scheme); err != nil {
+			return err
+		}
+	}
+
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	changed := false
+	for key, value := range values {
+		if _, ok := secret.Data[key]; !ok {
+			secret.Data[key] = []byte(value)
+			changed = true
+		}
+	}
+	for _, key := range keys {
+		if _, ok := secret.Data[key]; ok {
+			continue
+		}
+		value, err := randomSecret()
+		if err != nil {
+			return err
+		}
+		secret.Data[key] = []byte(value)
+		changed = true
+	}
+
+	switch {
+	case !exists:
+		return c.Create(ctx, &secret)
+	case changed:
+		return c.Update(ctx, &secret)
+	}
+	return nil
+}
+
+// clientRootURLs returns the public URLs of the web apps which authenticate
+// their users with the Identity, keyed by component. Keycloak only redirects
+// users back to these URLs after they logged in.
+func (r *IdentityReconciler) clientRootURLs(ctx context.Context, identity *camundacloudv1.Identity) (map[string]string, error) {
+	rootURLs := map[string]string{}
+
+	var operates camundacloudv1.OperateList
+	if err := r.List(ctx, &operates, client.InNamespace(identity.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, operate := range operates.Items {
+		if operate.Spec.IdentityRef == identity.Name && operate.Spec.Ingress != nil {
+			rootURLs["operate"] = ingressURL(operate.Spec.Ingress)
+		}
+	}
+
+	var tasklists camundacloudv1.TasklistList
+	if err := r.List(ctx, &tasklists, client.InNamespace(identity.Namespace)); err != nil {
+		return nil, err
+	}
+	for _, tasklist := range tasklists.Items {
+		if tasklist.Spec.IdentityRef == identity.Name && tasklist.Spec.Ingress != nil {
+			rootURLs["tasklist"] = ingressURL(tasklist.Spec.Ingress)
+		}
+	}
+	return rootURLs, nil
+}
+
+func createIdentityDeployment(identity *camundacloudv1.Identity, rootURLs map[string]string) *v1.Deployment {
+	app := identityApp(identity)
+	backendSpec := identity.Spec.Backend
+	imageName := backendSpec.ImageName
+	if imageName == "" {
+		imageName = identityImageName
+	}
+	replicas := backendSpec.Replicas
+	if replicas == nil {
+		replicas = getIntPointer(1)
+	}
+
+	admin := keycloakAdmin(identity)
+	envs := []v12.EnvVar{
+		{
+			Name:  "KEYCLOAK_URL",
+			Value: keycloakURL(identity),
+		},
+		{
+			Name:      "KEYCLOAK_SETUP_USER",
+			ValueFrom: &v12.EnvVarSource{SecretKeyRef: &admin.Username},
+		},
+		{
+			Name:      "KEYCLOAK_SETUP_PASSWORD",
+			ValueFrom: &v12.EnvVarSource{SecretKeyRef: &admin.Password},
+		},
+		{
+			Name:  "IDENTITY_AUTH_PROVIDER_ISSUER_URL",
+			Value: identityIssuerURL(identity),
+		},
+		{
+			Name:  "IDENTITY_AUTH_PROVIDER_BACKEND_URL",
+			Value: identityIssuerBackendURL(identity),
+		},
+	}
+	if identity.Spec.Ingress != nil {
+		envs = append(envs, v12.EnvVar{Name: "IDENTITY_URL", Value: ingressURL(identity.Spec.Ingress)})
+	}
+
+	for _, component := range identityClients {
+		secret := identityClientSecret(identity, component)
+		envs = append(envs, v12.EnvVar{
+			Name:      fmt.Sprintf("KEYCLOAK_INIT_%s_SECRET", strings.ToUpper(component)),
+			ValueFrom: &v12.EnvVarSource{SecretKeyRef: &secret},
+		})
+	}
+	components := make([]string, 0, len(rootURLs))
+	for component := range rootURLs {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		envs = append(envs, v12.EnvVar{
+			Name:  fmt.Sprintf("KEYCLOAK_INIT_%s_ROOT_URL", strings.ToUpper(component)),
+			Value: rootURLs[component],
+		})
+	}
+
+	for _, env := range backendSpec.OverrideEnv {
+		envs = append(envs, env)
+	}
+
+	labels := webAppLabels(app)
+	return &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      webAppName(app),
+			Namespace: identity.Namespace,
+		},
+		Spec: v1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Replicas: replicas,
+			Template: v12.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{
+						{
+							Name:            app.component,
+							Image:           fmt.Sprintf("%s:%s", imageName, identityVersion(identity)),
+							ImagePullPolicy: v12.PullAlways,
+							Env:             envs,
+							Ports: []v12.ContainerPort{
+								{
+									ContainerPort: 8080,
+									Name:          "http",
+								},
+								{
+									ContainerPort: 8082,
+									Name:          "management",
+								},
+							},
+							ReadinessProbe: &v12.Probe{
+								Handler: v12.Handler{
+									HTTPGet: &v12.HTTPGetAction{
+										Path: "/actuator/health",
+										Port: intstr.IntOrString{
+											IntVal: 8082,
+										},
+									},
+								},
+								PeriodSeconds:    10,
+								SuccessThreshold: 1,
+								TimeoutSeconds:   1,
+							},
+							Resources: backendSpec.Resources,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// createKeycloakDeployment returns the Deployment of the Keycloak the operator
+// runs. It serves under /auth, where Identity expects Keycloak.
+func createKeycloakDeployment(identity *camundacloudv1.Identity) *v1.Deployment {
+	app := keycloakApp(identity)
+	image := identity.Spec.Keycloak.Image
+	if image == "" {
+		image = defaultKeycloakImage
+	}
+	admin := keycloakAdmin(identity)
+	labels := webAppLabels(app)
+
+	return &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      webAppName(app),
+			Namespace: identity.Namespace,
+		},
+		Spec: v1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Replicas: getIntPointer(1),
+			Template: v12.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{
+						{
+							Name:  app.component,
+							Image: image,
+							Args:  []string{"start-dev", "--http-relative-path=/auth"},
+							Env: []v12.EnvVar{
+								{
+									Name:      "KEYCLOAK_ADMIN",
+									ValueFrom: &v12.EnvVarSource{SecretKeyRef: &admin.Username},
+								},
+								{
+									Name:      "KEYCLOAK_ADMIN_PASSWORD",
+									ValueFrom: &v12.EnvVarSource{SecretKeyRef: &admin.Password},
+								},
+								{
+									Name:  "KC_PROXY",
+									Value: "edge",
+								},
+							},
+							Ports: []v12.ContainerPort{
+								{
+									ContainerPort: 8080,
+									Name:          "http",
+								},
+							},
+							ReadinessProbe: &v12.Probe{
+								Handler: v12.Handler{
+									HTTPGet: &v12.HTTPGetAction{
+										Path: "/auth/realms/master",
+										Port: intstr.IntOrString{
+											IntVal: 8080,
+										},
+									},
+								},
+								PeriodSeconds:    10,
+								SuccessThreshold: 1,
+								TimeoutSeconds:   1,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// identityApp describes Identity as web app, which shares the Service, Ingress
+// and status handling of the web apps
+func identityApp(identity *camundacloudv1.Identity) *webApp {
+	return &webApp{
+		owner:     identity,
+		component: "identity",
+		backend:   identity.Spec.Backend,
+		ingress:   identity.Spec.Ingress,
+		status:    &identity.Status.WebAppStatus,
+	}
+}
+
+// keycloakApp describes the Keycloak run by the operator. Its status is not
+// reported separately and only carries the generation of the last reconciled
+// Identity.
+func keycloakApp(identity *camundacloudv1.Identity) *webApp {
+	return &webApp{
+		owner:     identity,
+		component: "keycloak",
+		ingress:   identity.Spec.Keycloak.Ingress,
+		status:    &camundacloudv1.WebAppStatus{ObservedGeneration: identity.Status.ObservedGeneration},
+	}
+}
+
+// identityVersion returns the image tag Identity runs
+func identityVersion(identity *camundacloudv1.Identity) string {
+	if identity.Spec.Backend.ImageTag != "" {
+		return identity.Spec.Backend.ImageTag
+	}
+	return defaultIdentityVersion
+}
+
+// keycloakURL returns the URL Identity and the components reach Keycloak at,
+// including its context path
+func keycloakURL(identity *camundacloudv1.Identity) string {
+	if url := identity.Spec.Keycloak.URL; url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:80/auth", webAppName(keycloakApp(identity)), identity.Namespace)
+}
+
+// keycloakPublicURL returns the URL browsers reach Keycloak at
+func keycloakPublicURL(identity *camundacloudv1.Identity) string {
+	keycloak := identity.Spec.Keycloak
+	switch {
+	case keycloak.PublicURL != "":
+		return strings.TrimSuffix(keycloak.PublicURL, "/")
+	case keycloak.URL == "" && keycloak.Ingress != nil:
+		scheme := "http"
+		if keycloak.Ingress.TLSSecretName != "" {
+			scheme = "https"
+		}
+		return fmt.Sprintf("%s://%s/auth", scheme, keycloak.Ingress.Host)
+	}
+	return keycloakURL(identity)
+}
+
+// keycloakAdmin returns the Secret keys of the Keycloak admin user
+func keycloakAdmin(identity *camundacloudv1.Identity) camundacloudv1.BasicAuthentication {
+	if admin := identity.Spec.Keycloak.Admin; admin != nil {
+		return *admin
+	}
+	secret := v12.LocalObjectReference{Name: keycloakAdminSecretName(identity)}
+	return camundacloudv1.BasicAuthentication{
+		Username: v12.SecretKeySelector{LocalObjectReference: secret, Key: "username"},
+		Password: v12.SecretKeySelector{LocalObjectReference: secret, Key: "password"},
+	}
+}
+
+// identityIssuerURL returns the issuer of the tokens as seen by the browsers
+func identityIssuerURL(identity *camundacloudv1.Identity) string {
+	return keycloakPublicURL(identity) + "/realms/" + identityRealm
+}
+
+// identityIssuerBackendURL returns the issuer of the tokens as seen by the
+// components
+func identityIssuerBackendURL(identity *camundacloudv1.Identity) string {
+	return keycloakURL(identity) + "/realms/" + identityRealm
+}
+
+// identityTokenURL returns the URL components get their tokens from
+func identityTokenURL(identity *camundacloudv1.Identity) string {
+	return identityIssuerBackendURL(identity) + "/protocol/openid-connect/token"
+}
+
+// identityURL returns the URL the components reach Identity at
+func identityURL(identity *camundacloudv1.Identity) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:80", webAppName(identityApp(identity)), identity.Namespace)
+}
+
+// identityClientSecretName returns the name of the generated Secret holding the
+// OAuth client secrets
+func identityClientSecretName(identity *camundacloudv1.Identity) string {
+	return identity.Name + "-identity-clients"
+}
+
+// identityClientSecret returns the Secret key holding the OAuth client secret
+// of a component
+func identityClientSecret(identity *camundacloudv1.Identity, component string) v12.SecretKeySelector {
+	return v12.SecretKeySelector{
+		LocalObjectReference: v12.LocalObjectReference{Name: identityClientSecretName(identity)},
+		Key:                  component,
+	}
+}
+
+// keycloakAdminSecretName returns the name of the generated Secret holding the
+// admin user of the Keycloak run by the operator
+func keycloakAdminSecretName(identity *camundacloudv1.Identity) string {
+	return identity.Name + "-keycloak-admin"
+}
+
+// ingressURL returns the URL a web app is reachable at through its Ingress
+func ingressURL(ingress *camundacloudv1.IngressSpec) string {
+	scheme := "http"
+	if ingress.TLSSecretName != "" {
+		scheme = "https"
+	}
+	return strings.TrimSuffix(fmt.Sprintf("%s://%s%s", scheme, ingress.Host, ingress.Path), "/")
+}
+
+// randomSecret returns a random value for a generated secret
+func randomSecret() (string, error) {
+	value := make([]byte, 24)
+	if _, err := rand.Read(value); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(value), nil
+}
+
+// identitiesForWebApp enqueues the Identity a web app authenticates its users
+// with, so Keycloak learns about the URL of the web app
+func identitiesForWebApp(obj client.Object) []reconcile.Request {
+	var identityRef string
+	switch app := obj.(type) {
+	case *camundacloudv1.Operate:
+		identityRef = app.Spec.IdentityRef
+	case *camundacloudv1.Tasklist:
+		identityRef = app.Spec.IdentityRef
+	}
+	if identityRef == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: obj.GetNamespace(), Name: identityRef}}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IdentityReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.Identity{}).
+		Watches(&source.Kind{Type: &camundacloudv1.Operate{}}, handler.EnqueueRequestsFromMapFunc(identitiesForWebApp)).
+		Watches(&source.Kind{Type: &camundacloudv1.Tasklist{}}, handler.EnqueueRequestsFromMapFunc(identitiesForWebApp)).
+		Owns(&v1.Deployment{}).
+		Owns(&v12.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&v12.Secret{}).
+		Complete(r)
+}
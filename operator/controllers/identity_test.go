@@ -0,0 +1,212 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("Identity", func() {
+	var identity *camundacloudv1.Identity
+
+	BeforeEach(func() {
+		identity = &camundacloudv1.Identity{
+			ObjectMeta: metav1.ObjectMeta{Name: "identity", Namespace: "team-1"},
+		}
+	})
+
+	It("sets up the OAuth clients in the Keycloak run by the operator", func() {
+		identity.Spec.Keycloak.Ingress = &camundacloudv1.IngressSpec{Host: "keycloak.example.com", TLSSecretName: "keycloak-tls"}
+
+		container := createIdentityDeployment(identity, map[string]string{"operate": "https://operate.example.com"}).Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("camunda/identity:" + defaultIdentityVersion))
+		Expect(findEnv(container.Env, "KEYCLOAK_URL").Value).To(Equal("http://identity-keycloak.team-1.svc.cluster.local:80/auth"))
+		Expect(findEnv(container.Env, "KEYCLOAK_SETUP_PASSWORD").ValueFrom.SecretKeyRef.Name).To(Equal("identity-keycloak-admin"))
+		Expect(findEnv(container.Env, "IDENTITY_AUTH_PROVIDER_ISSUER_URL").Value).To(Equal("https://keycloak.example.com/auth/realms/camunda-platform"))
+		Expect(findEnv(container.Env, "IDENTITY_AUTH_PROVIDER_BACKEND_URL").Value).To(Equal("http://identity-keycloak.team-1.svc.cluster.local:80/auth/realms/camunda-platform"))
+		Expect(*findEnv(container.Env, "KEYCLOAK_INIT_TASKLIST_SECRET").ValueFrom.SecretKeyRef).To(Equal(secretKey("identity-identity-clients", "tasklist")))
+		Expect(findEnv(container.Env, "KEYCLOAK_INIT_OPERATE_ROOT_URL").Value).To(Equal("https://operate.example.com"))
+		Expect(findEnv(container.Env, "KEYCLOAK_INIT_TASKLIST_ROOT_URL")).To(BeNil())
+
+		keycloak := createKeycloakDeployment(identity).Spec.Template.Spec.Containers[0]
+		Expect(keycloak.Image).To(Equal(defaultKeycloakImage))
+		Expect(*findEnv(keycloak.Env, "KEYCLOAK_ADMIN").ValueFrom.SecretKeyRef).To(Equal(secretKey("identity-keycloak-admin", "username")))
+	})
+
+	It("sets up the OAuth clients in an existing Keycloak", func() {
+		admin := camundacloudv1.BasicAuthentication{
+			Username: secretKey("keycloak", "username"),
+			Password: secretKey("keycloak", "password"),
+		}
+		identity.Spec.Keycloak = camundacloudv1.KeycloakSpec{
+			URL:       "http://keycloak.auth/auth/",
+			PublicURL: "https://login.example.com/auth",
+			Admin:     &admin,
+		}
+
+		container := createIdentityDeployment(identity, nil).Spec.Template.Spec.Containers[0]
+		Expect(findEnv(container.Env, "KEYCLOAK_URL").Value).To(Equal("http://keycloak.auth/auth"))
+		Expect(*findEnv(container.Env, "KEYCLOAK_SETUP_USER").ValueFrom.SecretKeyRef).To(Equal(admin.Username))
+		Expect(findEnv(container.Env, "IDENTITY_AUTH_PROVIDER_ISSUER_URL").Value).To(Equal("https://login.example.com/auth/realms/camunda-platform"))
+	})
+
+	It("requires the admin user of an existing Keycloak", func() {
+		identity.Spec.Keycloak.URL = "http://keycloak.auth/auth"
+
+		ctx := context.Background()
+		s := testScheme()
+		reconciler := &IdentityReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(identity).Build(),
+			Scheme: s,
+		}
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(identity)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(requeueInterval))
+
+		var updated camundacloudv1.Identity
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(identity), &updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.WebAppPhaseDegraded))
+		condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.WebAppConditionDegraded)
+		Expect(condition.Reason).To(Equal("KeycloakAdminMissing"))
+	})
+
+	It("removes only the Keycloak objects it ran once an existing Keycloak is used", func() {
+		identity.UID = "identity-uid"
+		identity.Spec.Keycloak = camundacloudv1.KeycloakSpec{
+			URL: "http://keycloak.auth/auth",
+			Admin: &camundacloudv1.BasicAuthentication{
+				Username: secretKey("keycloak", "username"),
+				Password: secretKey("keycloak", "password"),
+			},
+		}
+
+		ctx := context.Background()
+		s := testScheme()
+		owned := createKeycloakDeployment(identity)
+		Expect(ctrl.SetControllerReference(identity, owned, s)).To(Succeed())
+		foreign := createWebAppService(keycloakApp(identity))
+		reconciler := &IdentityReconciler{
+			Client: &upsertClient{Client: fake.NewClientBuilder().WithScheme(s).WithObjects(identity, owned, foreign).Build()},
+			Scheme: s,
+		}
+
+		_, err := reconciler.reconcileIdentity(ctx, identity, nil)
+		Expect(err).NotTo(HaveOccurred())
+		err = reconciler.Get(ctx, client.ObjectKeyFromObject(owned), &v1.Deployment{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(foreign), &v12.Service{})).To(Succeed())
+	})
+
+	It("keeps generated secrets", func() {
+		ctx := context.Background()
+		s := testScheme()
+		reconciler := &IdentityReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(identity).Build(),
+			Scheme: s,
+		}
+		key := client.ObjectKey{Namespace: identity.Namespace, Name: identityClientSecretName(identity)}
+
+		Expect(generateSecret(ctx, reconciler.Client, s, identity, key.Name, nil, []string{"operate"})).To(Succeed())
+		var secret v12.Secret
+		Expect(reconciler.Get(ctx, key, &secret)).To(Succeed())
+		operateSecret := secret.Data["operate"]
+		Expect(operateSecret).NotTo(BeEmpty())
+		Expect(secret.OwnerReferences[0].Name).To(Equal(identity.Name))
+
+		Expect(generateSecret(ctx, reconciler.Client, s, identity, key.Name, nil, identityClients)).To(Succeed())
+		Expect(reconciler.Get(ctx, key, &secret)).To(Succeed())
+		Expect(secret.Data["operate"]).To(Equal(operateSecret))
+		Expect(secret.Data).To(HaveLen(len(identityClients)))
+	})
+
+	It("authenticates Operate users and Operate at the gateway", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{
+			{
+				Name:          "elasticsearch",
+				Type:          camundacloudv1.ExporterElasticsearch,
+				Elasticsearch: &camundacloudv1.SearchExporter{URL: "http://elastic:9200"},
+			},
+		}
+		operate := &camundacloudv1.Operate{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.OperateSpec{ZeebeRef: zeebe.Name, IdentityRef: identity.Name},
+		}
+		app := operateApp(operate)
+		app.identity = identity
+
+		container := webAppDeployment(app, zeebe).Spec.Template.Spec.Containers[0]
+		Expect(findEnv(container.Env, "SPRING_PROFILES_ACTIVE").Value).To(Equal("identity-auth"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_IDENTITY_BASEURL").Value).To(Equal("http://identity-identity.team-1.svc.cluster.local:80"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_IDENTITY_CLIENTID").Value).To(Equal("operate"))
+		Expect(*findEnv(container.Env, "CAMUNDA_OPERATE_IDENTITY_CLIENTSECRET").ValueFrom.SecretKeyRef).To(Equal(secretKey("identity-identity-clients", "operate")))
+		Expect(findEnv(container.Env, "ZEEBE_AUTHORIZATION_SERVER_URL").Value).To(Equal("http://identity-keycloak.team-1.svc.cluster.local:80/auth/realms/camunda-platform/protocol/openid-connect/token"))
+		Expect(findEnv(container.Env, "ZEEBE_TOKEN_AUDIENCE").Value).To(Equal("zeebe-api"))
+	})
+
+	It("waits for the Identity of a web app", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{
+			{
+				Name:          "elasticsearch",
+				Type:          camundacloudv1.ExporterElasticsearch,
+				Elasticsearch: &camundacloudv1.SearchExporter{URL: "http://elastic:9200"},
+			},
+		}
+		tasklist := &camundacloudv1.Tasklist{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.TasklistSpec{ZeebeRef: zeebe.Name, IdentityRef: identity.Name},
+		}
+
+		ctx := context.Background()
+		s := testScheme()
+		reconciler := &TasklistReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe, tasklist).Build(),
+			Scheme: s,
+		}
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tasklist)})
+		Expect(err).NotTo(HaveOccurred())
+
+		var updated camundacloudv1.Tasklist
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(tasklist), &updated)).To(Succeed())
+		condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.WebAppConditionDegraded)
+		Expect(condition.Reason).To(Equal("IdentityNotFound"))
+	})
+
+	It("authenticates clients at the gateway", func() {
+		container := v12.Container{Env: []v12.EnvVar{{Name: "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_AUDIENCE", Value: "custom"}}}
+		addGatewayAuthentication(&container, "ZEEBE_GATEWAY_", identity)
+
+		Expect(container.Env[0]).To(Equal(v12.EnvVar{Name: "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_MODE", Value: "identity"}))
+		Expect(findEnv(container.Env, "ZEEBE_GATEWAY_SECURITY_AUTHENTICATION_IDENTITY_ISSUERBACKENDURL").Value).To(Equal("http://identity-keycloak.team-1.svc.cluster.local:80/auth/realms/camunda-platform"))
+		// the override goes last, so it wins
+		Expect(container.Env[len(container.Env)-1].Value).To(Equal("custom"))
+	})
+})
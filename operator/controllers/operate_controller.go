@@ -0,0 +1,122 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// OperateReconciler reconciles a Operate object
+type OperateReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// operateImageName is the image Operate runs if the spec does not name one
+const operateImageName = "camunda/operate"
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=operates,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=operates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=operates/finalizers,verbs=update
+
+// CRUD networking: ingresses exposing the web apps
+// +kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile applies the Deployment, Service and optional Ingress of Operate next
+// to the referenced Zeebe cluster, see reconcileWebApp.
+func (r *OperateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var operate camundacloudv1.Operate
+	if err := r.Get(ctx, req.NamespacedName, &operate); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return reconcileWebApp(ctx, r.Client, r.Scheme, r.Recorder, operateApp(&operate))
+}
+
+// operateApp describes Operate as web app
+func operateApp(operate *camundacloudv1.Operate) *webApp {
+	return &webApp{
+		owner:         operate,
+		component:     "operate",
+		envPrefix:     "CAMUNDA_OPERATE",
+		imageName:     operateImageName,
+		port:          8080,
+		readinessPath: "/actuator/health/readiness",
+		gateway:       true,
+		version:       webAppVersion,
+		env:           webAppEnv,
+		zeebeRef:      operate.Spec.ZeebeRef,
+		exporter:      operate.Spec.Exporter,
+		identityRef:   operate.Spec.IdentityRef,
+		backend:       operate.Spec.Backend,
+		ingress:       operate.Spec.Ingress,
+		status:        &operate.Status,
+	}
+}
+
+// operatesForZeebe enqueues the Operate resources which reference a Zeebe
+// cluster, so they follow changes of its gateway and exporters
+func (r *OperateReconciler) operatesForZeebe(obj client.Object) []reconcile.Request {
+	var operates camundacloudv1.OperateList
+	if err := r.List(context.Background(), &operates, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, operate := range operates.Items {
+		if operate.Spec.ZeebeRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&operate)})
+		}
+	}
+	return requests
+}
+
+// operatesForStorage enqueues the Operate resources of the clusters exporting to a
+// SecondaryStorage, so they follow changes of its endpoint
+func (r *OperateReconciler) operatesForStorage(obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, zeebe := range zeebesUsingStorage(r.Client, obj) {
+		requests = append(requests, r.operatesForZeebe(&zeebe)...)
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OperateReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.Operate{}).
+		Watches(&source.Kind{Type: &camundacloudv1.Zeebe{}}, handler.EnqueueRequestsFromMapFunc(r.operatesForZeebe)).
+		Watches(&source.Kind{Type: &camundacloudv1.SecondaryStorage{}}, handler.EnqueueRequestsFromMapFunc(r.operatesForStorage)).
+		Owns(&v1.Deployment{}).
+		Owns(&v12.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Complete(r)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// OptimizeReconciler reconciles a Optimize object
+type OptimizeReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// optimizeImageName is the image Optimize runs if the spec does not name one
+const optimizeImageName = "camunda/optimize"
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=optimizes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=optimizes/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=optimizes/finalizers,verbs=update
+
+// Reconcile applies the Deployment, Service and optional Ingress of Optimize next
+// to the referenced Zeebe cluster, see reconcileWebApp. Optimize is not rolled
+// out with a version which cannot import the records of the brokers.
+func (r *OptimizeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var optimize camundacloudv1.Optimize
+	if err := r.Get(ctx, req.NamespacedName, &optimize); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return reconcileWebApp(ctx, r.Client, r.Scheme, r.Recorder, optimizeApp(&optimize))
+}
+
+// optimizeApp describes Optimize as web app
+func optimizeApp(optimize *camundacloudv1.Optimize) *webApp {
+	return &webApp{
+		owner:         optimize,
+		component:     "optimize",
+		envPrefix:     "CAMUNDA_OPTIMIZE",
+		imageName:     optimizeImageName,
+		port:          8090,
+		readinessPath: "/api/readyz",
+		version:       optimizeVersion,
+		env: func(app *webApp, zeebe *camundacloudv1.Zeebe, exporter *camundacloudv1.ExporterSpec) []v12.EnvVar {
+			return optimizeEnv(optimize, zeebe, exporter)
+		},
+		zeebeRef: optimize.Spec.ZeebeRef,
+		exporter: optimize.Spec.Exporter,
+		backend:  optimize.Spec.Backend,
+		ingress:  optimize.Spec.Ingress,
+		status:   &optimize.Status,
+	}
+}
+
+// optimizeVersion returns the image tag of Optimize, which defaults to the
+// version compatible with the brokers. Release versions are checked against the
+// brokers, while custom tags like latest are taken as they are.
+func optimizeVersion(app *webApp, zeebe *camundacloudv1.Zeebe) (string, error) {
+	zeebeTag := zeebe.Spec.Broker.Backend.ImageTag
+	zeebeVersion, zeebeErr := camundacloudv1.ParseZeebeVersion(zeebeTag)
+
+	if tag := app.backend.ImageTag; tag != "" {
+		version, err := camundacloudv1.ParseZeebeVersion(tag)
+		if err != nil || zeebeErr != nil {
+			return tag, nil
+		}
+		return tag, camundacloudv1.CheckOptimizeVersion(zeebeVersion, version)
+	}
+
+	if zeebeErr != nil {
+		return "", fmt.Errorf("the Optimize version compatible with Zeebe %s is unknown, set the image tag of Optimize", zeebeTag)
+	}
+	version, err := zeebeVersion.OptimizeVersion()
+	if err != nil {
+		return "", err
+	}
+	return version.String(), nil
+}
+
+// optimizeEnv points Optimize at the Elasticsearch or OpenSearch the exporter
+// writes to, lets it import the records of all partitions and configures the
+// identity provider users log in with
+func optimizeEnv(optimize *camundacloudv1.Optimize, zeebe *camundacloudv1.Zeebe, exporter *camundacloudv1.ExporterSpec) []v12.EnvVar {
+	search := searchExporter(exporter)
+	host, port, tls := searchEndpoint(search.URL)
+
+	hostEnv := "OPTIMIZE_ELASTICSEARCH_"
+	prefix := "CAMUNDA_OPTIMIZE_ELASTICSEARCH_"
+	var envs []v12.EnvVar
+	if exporter.Type == camundacloudv1.ExporterOpenSearch {
+		hostEnv = "CAMUNDA_OPTIMIZE_OPENSEARCH_"
+		prefix = "CAMUNDA_OPTIMIZE_OPENSEARCH_"
+		envs = append(envs, v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_DATABASE", Value: "opensearch"})
+	}
+
+	envs = append(envs,
+		v12.EnvVar{Name: hostEnv + "HOST", Value: host},
+		v12.EnvVar{Name: hostEnv + "HTTP_PORT", Value: port},
+		v12.EnvVar{Name: prefix + "SECURITY_SSL_ENABLED", Value: strconv.FormatBool(tls)},
+		v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_ZEEBE_ENABLED", Value: "true"},
+		v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_ZEEBE_PARTITION_COUNT", Value: fmt.Sprintf("%d", *zeebe.Spec.Broker.Partitions.Count)},
+	)
+	if search.IndexPrefix != "" {
+		envs = append(envs, v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_ZEEBE_NAME", Value: search.IndexPrefix})
+	}
+	if search.Authentication != nil {
+		username := search.Authentication.Username
+		password := search.Authentication.Password
+		envs = append(envs,
+			v12.EnvVar{Name: prefix + "SECURITY_USERNAME", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &username}},
+			v12.EnvVar{Name: prefix + "SECURITY_PASSWORD", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &password}},
+		)
+	}
+	if search.CACertificate != nil {
+		envs = append(envs, v12.EnvVar{Name: prefix + "SECURITY_SSL_CERTIFICATE", Value: webAppCertificatePath})
+	}
+
+	if identity := optimize.Spec.Identity; identity != nil {
+		backendURL := identity.IssuerBackendURL
+		if backendURL == "" {
+			backendURL = identity.IssuerURL
+		}
+		clientSecret := identity.ClientSecret
+		envs = append(envs,
+			v12.EnvVar{Name: "SPRING_PROFILES_ACTIVE", Value: "ccsm"},
+			v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_IDENTITY_ISSUER_URL", Value: identity.IssuerURL},
+			v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_IDENTITY_ISSUER_BACKEND_URL", Value: backendURL},
+			v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_IDENTITY_CLIENTID", Value: identity.ClientID},
+			v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_IDENTITY_CLIENTSECRET", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &clientSecret}},
+		)
+		if identity.Audience != "" {
+			envs = append(envs, v12.EnvVar{Name: "CAMUNDA_OPTIMIZE_IDENTITY_AUDIENCE", Value: identity.Audience})
+		}
+	}
+	return envs
+}
+
+// searchEndpoint splits the URL of an Elasticsearch or OpenSearch into host and
+// port, which default to the port of the scheme, and whether it uses TLS
+func searchEndpoint(searchURL string) (string, string, bool) {
+	parsed, err := url.Parse(searchURL)
+	if err != nil {
+		return searchURL, "9200", false
+	}
+	tls := parsed.Scheme == "https"
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if tls {
+			port = "443"
+		}
+	}
+	return parsed.Hostname(), port, tls
+}
+
+// optimizesForZeebe enqueues the Optimize resources which reference a Zeebe
+// cluster, so they follow changes of its gateway and exporters
+func (r *OptimizeReconciler) optimizesForZeebe(obj client.Object) []reconcile.Request {
+	var optimizes camundacloudv1.OptimizeList
+	if err := r.List(context.Background(), &optimizes, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, optimize := range optimizes.Items {
+		if optimize.Spec.ZeebeRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&optimize)})
+		}
+	}
+	return requests
+}
+
+// optimizesForStorage enqueues the Optimize resources of the clusters exporting to a
+// SecondaryStorage, so they follow changes of its endpoint
+func (r *OptimizeReconciler) optimizesForStorage(obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, zeebe := range zeebesUsingStorage(r.Client, obj) {
+		requests = append(requests, r.optimizesForZeebe(&zeebe)...)
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *OptimizeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.Optimize{}).
+		Watches(&source.Kind{Type: &camundacloudv1.Zeebe{}}, handler.EnqueueRequestsFromMapFunc(r.optimizesForZeebe)).
+		Watches(&source.Kind{Type: &camundacloudv1.SecondaryStorage{}}, handler.EnqueueRequestsFromMapFunc(r.optimizesForStorage)).
+		Owns(&v1.Deployment{}).
+		Owns(&v12.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Complete(r)
+}
@@ -0,0 +1,649 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// SecondaryStorageReconciler reconciles a SecondaryStorage object
+type SecondaryStorageReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// Images the storage runs if the spec does not name them
+const (
+	elasticsearchImageName      = "docker.elastic.co/elasticsearch/elasticsearch"
+	defaultElasticsearchVersion = "8.9.2"
+	openSearchImageName         = "opensearchproject/opensearch"
+	defaultOpenSearchVersion    = "2.9.0"
+)
+
+// defaultSearchStorageSize is used for the data volumes of the storage if the
+// spec sets no size
+var defaultSearchStorageSize = resource.MustParse("8Gi")
+
+// elasticsearchUser is the built-in superuser of Elasticsearch, whose password
+// the operator generates
+const elasticsearchUser = "elastic"
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=secondarystorages,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=secondarystorages/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=secondarystorages/finalizers,verbs=update
+
+// Reconcile publishes the endpoint of an existing storage, or applies the
+// Services and StatefulSet of the Elasticsearch or OpenSearch the operator runs.
+// The endpoint and credentials of a storage run by the operator are published
+// right away, so the resources referring to it can be configured while the
+// nodes start.
+func (r *SecondaryStorageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var storage camundacloudv1.SecondaryStorage
+	if err := r.Get(ctx, req.NamespacedName, &storage); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var statefulSet *v1.StatefulSet
+	var err error
+	if storage.Spec.External != nil {
+		err = r.removeNodes(ctx, &storage)
+	} else {
+		statefulSet, err = r.reconcileNodes(ctx, &storage)
+	}
+	setStorageStatus(&storage, statefulSet, err)
+	if statusErr := r.Status().Update(ctx, &storage); statusErr != nil {
+		logger.Error(statusErr, "unable to update status of SecondaryStorage")
+		if err == nil {
+			err = statusErr
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if storage.Status.Phase != camundacloudv1.SecondaryStoragePhaseRunning {
+		// check back until all nodes are rolled out and ready
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileNodes generates the credentials and transport certificates of the
+// storage and applies its Services and StatefulSet. It returns the StatefulSet
+// as seen by the API server.
+func (r *SecondaryStorageReconciler) reconcileNodes(ctx context.Context, storage *camundacloudv1.SecondaryStorage) (*v1.StatefulSet, error) {
+	logger := log.FromContext(ctx)
+
+	if storage.Spec.Type == camundacloudv1.SecondaryStorageElasticsearch {
+		values := map[string]string{"username": elasticsearchUser}
+		if err := generateSecret(ctx, r.Client, r.Scheme, storage, storageCredentialsName(storage), values, []string{"password"}); err != nil {
+			logger.Error(err, "unable to generate credentials of SecondaryStorage")
+			return nil, err
+		}
+		if err := r.generateTransportCertificates(ctx, storage); err != nil {
+			logger.Error(err, "unable to generate transport certificates of SecondaryStorage")
+			return nil, err
+		}
+	}
+
+	statefulSet := createStorageStatefulSet(storage)
+	var existing v1.StatefulSet
+	err := r.Get(ctx, client.ObjectKeyFromObject(statefulSet), &existing)
+	if err == nil {
+		keepVolumeClaimTemplates(statefulSet, &existing)
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	for _, obj := range []client.Object{createStorageService(storage), createStorageNodesService(storage), statefulSet} {
+		if err := ctrl.SetControllerReference(storage, obj, r.Scheme); err != nil {
+			logger.Error(err, "unable to construct object of SecondaryStorage", "name", obj.GetName())
+			return nil, err
+		}
+		result, err := applyObject(ctx, r.Client, r.Scheme, obj)
+		if err != nil {
+			logger.Error(err, "unable to apply object of SecondaryStorage", "name", obj.GetName())
+			return nil, err
+		}
+		recordDrift(r.Recorder, storage, storage.Status.ObservedGeneration, obj, result)
+		logger.V(1).Info("applied object of SecondaryStorage", "name", obj.GetName())
+	}
+	return statefulSet, nil
+}
+
+// removeNodes deletes the Services and StatefulSet of nodes the operator ran
+// before the storage was pointed to an existing cluster. The data volumes are
+// kept.
+func (r *SecondaryStorageReconciler) removeNodes(ctx context.Context, storage *camundacloudv1.SecondaryStorage) error {
+	for _, obj := range []client.Object{createStorageStatefulSet(storage), createStorageService(storage), createStorageNodesService(storage)} {
+		err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		// an object of the same name the storage did not create is left alone
+		if err != nil || !metav1.IsControlledBy(obj, storage) {
+			continue
+		}
+		if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "unable to delete object of SecondaryStorage", "name", obj.GetName())
+			return err
+		}
+	}
+	return nil
+}
+
+// generateTransportCertificates creates the Secret with the CA and the node
+// certificate Elasticsearch nodes authenticate each other with. Elasticsearch
+// refuses to form a cluster with security but without transport encryption.
+// The nodes only verify that the certificates are signed by the CA.
+func (r *SecondaryStorageReconciler) generateTransportCertificates(ctx context.Context, storage *camundacloudv1.SecondaryStorage) error {
+	var secret v12.Secret
+	err := r.Get(ctx, client.ObjectKey{Namespace: storage.Namespace, Name: storageTransportName(storage)}, &secret)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	data, err := createTransportCertificates(storageName(storage))
+	if err != nil {
+		return err
+	}
+	secret = v12.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: storageTransportName(storage), Namespace: storage.Namespace},
+		Data:       data,
+	}
+	if err := ctrl.SetControllerReference(storage, &secret, r.Scheme); err != nil {
+		return err
+	}
+	return r.Create(ctx, &secret)
+}
+
+// createTransportCertificates returns a CA and a node certificate signed by it
+// in PEM format, keyed by ca.crt, tls.crt and tls.key
+func createTransportCertificates(commonName string) (map[string][]byte, error) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	nodeKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	notBefore := time.Now().Add(-time.Hour)
+	notAfter := notBefore.AddDate(10, 0, 0)
+	ca := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName + "-ca"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, ca, ca, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	node := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	nodeDER, err := x509.CreateCertificate(rand.Reader, node, ca, &nodeKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	nodeKeyDER, err := x509.MarshalPKCS8PrivateKey(nodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string][]byte{
+		"ca.crt":  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		"tls.crt": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: nodeDER}),
+		"tls.key": pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: nodeKeyDER}),
+	}, nil
+}
+
+func createStorageStatefulSet(storage *camundacloudv1.SecondaryStorage) *v1.StatefulSet {
+	backendSpec := storage.Spec.Backend
+	labels := storageLabels(storage)
+	replicas := storageReplicas(storage)
+
+	imageName, imageTag, home := elasticsearchImageName, defaultElasticsearchVersion, "/usr/share/elasticsearch"
+	if storage.Spec.Type == camundacloudv1.SecondaryStorageOpenSearch {
+		imageName, imageTag, home = openSearchImageName, defaultOpenSearchVersion, "/usr/share/opensearch"
+	}
+	if backendSpec.ImageName != "" {
+		imageName = backendSpec.ImageName
+	}
+	if backendSpec.ImageTag != "" {
+		imageTag = backendSpec.ImageTag
+	}
+
+	envs := storageEnv(storage)
+	for _, env := range backendSpec.OverrideEnv {
+		envs = append(envs, env)
+	}
+
+	volumeMounts := []v12.VolumeMount{
+		{
+			Name:      dataVolumeName,
+			MountPath: home + "/data",
+		},
+	}
+	var volumes []v12.Volume
+	if storage.Spec.Type == camundacloudv1.SecondaryStorageElasticsearch {
+		volumes = append(volumes, v12.Volume{
+			Name: "transport-certificates",
+			VolumeSource: v12.VolumeSource{
+				Secret: &v12.SecretVolumeSource{SecretName: storageTransportName(storage)},
+			},
+		})
+		volumeMounts = append(volumeMounts, v12.VolumeMount{
+			Name:      "transport-certificates",
+			MountPath: home + "/config/certs",
+			ReadOnly:  true,
+		})
+	}
+
+	size := defaultSearchStorageSize
+	if storage.Spec.Storage.Size != nil {
+		size = *storage.Spec.Storage.Size
+	}
+	// the images run as user and group 1000, which must be able to write the data
+	fsGroup := int64(1000)
+
+	return &v1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      storageName(storage),
+			Namespace: storage.Namespace,
+		},
+		Spec: v1.StatefulSetSpec{
+			Replicas:    &replicas,
+			ServiceName: storageNodesName(storage),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			// all nodes have to be up to elect the first master
+			PodManagementPolicy: v1.ParallelPodManagement,
+			Template: v12.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v12.PodSpec{
+					SecurityContext: &v12.PodSecurityContext{FSGroup: &fsGroup},
+					Containers: []v12.Container{
+						{
+							Name:            "search",
+							Image:           fmt.Sprintf("%s:%s", imageName, imageTag),
+							ImagePullPolicy: v12.PullAlways,
+							Env:             envs,
+							Ports: []v12.ContainerPort{
+								{
+									ContainerPort: 9200,
+									Name:          "http",
+								},
+								{
+									ContainerPort: 9300,
+									Name:          "transport",
+								},
+							},
+							ReadinessProbe: &v12.Probe{
+								Handler: v12.Handler{
+									TCPSocket: &v12.TCPSocketAction{
+										Port: intstr.FromString("http"),
+									},
+								},
+								PeriodSeconds:    10,
+								SuccessThreshold: 1,
+								TimeoutSeconds:   1,
+							},
+							Resources:    backendSpec.Resources,
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+			VolumeClaimTemplates: volumeClaimTemplates(storage.Spec.Storage, size),
+		},
+	}
+}
+
+// storageEnv configures the nodes through the environment, which both images
+// turn into settings. Memory mapping is disabled, so the nodes run without
+// raising vm.max_map_count on the Kubernetes nodes.
+func storageEnv(storage *camundacloudv1.SecondaryStorage) []v12.EnvVar {
+	replicas := storageReplicas(storage)
+	envs := []v12.EnvVar{
+		{
+			Name: "K8S_NAME",
+			ValueFrom: &v12.EnvVarSource{
+				FieldRef: &v12.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "metadata.name",
+				},
+			},
+		},
+		{
+			Name:  "cluster.name",
+			Value: storage.Name,
+		},
+		{
+			Name:  "node.name",
+			Value: "$(K8S_NAME)",
+		},
+		{
+			Name:  "network.host",
+			Value: "0.0.0.0",
+		},
+		{
+			Name:  "node.store.allow_mmap",
+			Value: "false",
+		},
+	}
+
+	if replicas == 1 {
+		envs = append(envs, v12.EnvVar{Name: "discovery.type", Value: "single-node"})
+	} else {
+		nodes := make([]string, replicas)
+		for i := range nodes {
+			nodes[i] = fmt.Sprintf("%s-%d", storageName(storage), i)
+		}
+		envs = append(envs,
+			v12.EnvVar{Name: "discovery.seed_hosts", Value: storageNodesName(storage)},
+			v12.EnvVar{Name: "cluster.initial_master_nodes", Value: strings.Join(nodes, ",")},
+		)
+	}
+
+	switch storage.Spec.Type {
+	case camundacloudv1.SecondaryStorageOpenSearch:
+		// the security plugin requires TLS on the HTTP port with certificates the
+		// clients would have to trust, so the storage runs without it
+		envs = append(envs,
+			v12.EnvVar{Name: "OPENSEARCH_JAVA_OPTS", Value: "-Xms1g -Xmx1g"},
+			v12.EnvVar{Name: "DISABLE_INSTALL_DEMO_CONFIG", Value: "true"},
+			v12.EnvVar{Name: "DISABLE_SECURITY_PLUGIN", Value: "true"},
+		)
+	default:
+		password := v12.SecretKeySelector{
+			LocalObjectReference: v12.LocalObjectReference{Name: storageCredentialsName(storage)},
+			Key:                  "password",
+		}
+		envs = append(envs,
+			v12.EnvVar{Name: "ES_JAVA_OPTS", Value: "-Xms1g -Xmx1g"},
+			v12.EnvVar{Name: "ELASTIC_PASSWORD", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &password}},
+			v12.EnvVar{Name: "xpack.security.enabled", Value: "true"},
+			v12.EnvVar{Name: "xpack.security.http.ssl.enabled", Value: "false"},
+			v12.EnvVar{Name: "xpack.security.transport.ssl.enabled", Value: "true"},
+			v12.EnvVar{Name: "xpack.security.transport.ssl.verification_mode", Value: "certificate"},
+			v12.EnvVar{Name: "xpack.security.transport.ssl.key", Value: "certs/tls.key"},
+			v12.EnvVar{Name: "xpack.security.transport.ssl.certificate", Value: "certs/tls.crt"},
+			v12.EnvVar{Name: "xpack.security.transport.ssl.certificate_authorities", Value: "certs/ca.crt"},
+		)
+	}
+	return envs
+}
+
+// createStorageService returns the Service clients reach the ready nodes at
+func createStorageService(storage *camundacloudv1.SecondaryStorage) *v12.Service {
+	labels := storageLabels(storage)
+	return &v12.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      storageName(storage),
+			Namespace: storage.Namespace,
+		},
+		Spec: v12.ServiceSpec{
+			Type: v12.ServiceTypeClusterIP,
+			Ports: []v12.ServicePort{
+				{
+					Port:       9200,
+					TargetPort: intstr.FromString("http"),
+					Protocol:   v12.ProtocolTCP,
+					Name:       "http",
+				},
+			},
+			Selector: labels,
+		},
+	}
+}
+
+// createStorageNodesService returns the headless Service the nodes discover
+// each other with, which includes nodes that are not ready yet
+func createStorageNodesService(storage *camundacloudv1.SecondaryStorage) *v12.Service {
+	labels := storageLabels(storage)
+	return &v12.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      storageNodesName(storage),
+			Namespace: storage.Namespace,
+		},
+		Spec: v12.ServiceSpec{
+			ClusterIP:                v12.ClusterIPNone,
+			PublishNotReadyAddresses: true,
+			Type:                     v12.ServiceTypeClusterIP,
+			Ports: []v12.ServicePort{
+				{
+					Port:       9300,
+					TargetPort: intstr.FromString("transport"),
+					Protocol:   v12.ProtocolTCP,
+					Name:       "transport",
+				},
+			},
+			Selector: labels,
+		},
+	}
+}
+
+// setStorageStatus publishes the endpoint and credentials of the storage and
+// computes conditions, phase and replica counts. An existing storage is
+// considered ready, since the operator cannot tell otherwise. A failed
+// reconciliation is reported as Degraded.
+func setStorageStatus(storage *camundacloudv1.SecondaryStorage, statefulSet *v1.StatefulSet, reconcileErr error) {
+	status := &storage.Status
+	status.ObservedGeneration = storage.Generation
+
+	if reconcileErr != nil {
+		setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionDegraded, metav1.ConditionTrue, "ReconcileFailed", reconcileErr.Error())
+		setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionReady, metav1.ConditionFalse, "ReconcileFailed", "The storage could not be reconciled")
+		status.Phase = camundacloudv1.SecondaryStoragePhaseDegraded
+		return
+	}
+
+	if external := storage.Spec.External; external != nil {
+		status.URL = external.URL
+		status.Authentication = external.Authentication
+		status.CACertificate = external.CACertificate
+		status.ReadyReplicas = 0
+		setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionDegraded, metav1.ConditionFalse, "External", "The storage runs outside of the operator")
+		setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionReady, metav1.ConditionTrue, "External", "The storage runs outside of the operator")
+		status.Phase = camundacloudv1.SecondaryStoragePhaseRunning
+		return
+	}
+
+	status.URL = fmt.Sprintf("http://%s.%s.svc.cluster.local:9200", storageName(storage), storage.Namespace)
+	status.Authentication = nil
+	status.CACertificate = nil
+	if storage.Spec.Type == camundacloudv1.SecondaryStorageElasticsearch {
+		credentials := v12.LocalObjectReference{Name: storageCredentialsName(storage)}
+		status.Authentication = &camundacloudv1.BasicAuthentication{
+			Username: v12.SecretKeySelector{LocalObjectReference: credentials, Key: "username"},
+			Password: v12.SecretKeySelector{LocalObjectReference: credentials, Key: "password"},
+		}
+	}
+
+	replicas := *statefulSet.Spec.Replicas
+	statefulSetStatus := statefulSet.Status
+	status.ReadyReplicas = statefulSetStatus.ReadyReplicas
+	rolledOut := statefulSetStatus.ObservedGeneration >= statefulSet.Generation &&
+		statefulSetStatus.Replicas == replicas &&
+		statefulSetStatus.UpdatedReplicas == replicas
+
+	message := fmt.Sprintf("%d of %d nodes ready", status.ReadyReplicas, replicas)
+	setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionDegraded, metav1.ConditionFalse, "Reconciled", message)
+	if rolledOut && status.ReadyReplicas >= replicas {
+		setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionReady, metav1.ConditionTrue, "NodesReady", message)
+	} else {
+		setStorageCondition(storage, camundacloudv1.SecondaryStorageConditionReady, metav1.ConditionFalse, "NodesNotReady", message)
+	}
+
+	if meta.IsStatusConditionTrue(status.Conditions, camundacloudv1.SecondaryStorageConditionReady) {
+		status.Phase = camundacloudv1.SecondaryStoragePhaseRunning
+	} else {
+		status.Phase = camundacloudv1.SecondaryStoragePhasePending
+	}
+}
+
+func setStorageCondition(storage *camundacloudv1.SecondaryStorage, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&storage.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: storage.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// resolveSecondaryStorages fills the URL, credentials and CA certificate of the
+// search exporters which refer to a SecondaryStorage from its status. Only the
+// copy in memory is changed, the stored spec keeps the reference. It returns the
+// name of the first referenced storage which has not published its endpoint
+// yet.
+func resolveSecondaryStorages(ctx context.Context, c client.Client, zeebe *camundacloudv1.Zeebe) (string, error) {
+	for i := range zeebe.Spec.Broker.Exporters {
+		search := searchExporter(&zeebe.Spec.Broker.Exporters[i])
+		if search == nil || search.StorageRef == "" {
+			continue
+		}
+
+		var storage camundacloudv1.SecondaryStorage
+		if err := c.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: search.StorageRef}, &storage); err != nil {
+			return "", err
+		}
+		if storage.Status.URL == "" {
+			return storage.Name, nil
+		}
+		search.URL = storage.Status.URL
+		search.Authentication = storage.Status.Authentication
+		search.CACertificate = storage.Status.CACertificate
+	}
+	return "", nil
+}
+
+// zeebesUsingStorage lists the clusters with an exporter referring to the
+// SecondaryStorage, which watches map changes of the storage to
+func zeebesUsingStorage(c client.Client, storage client.Object) []camundacloudv1.Zeebe {
+	var zeebes camundacloudv1.ZeebeList
+	if err := c.List(context.Background(), &zeebes, client.InNamespace(storage.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var using []camundacloudv1.Zeebe
+	for _, zeebe := range zeebes.Items {
+		for i := range zeebe.Spec.Broker.Exporters {
+			if search := searchExporter(&zeebe.Spec.Broker.Exporters[i]); search != nil && search.StorageRef == storage.GetName() {
+				using = append(using, zeebe)
+				break
+			}
+		}
+	}
+	return using
+}
+
+func storageReplicas(storage *camundacloudv1.SecondaryStorage) int32 {
+	if storage.Spec.Backend.Replicas != nil {
+		return *storage.Spec.Backend.Replicas
+	}
+	return 1
+}
+
+// storageName returns the name of the StatefulSet and the client Service
+func storageName(storage *camundacloudv1.SecondaryStorage) string {
+	return storage.Name + "-search"
+}
+
+// storageNodesName returns the name of the headless Service of the nodes
+func storageNodesName(storage *camundacloudv1.SecondaryStorage) string {
+	return storage.Name + "-search-nodes"
+}
+
+// storageCredentialsName returns the name of the generated Secret holding the
+// credentials of the storage
+func storageCredentialsName(storage *camundacloudv1.SecondaryStorage) string {
+	return storage.Name + "-search-credentials"
+}
+
+// storageTransportName returns the name of the generated Secret holding the
+// transport certificates of the nodes
+func storageTransportName(storage *camundacloudv1.SecondaryStorage) string {
+	return storage.Name + "-search-transport"
+}
+
+func storageLabels(storage *camundacloudv1.SecondaryStorage) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "Operator",
+		"app.kubernetes.io/name":       "secondary-storage",
+		"app.kubernetes.io/instance":   storage.Name,
+		"app.kubernetes.io/app":        "search",
+		"app.kubernetes.io/component":  "search",
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SecondaryStorageReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.SecondaryStorage{}).
+		Owns(&v1.StatefulSet{}).
+		Owns(&v12.Service{}).
+		Owns(&v12.Secret{}).
+		Complete(r)
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("SecondaryStorage", func() {
+	var storage *camundacloudv1.SecondaryStorage
+
+	BeforeEach(func() {
+		storage = &camundacloudv1.SecondaryStorage{
+			ObjectMeta: metav1.ObjectMeta{Name: "search", Namespace: "team-1"},
+			Spec:       camundacloudv1.SecondaryStorageSpec{Type: camundacloudv1.SecondaryStorageElasticsearch},
+		}
+	})
+
+	It("runs a single Elasticsearch node secured with a generated password", func() {
+		statefulSet := createStorageStatefulSet(storage)
+		Expect(*statefulSet.Spec.Replicas).To(Equal(int32(1)))
+		Expect(statefulSet.Spec.ServiceName).To(Equal("search-search-nodes"))
+		Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[v12.ResourceStorage]).To(Equal(defaultSearchStorageSize))
+
+		container := statefulSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(elasticsearchImageName + ":" + defaultElasticsearchVersion))
+		Expect(findEnv(container.Env, "discovery.type").Value).To(Equal("single-node"))
+		Expect(findEnv(container.Env, "cluster.initial_master_nodes")).To(BeNil())
+		Expect(*findEnv(container.Env, "ELASTIC_PASSWORD").ValueFrom.SecretKeyRef).To(Equal(secretKey("search-search-credentials", "password")))
+		Expect(findEnv(container.Env, "xpack.security.transport.ssl.enabled").Value).To(Equal("true"))
+		Expect(container.VolumeMounts[1].MountPath).To(Equal("/usr/share/elasticsearch/config/certs"))
+	})
+
+	It("runs an OpenSearch cluster without credentials", func() {
+		size := resource.MustParse("20Gi")
+		storage.Spec.Type = camundacloudv1.SecondaryStorageOpenSearch
+		storage.Spec.Backend.Replicas = getIntPointer(3)
+		storage.Spec.Storage.Size = &size
+
+		statefulSet := createStorageStatefulSet(storage)
+		Expect(statefulSet.Spec.PodManagementPolicy).To(Equal(v1.ParallelPodManagement))
+		Expect(statefulSet.Spec.VolumeClaimTemplates[0].Spec.Resources.Requests[v12.ResourceStorage]).To(Equal(size))
+
+		container := statefulSet.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal(openSearchImageName + ":" + defaultOpenSearchVersion))
+		Expect(findEnv(container.Env, "discovery.type")).To(BeNil())
+		Expect(findEnv(container.Env, "discovery.seed_hosts").Value).To(Equal("search-search-nodes"))
+		Expect(findEnv(container.Env, "cluster.initial_master_nodes").Value).To(Equal("search-search-0,search-search-1,search-search-2"))
+		Expect(findEnv(container.Env, "ELASTIC_PASSWORD")).To(BeNil())
+		Expect(container.VolumeMounts).To(HaveLen(1))
+
+		setStorageStatus(storage, statefulSet, nil)
+		Expect(storage.Status.URL).To(Equal("http://search-search.team-1.svc.cluster.local:9200"))
+		Expect(storage.Status.Authentication).To(BeNil())
+	})
+
+	It("publishes its endpoint while the nodes start", func() {
+		statefulSet := createStorageStatefulSet(storage)
+		setStorageStatus(storage, statefulSet, nil)
+		Expect(storage.Status.Phase).To(Equal(camundacloudv1.SecondaryStoragePhasePending))
+		Expect(storage.Status.URL).To(Equal("http://search-search.team-1.svc.cluster.local:9200"))
+		Expect(storage.Status.Authentication.Username).To(Equal(secretKey("search-search-credentials", "username")))
+
+		statefulSet.Status = v1.StatefulSetStatus{Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1}
+		setStorageStatus(storage, statefulSet, nil)
+		Expect(storage.Status.Phase).To(Equal(camundacloudv1.SecondaryStoragePhaseRunning))
+		Expect(storage.Status.ReadyReplicas).To(Equal(int32(1)))
+	})
+
+	It("publishes an existing cluster", func() {
+		caCertificate := secretKey("elastic-ca", "ca.crt")
+		storage.Spec.External = &camundacloudv1.ExternalSecondaryStorage{
+			URL: "https://elastic:9200",
+			Authentication: &camundacloudv1.BasicAuthentication{
+				Username: secretKey("elastic", "username"),
+				Password: secretKey("elastic", "password"),
+			},
+			CACertificate: &caCertificate,
+		}
+
+		ctx := context.Background()
+		s := testScheme()
+		reconciler := &SecondaryStorageReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(storage).Build(),
+			Scheme: s,
+		}
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(storage)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeZero())
+
+		var updated camundacloudv1.SecondaryStorage
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(storage), &updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.SecondaryStoragePhaseRunning))
+		Expect(updated.Status.URL).To(Equal("https://elastic:9200"))
+		Expect(*updated.Status.CACertificate).To(Equal(caCertificate))
+		Expect(meta.IsStatusConditionTrue(updated.Status.Conditions, camundacloudv1.SecondaryStorageConditionReady)).To(BeTrue())
+	})
+
+	It("removes only the nodes it ran once pointed to an existing cluster", func() {
+		storage.UID = "search-uid"
+		ctx := context.Background()
+		s := testScheme()
+		owned := createStorageStatefulSet(storage)
+		Expect(ctrl.SetControllerReference(storage, owned, s)).To(Succeed())
+		foreign := createStorageService(storage)
+		reconciler := &SecondaryStorageReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(storage, owned, foreign).Build(),
+			Scheme: s,
+		}
+
+		Expect(reconciler.removeNodes(ctx, storage)).To(Succeed())
+		err := reconciler.Get(ctx, client.ObjectKeyFromObject(owned), &v1.StatefulSet{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(foreign), &v12.Service{})).To(Succeed())
+	})
+
+	It("generates transport certificates signed by their CA", func() {
+		data, err := createTransportCertificates("search-search")
+		Expect(err).NotTo(HaveOccurred())
+
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(data["ca.crt"])).To(BeTrue())
+		block, _ := pem.Decode(data["tls.crt"])
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = certificate.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+		Expect(err).NotTo(HaveOccurred())
+
+		block, _ = pem.Decode(data["tls.key"])
+		_, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when an exporter refers to it", func() {
+		var zeebe *camundacloudv1.Zeebe
+
+		BeforeEach(func() {
+			zeebe = testZeebe("cluster-1", 3)
+			zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{
+				{
+					Name:          "elasticsearch",
+					Type:          camundacloudv1.ExporterElasticsearch,
+					Elasticsearch: &camundacloudv1.SearchExporter{StorageRef: storage.Name},
+				},
+			}
+		})
+
+		It("points the exporter at its endpoint", func() {
+			setStorageStatus(storage, createStorageStatefulSet(storage), nil)
+			c := fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(storage).Build()
+
+			pending, err := resolveSecondaryStorages(context.Background(), c, zeebe)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeEmpty())
+
+			search := zeebe.Spec.Broker.Exporters[0].Elasticsearch
+			Expect(search.URL).To(Equal("http://search-search.team-1.svc.cluster.local:9200"))
+			Expect(search.Authentication.Password).To(Equal(secretKey("search-search-credentials", "password")))
+			envs := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec.Containers[0].Env
+			Expect(findEnv(envs, "ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_AUTHENTICATION_PASSWORD").ValueFrom.SecretKeyRef.Name).To(Equal("search-search-credentials"))
+		})
+
+		It("holds back web apps until its endpoint is published", func() {
+			operate := &camundacloudv1.Operate{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+				Spec:       camundacloudv1.OperateSpec{ZeebeRef: zeebe.Name},
+			}
+
+			ctx := context.Background()
+			s := testScheme()
+			reconciler := &OperateReconciler{
+				Client: fake.NewClientBuilder().WithScheme(s).WithObjects(storage, zeebe, operate).Build(),
+				Scheme: s,
+			}
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(operate)})
+			Expect(err).NotTo(HaveOccurred())
+
+			var updated camundacloudv1.Operate
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(operate), &updated)).To(Succeed())
+			condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.WebAppConditionDegraded)
+			Expect(condition.Reason).To(Equal("StorageNotReady"))
+		})
+
+		It("holds back the brokers until its endpoint is published", func() {
+			ctx := context.Background()
+			s := testScheme()
+			reconciler := &ZeebeReconciler{
+				Client: fake.NewClientBuilder().WithScheme(s).WithObjects(storage, zeebe).Build(),
+				Scheme: s,
+			}
+			result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(zeebe)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(requeueInterval))
+
+			var updated camundacloudv1.Zeebe
+			Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(zeebe), &updated)).To(Succeed())
+			Expect(updated.Status.Phase).To(Equal(camundacloudv1.ZeebePhasePending))
+			condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.ZeebeConditionProgressing)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Reason).To(Equal("StorageNotReady"))
+			err = reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerName(zeebe)}, &v1.StatefulSet{})
+			Expect(errors.IsNotFound(err)).To(BeTrue())
+		})
+
+		It("reconciles the clusters and web apps using it when it changes", func() {
+			operate := &camundacloudv1.Operate{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+				Spec:       camundacloudv1.OperateSpec{ZeebeRef: zeebe.Name},
+			}
+			other := testZeebe("cluster-2", 3)
+
+			s := testScheme()
+			c := fake.NewClientBuilder().WithScheme(s).WithObjects(storage, zeebe, other, operate).Build()
+
+			Expect((&ZeebeReconciler{Client: c, Scheme: s}).zeebesForStorage(storage)).To(Equal([]reconcile.Request{
+				{NamespacedName: client.ObjectKeyFromObject(zeebe)},
+			}))
+			Expect((&OperateReconciler{Client: c, Scheme: s}).operatesForStorage(storage)).To(Equal([]reconcile.Request{
+				{NamespacedName: client.ObjectKeyFromObject(operate)},
+			}))
+		})
+	})
+})
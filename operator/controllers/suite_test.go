@@ -22,7 +22,10 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -78,3 +81,32 @@ var _ = AfterSuite(func() {
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
+
+// testScheme knows the Kubernetes and the operator types
+func testScheme() *runtime.Scheme {
+	s := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(s)).To(Succeed())
+	Expect(camundacloudv1.AddToScheme(s)).To(Succeed())
+	return s
+}
+
+// testZeebeWithBackupStore is a three broker cluster which backs up to S3
+func testZeebeWithBackupStore() *camundacloudv1.Zeebe {
+	zeebe := testZeebe("cluster-1", 3)
+	zeebe.Spec.Broker.Backup = &camundacloudv1.BackupStoreSpec{
+		Store: camundacloudv1.BackupStoreS3,
+		S3: &camundacloudv1.S3BackupStore{
+			BucketName: "backups",
+			Region:     "eu-west-1",
+			AccessKey: &v12.SecretKeySelector{
+				LocalObjectReference: v12.LocalObjectReference{Name: "s3"},
+				Key:                  "accessKey",
+			},
+			SecretKey: &v12.SecretKeySelector{
+				LocalObjectReference: v12.LocalObjectReference{Name: "s3"},
+				Key:                  "secretKey",
+			},
+		},
+	}
+	return zeebe
+}
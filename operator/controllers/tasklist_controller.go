@@ -0,0 +1,119 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// TasklistReconciler reconciles a Tasklist object
+type TasklistReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// tasklistImageName is the image Tasklist runs if the spec does not name one
+const tasklistImageName = "camunda/tasklist"
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=tasklists,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=tasklists/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=tasklists/finalizers,verbs=update
+
+// Reconcile applies the Deployment, Service and optional Ingress of Tasklist next
+// to the referenced Zeebe cluster, see reconcileWebApp.
+func (r *TasklistReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var tasklist camundacloudv1.Tasklist
+	if err := r.Get(ctx, req.NamespacedName, &tasklist); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	return reconcileWebApp(ctx, r.Client, r.Scheme, r.Recorder, tasklistApp(&tasklist))
+}
+
+// tasklistApp describes Tasklist as web app
+func tasklistApp(tasklist *camundacloudv1.Tasklist) *webApp {
+	return &webApp{
+		owner:         tasklist,
+		component:     "tasklist",
+		envPrefix:     "CAMUNDA_TASKLIST",
+		imageName:     tasklistImageName,
+		port:          8080,
+		readinessPath: "/actuator/health/readiness",
+		gateway:       true,
+		version:       webAppVersion,
+		env:           webAppEnv,
+		zeebeRef:      tasklist.Spec.ZeebeRef,
+		exporter:      tasklist.Spec.Exporter,
+		identityRef:   tasklist.Spec.IdentityRef,
+		backend:       tasklist.Spec.Backend,
+		ingress:       tasklist.Spec.Ingress,
+		status:        &tasklist.Status,
+	}
+}
+
+// tasklistsForZeebe enqueues the Tasklist resources which reference a Zeebe
+// cluster, so they follow changes of its gateway and exporters
+func (r *TasklistReconciler) tasklistsForZeebe(obj client.Object) []reconcile.Request {
+	var tasklists camundacloudv1.TasklistList
+	if err := r.List(context.Background(), &tasklists, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, tasklist := range tasklists.Items {
+		if tasklist.Spec.ZeebeRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&tasklist)})
+		}
+	}
+	return requests
+}
+
+// tasklistsForStorage enqueues the Tasklist resources of the clusters exporting to a
+// SecondaryStorage, so they follow changes of its endpoint
+func (r *TasklistReconciler) tasklistsForStorage(obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, zeebe := range zeebesUsingStorage(r.Client, obj) {
+		requests = append(requests, r.tasklistsForZeebe(&zeebe)...)
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *TasklistReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.Tasklist{}).
+		Watches(&source.Kind{Type: &camundacloudv1.Zeebe{}}, handler.EnqueueRequestsFromMapFunc(r.tasklistsForZeebe)).
+		Watches(&source.Kind{Type: &camundacloudv1.SecondaryStorage{}}, handler.EnqueueRequestsFromMapFunc(r.tasklistsForStorage)).
+		Owns(&v1.Deployment{}).
+		Owns(&v12.Service{}).
+		Owns(&networkingv1.Ingress{}).
+		Complete(r)
+}
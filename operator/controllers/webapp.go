@@ -0,0 +1,540 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// webAppCertificatePath is where the CA certificate of the Elasticsearch or
+// OpenSearch exporter is mounted into a web app
+const webAppCertificatePath = "/usr/local/camunda/certs/ca.crt"
+
+// webApp is a web app which runs as Deployment next to a Zeebe cluster and reads
+// the records of its Elasticsearch or OpenSearch exporter, like Operate and
+// Tasklist. Its objects are named and labeled after the resource it belongs to
+// and its component.
+type webApp struct {
+	// resource the objects of the web app belong to
+	owner client.Object
+	// name of the component, used as container name, label and name suffix
+	component string
+	// prefix of the environment variables which configure the web app
+	envPrefix string
+	// image which runs if the backend does not name one
+	imageName string
+	// port serving the web app and its readiness endpoint
+	port          int32
+	readinessPath string
+	// whether the web app connects to the gateway of the Zeebe cluster
+	gateway bool
+	// version returns the image tag the web app runs with the given cluster, or
+	// an error if there is none it can run with
+	version func(app *webApp, zeebe *camundacloudv1.Zeebe) (string, error)
+	// env returns the environment which connects the web app to the cluster and
+	// the Elasticsearch or OpenSearch of the exporter
+	env func(app *webApp, zeebe *camundacloudv1.Zeebe, exporter *camundacloudv1.ExporterSpec) []v12.EnvVar
+
+	zeebeRef    string
+	exporter    string
+	identityRef string
+	backend     camundacloudv1.BackendSpec
+	ingress     *camundacloudv1.IngressSpec
+	status      *camundacloudv1.WebAppStatus
+
+	// Identity users log in with, fetched from the identityRef
+	identity *camundacloudv1.Identity
+}
+
+// reconcileWebApp applies the Deployment, Service and optional Ingress of a web
+// app and records its readiness in the status of the owner. The gateway address
+// and the Elasticsearch or OpenSearch to import from are taken from the
+// referenced Zeebe cluster, so the web app follows changes of the cluster.
+func reconcileWebApp(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, app *webApp) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var zeebe camundacloudv1.Zeebe
+	err := c.Get(ctx, client.ObjectKey{Namespace: app.owner.GetNamespace(), Name: app.zeebeRef}, &zeebe)
+	if errors.IsNotFound(err) {
+		// the cluster might not be created yet
+		return degradeWebApp(ctx, c, app, "ZeebeNotFound", fmt.Sprintf("Zeebe cluster %s not found", app.zeebeRef))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := zeebe.ValidateSpec(); err != nil {
+		// the settings taken from the cluster might be missing
+		return degradeWebApp(ctx, c, app, "ZeebeInvalid", fmt.Sprintf("Zeebe cluster %s is invalid: %s", zeebe.Name, err))
+	}
+
+	pending, err := resolveSecondaryStorages(ctx, c, &zeebe)
+	if errors.IsNotFound(err) {
+		return degradeWebApp(ctx, c, app, "StorageNotFound", fmt.Sprintf("Secondary storage of Zeebe cluster %s not found", zeebe.Name))
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pending != "" {
+		return degradeWebApp(ctx, c, app, "StorageNotReady", fmt.Sprintf("Secondary storage %s has not published its endpoint yet", pending))
+	}
+
+	exporter := webAppExporter(app, &zeebe)
+	if exporter == nil {
+		message := fmt.Sprintf("Zeebe cluster %s has no Elasticsearch or OpenSearch exporter", zeebe.Name)
+		if app.exporter != "" {
+			message = fmt.Sprintf("Zeebe cluster %s has no Elasticsearch or OpenSearch exporter %s", zeebe.Name, app.exporter)
+		}
+		return degradeWebApp(ctx, c, app, "ExporterNotFound", message)
+	}
+
+	if app.identityRef != "" {
+		var identity camundacloudv1.Identity
+		err := c.Get(ctx, client.ObjectKey{Namespace: app.owner.GetNamespace(), Name: app.identityRef}, &identity)
+		if errors.IsNotFound(err) {
+			return degradeWebApp(ctx, c, app, "IdentityNotFound", fmt.Sprintf("Identity %s not found", app.identityRef))
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		app.identity = &identity
+	}
+
+	version, err := app.version(app, &zeebe)
+	if err != nil {
+		return degradeWebApp(ctx, c, app, "IncompatibleVersion", err.Error())
+	}
+
+	if app.gateway {
+		app.status.GatewayAddress = gatewayAddress(&zeebe)
+	}
+	app.status.DatabaseURL = searchExporter(exporter).URL
+
+	deployment, err := applyWebApp(ctx, c, scheme, recorder, app, createWebAppDeployment(app, &zeebe, exporter, version))
+	setWebAppStatus(app, deployment, version, err)
+	if statusErr := c.Status().Update(ctx, app.owner); statusErr != nil {
+		logger.Error(statusErr, "unable to update status", "component", app.component)
+		if err == nil {
+			err = statusErr
+		}
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if app.status.Phase != camundacloudv1.WebAppPhaseRunning {
+		// check back until all replicas are rolled out and ready
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// applyWebApp applies the given Deployment with the Service and Ingress of a
+// web app and returns the Deployment as seen by the API server. A left over
+// Ingress is removed if the spec no longer asks for one, and out of band
+// changes of the objects are reported on the owner.
+func applyWebApp(ctx context.Context, c client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, app *webApp, deployment *v1.Deployment) (*v1.Deployment, error) {
+	logger := log.FromContext(ctx)
+
+	objects := []client.Object{deployment, createWebAppService(app)}
+
+	ingress := createWebAppIngress(app)
+	if app.ingress != nil {
+		objects = append(objects, ingress)
+	} else {
+		err := c.Get(ctx, client.ObjectKeyFromObject(ingress), ingress)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		// an Ingress of the same name the web app did not create is left alone
+		if err == nil && metav1.IsControlledBy(ingress, app.owner) {
+			if err := c.Delete(ctx, ingress); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "unable to delete ingress", "component", app.component, "ingress", ingress.Name)
+				return nil, err
+			}
+		}
+	}
+
+	for _, obj := range objects {
+		if err := ctrl.SetControllerReference(app.owner, obj, scheme); err != nil {
+			logger.Error(err, "unable to construct object", "component", app.component, "name", obj.GetName())
+			return nil, err
+		}
+
+		result, err := applyObject(ctx, c, scheme, obj)
+		if err != nil {
+			logger.Error(err, "unable to apply object", "component", app.component, "name", obj.GetName())
+			return nil, err
+		}
+		recordDrift(recorder, app.owner, app.status.ObservedGeneration, obj, result)
+
+		logger.V(1).Info("applied object", "component", app.component, "name", obj.GetName())
+	}
+	return deployment, nil
+}
+
+// degradeWebApp reports a missing or invalid Zeebe cluster, a missing storage,
+// exporter or Identity, or a cluster whose version the web app cannot run with,
+// and checks back in case that changes
+func degradeWebApp(ctx context.Context, c client.Client, app *webApp, reason, message string) (ctrl.Result, error) {
+	app.status.ObservedGeneration = app.owner.GetGeneration()
+	setWebAppCondition(app, camundacloudv1.WebAppConditionDegraded, metav1.ConditionTrue, reason, message)
+	setWebAppCondition(app, camundacloudv1.WebAppConditionReady, metav1.ConditionFalse, reason, message)
+	app.status.Phase = camundacloudv1.WebAppPhaseDegraded
+	return ctrl.Result{RequeueAfter: requeueInterval}, c.Status().Update(ctx, app.owner)
+}
+
+// webAppExporter returns the Elasticsearch or OpenSearch exporter of the Zeebe
+// cluster the web app imports from, or nil if there is none
+func webAppExporter(app *webApp, zeebe *camundacloudv1.Zeebe) *camundacloudv1.ExporterSpec {
+	for i := range zeebe.Spec.Broker.Exporters {
+		exporter := &zeebe.Spec.Broker.Exporters[i]
+		if searchExporter(exporter) == nil {
+			continue
+		}
+		if app.exporter == "" || app.exporter == exporter.Name {
+			return exporter
+		}
+	}
+	return nil
+}
+
+// webAppEnv connects web apps which share the configuration of Operate to the
+// gateway of the cluster and points them at the Elasticsearch or OpenSearch the
+// exporter writes to, both for their own indices and for importing the records.
+// With an Identity, users log in with it and the web app authenticates with its
+// OAuth client at the gateway.
+func webAppEnv(app *webApp, zeebe *camundacloudv1.Zeebe, exporter *camundacloudv1.ExporterSpec) []v12.EnvVar {
+	search := searchExporter(exporter)
+	database := "ELASTICSEARCH"
+	envs := []v12.EnvVar{
+		{
+			Name:  app.envPrefix + "_ZEEBE_GATEWAYADDRESS",
+			Value: gatewayAddress(zeebe),
+		},
+	}
+	if exporter.Type == camundacloudv1.ExporterOpenSearch {
+		database = "OPENSEARCH"
+		envs = append(envs, v12.EnvVar{Name: app.envPrefix + "_DATABASE", Value: "opensearch"})
+	}
+
+	for _, prefix := range []string{app.envPrefix + "_" + database + "_", app.envPrefix + "_ZEEBE" + database + "_"} {
+		envs = append(envs, v12.EnvVar{Name: prefix + "URL", Value: search.URL})
+		if search.Authentication != nil {
+			username := search.Authentication.Username
+			password := search.Authentication.Password
+			envs = append(envs,
+				v12.EnvVar{Name: prefix + "USERNAME", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &username}},
+				v12.EnvVar{Name: prefix + "PASSWORD", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &password}},
+			)
+		}
+		if search.CACertificate != nil {
+			envs = append(envs, v12.EnvVar{Name: prefix + "SSL_CERTIFICATEPATH", Value: webAppCertificatePath})
+		}
+	}
+	if search.IndexPrefix != "" {
+		envs = append(envs, v12.EnvVar{Name: app.envPrefix + "_ZEEBE" + database + "_PREFIX", Value: search.IndexPrefix})
+	}
+	if app.identity != nil {
+		envs = append(envs, webAppIdentityEnv(app)...)
+	}
+	return envs
+}
+
+// webAppIdentityEnv configures the OAuth client of a web app which shares the
+// configuration of Operate. Identity names the client after the component.
+func webAppIdentityEnv(app *webApp) []v12.EnvVar {
+	identity := app.identity
+	prefix := app.envPrefix + "_IDENTITY_"
+	clientSecret := identityClientSecret(identity, app.component)
+	return []v12.EnvVar{
+		{Name: "SPRING_PROFILES_ACTIVE", Value: "identity-auth"},
+		{Name: prefix + "BASEURL", Value: identityURL(identity)},
+		{Name: prefix + "ISSUER_URL", Value: identityIssuerURL(identity)},
+		{Name: prefix + "ISSUER_BACKEND_URL", Value: identityIssuerBackendURL(identity)},
+		{Name: prefix + "CLIENTID", Value: app.component},
+		{Name: prefix + "CLIENTSECRET", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &clientSecret}},
+		{Name: prefix + "AUDIENCE", Value: app.component + "-api"},
+		{Name: "ZEEBE_CLIENT_ID", Value: app.component},
+		{Name: "ZEEBE_CLIENT_SECRET", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &clientSecret}},
+		{Name: "ZEEBE_AUTHORIZATION_SERVER_URL", Value: identityTokenURL(identity)},
+		{Name: "ZEEBE_TOKEN_AUDIENCE", Value: "zeebe-api"},
+	}
+}
+
+func createWebAppDeployment(app *webApp, zeebe *camundacloudv1.Zeebe, exporter *camundacloudv1.ExporterSpec, version string) *v1.Deployment {
+	backendSpec := app.backend
+	labels := webAppLabels(app)
+
+	imageName := backendSpec.ImageName
+	if imageName == "" {
+		imageName = app.imageName
+	}
+	replicas := backendSpec.Replicas
+	if replicas == nil {
+		replicas = getIntPointer(1)
+	}
+
+	envs := app.env(app, zeebe, exporter)
+	for _, env := range backendSpec.OverrideEnv {
+		envs = append(envs, env)
+	}
+
+	var volumes []v12.Volume
+	var volumeMounts []v12.VolumeMount
+	if certificate := searchExporter(exporter).CACertificate; certificate != nil {
+		volumes = append(volumes, v12.Volume{
+			Name: "ca-certificate",
+			VolumeSource: v12.VolumeSource{
+				Secret: &v12.SecretVolumeSource{
+					SecretName: certificate.Name,
+					Optional:   certificate.Optional,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, v12.VolumeMount{
+			Name:      "ca-certificate",
+			MountPath: webAppCertificatePath,
+			SubPath:   certificate.Key,
+			ReadOnly:  true,
+		})
+	}
+
+	return &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      webAppName(app),
+			Namespace: app.owner.GetNamespace(),
+		},
+		Spec: v1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Replicas: replicas,
+			Template: v12.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{
+						{
+							Name:            app.component,
+							Image:           fmt.Sprintf("%s:%s", imageName, version),
+							ImagePullPolicy: v12.PullAlways,
+							Env:             envs,
+							Ports: []v12.ContainerPort{
+								{
+									ContainerPort: app.port,
+									Name:          "http",
+								},
+							},
+							ReadinessProbe: &v12.Probe{
+								Handler: v12.Handler{
+									HTTPGet: &v12.HTTPGetAction{
+										Path: app.readinessPath,
+										Port: intstr.IntOrString{
+											IntVal: app.port,
+										},
+									},
+								},
+								PeriodSeconds:    10,
+								SuccessThreshold: 1,
+								TimeoutSeconds:   1,
+							},
+							Resources:    backendSpec.Resources,
+							VolumeMounts: volumeMounts,
+						},
+					},
+					Volumes: volumes,
+				},
+			},
+		},
+	}
+}
+
+func createWebAppService(app *webApp) *v12.Service {
+	labels := webAppLabels(app)
+	return &v12.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      webAppName(app),
+			Namespace: app.owner.GetNamespace(),
+		},
+		Spec: v12.ServiceSpec{
+			Type: v12.ServiceTypeClusterIP,
+			Ports: []v12.ServicePort{
+				{
+					Port:       80,
+					TargetPort: intstr.FromString("http"),
+					Protocol:   v12.ProtocolTCP,
+					Name:       "http",
+				},
+			},
+			Selector: labels,
+		},
+	}
+}
+
+// createWebAppIngress returns the Ingress of a web app. Without an ingress in
+// the spec, only its name and namespace are set, which is enough to delete it.
+func createWebAppIngress(app *webApp) *networkingv1.Ingress {
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    webAppLabels(app),
+			Name:      webAppName(app),
+			Namespace: app.owner.GetNamespace(),
+		},
+	}
+	if app.ingress != nil {
+		ingress.Annotations = app.ingress.Annotations
+		ingress.Spec = createIngressSpec(app.ingress, webAppName(app))
+	}
+	return ingress
+}
+
+// createIngressSpec routes the host and path of the given ingress to the http
+// port of a Service
+func createIngressSpec(spec *camundacloudv1.IngressSpec, serviceName string) networkingv1.IngressSpec {
+	ingressPath := spec.Path
+	if ingressPath == "" {
+		ingressPath = "/"
+	}
+	pathType := networkingv1.PathTypePrefix
+
+	ingressSpec := networkingv1.IngressSpec{
+		IngressClassName: spec.ClassName,
+		Rules: []networkingv1.IngressRule{
+			{
+				Host: spec.Host,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{
+							{
+								Path:     path.Clean(ingressPath),
+								PathType: &pathType,
+								Backend: networkingv1.IngressBackend{
+									Service: &networkingv1.IngressServiceBackend{
+										Name: serviceName,
+										Port: networkingv1.ServiceBackendPort{Name: "http"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	if spec.TLSSecretName != "" {
+		ingressSpec.TLS = []networkingv1.IngressTLS{
+			{
+				Hosts:      []string{spec.Host},
+				SecretName: spec.TLSSecretName,
+			},
+		}
+	}
+	return ingressSpec
+}
+
+// setWebAppStatus computes conditions, phase and replica counts from the
+// applied Deployment. A failed reconciliation is reported as Degraded.
+func setWebAppStatus(app *webApp, deployment *v1.Deployment, version string, reconcileErr error) {
+	status := app.status
+	status.ObservedGeneration = app.owner.GetGeneration()
+
+	if reconcileErr != nil {
+		setWebAppCondition(app, camundacloudv1.WebAppConditionDegraded, metav1.ConditionTrue, "ReconcileFailed", reconcileErr.Error())
+		setWebAppCondition(app, camundacloudv1.WebAppConditionReady, metav1.ConditionFalse, "ReconcileFailed", "The web app could not be reconciled")
+		status.Phase = camundacloudv1.WebAppPhaseDegraded
+		return
+	}
+
+	replicas := *deployment.Spec.Replicas
+	deploymentStatus := deployment.Status
+	status.ReadyReplicas = deploymentStatus.ReadyReplicas
+	rolledOut := deploymentStatus.ObservedGeneration >= deployment.Generation &&
+		deploymentStatus.Replicas == replicas &&
+		deploymentStatus.UpdatedReplicas == replicas
+	if rolledOut {
+		status.Version = version
+	}
+
+	message := fmt.Sprintf("%d of %d replicas ready", status.ReadyReplicas, replicas)
+	setWebAppCondition(app, camundacloudv1.WebAppConditionDegraded, metav1.ConditionFalse, "Reconciled", message)
+	if rolledOut && status.ReadyReplicas >= replicas {
+		setWebAppCondition(app, camundacloudv1.WebAppConditionReady, metav1.ConditionTrue, "ReplicasReady", message)
+	} else {
+		setWebAppCondition(app, camundacloudv1.WebAppConditionReady, metav1.ConditionFalse, "ReplicasNotReady", message)
+	}
+
+	if meta.IsStatusConditionTrue(status.Conditions, camundacloudv1.WebAppConditionReady) {
+		status.Phase = camundacloudv1.WebAppPhaseRunning
+	} else {
+		status.Phase = camundacloudv1.WebAppPhasePending
+	}
+}
+
+func setWebAppCondition(app *webApp, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&app.status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: app.owner.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// webAppVersion returns the image tag of web apps which are released together
+// with Zeebe, which defaults to the image tag of the brokers
+func webAppVersion(app *webApp, zeebe *camundacloudv1.Zeebe) (string, error) {
+	if app.backend.ImageTag != "" {
+		return app.backend.ImageTag, nil
+	}
+	return zeebe.Spec.Broker.Backend.ImageTag, nil
+}
+
+// webAppName returns the name of the Deployment, Service and Ingress of a web app
+func webAppName(app *webApp) string {
+	return app.owner.GetName() + "-" + app.component
+}
+
+func webAppLabels(app *webApp) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "Operator",
+		"app.kubernetes.io/name":       app.component,
+		"app.kubernetes.io/instance":   app.owner.GetName(),
+		"app.kubernetes.io/app":        app.component,
+		"app.kubernetes.io/component":  app.component,
+		"app":                          app.component,
+	}
+}
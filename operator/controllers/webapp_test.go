@@ -0,0 +1,323 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// webAppDeployment renders the Deployment of a web app next to the given cluster
+func webAppDeployment(app *webApp, zeebe *camundacloudv1.Zeebe) *v1.Deployment {
+	exporter := webAppExporter(app, zeebe)
+	Expect(exporter).NotTo(BeNil())
+	version, err := app.version(app, zeebe)
+	Expect(err).NotTo(HaveOccurred())
+	return createWebAppDeployment(app, zeebe, exporter, version)
+}
+
+var _ = Describe("Operate", func() {
+	var (
+		zeebe   *camundacloudv1.Zeebe
+		operate *camundacloudv1.Operate
+	)
+
+	BeforeEach(func() {
+		caCertificate := secretKey("elastic-ca", "ca.crt")
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{
+			{
+				Name: "elasticsearch",
+				Type: camundacloudv1.ExporterElasticsearch,
+				Elasticsearch: &camundacloudv1.SearchExporter{
+					URL:         "https://elastic:9200",
+					IndexPrefix: "team-1",
+					Authentication: &camundacloudv1.BasicAuthentication{
+						Username: secretKey("elastic", "username"),
+						Password: secretKey("elastic", "password"),
+					},
+					CACertificate: &caCertificate,
+				},
+			},
+			{
+				Name:       "opensearch",
+				Type:       camundacloudv1.ExporterOpenSearch,
+				OpenSearch: &camundacloudv1.SearchExporter{URL: "http://opensearch:9200"},
+			},
+		}
+		operate = &camundacloudv1.Operate{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.OperateSpec{ZeebeRef: zeebe.Name},
+		}
+	})
+
+	deployment := func() *v1.Deployment {
+		return webAppDeployment(operateApp(operate), zeebe)
+	}
+
+	It("connects to the gateway and the Elasticsearch of the Zeebe cluster", func() {
+		container := deployment().Spec.Template.Spec.Containers[0]
+
+		Expect(container.Image).To(Equal("camunda/operate:" + camundacloudv1.DefaultZeebeVersion))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ZEEBE_GATEWAYADDRESS").Value).To(Equal("cluster-1-broker.team-1.svc.cluster.local:26500"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ELASTICSEARCH_URL").Value).To(Equal("https://elastic:9200"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ZEEBEELASTICSEARCH_URL").Value).To(Equal("https://elastic:9200"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ZEEBEELASTICSEARCH_PREFIX").Value).To(Equal("team-1"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ZEEBEELASTICSEARCH_PASSWORD").ValueFrom.SecretKeyRef.Name).To(Equal("elastic"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ELASTICSEARCH_SSL_CERTIFICATEPATH").Value).To(Equal(webAppCertificatePath))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_DATABASE")).To(BeNil())
+		Expect(container.VolumeMounts[0].MountPath).To(Equal(webAppCertificatePath))
+	})
+
+	It("connects to a standalone gateway", func() {
+		zeebe.Spec.Gateway.Standalone = true
+
+		container := deployment().Spec.Template.Spec.Containers[0]
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ZEEBE_GATEWAYADDRESS").Value).To(Equal("cluster-1-gateway.team-1.svc.cluster.local:26500"))
+	})
+
+	It("imports from the named OpenSearch exporter", func() {
+		operate.Spec.Exporter = "opensearch"
+
+		container := deployment().Spec.Template.Spec.Containers[0]
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_DATABASE").Value).To(Equal("opensearch"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ZEEBEOPENSEARCH_URL").Value).To(Equal("http://opensearch:9200"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPERATE_ELASTICSEARCH_URL")).To(BeNil())
+		Expect(container.VolumeMounts).To(BeEmpty())
+	})
+
+	It("exposes Operate through an Ingress", func() {
+		operate.Spec.Ingress = &camundacloudv1.IngressSpec{Host: "operate.example.com", TLSSecretName: "operate-tls"}
+
+		ingress := createWebAppIngress(operateApp(operate))
+		Expect(ingress.Spec.Rules[0].Host).To(Equal("operate.example.com"))
+		path := ingress.Spec.Rules[0].HTTP.Paths[0]
+		Expect(path.Path).To(Equal("/"))
+		Expect(path.Backend.Service.Name).To(Equal("cluster-1-operate"))
+		Expect(ingress.Spec.TLS[0].SecretName).To(Equal("operate-tls"))
+	})
+
+	It("removes only an Ingress it created once the spec drops it", func() {
+		ctx := context.Background()
+		operate.UID = "operate-uid"
+		s := testScheme()
+		app := operateApp(operate)
+		foreign := createWebAppIngress(app)
+		owned := createWebAppIngress(app)
+		owned.Name = "cluster-2-operate"
+		other := operate.DeepCopy()
+		other.Name = "cluster-2"
+		other.UID = "other-uid"
+		Expect(ctrl.SetControllerReference(other, owned, s)).To(Succeed())
+
+		c := &upsertClient{Client: fake.NewClientBuilder().WithScheme(s).WithObjects(foreign, owned).Build()}
+		_, err := applyWebApp(ctx, c, s, nil, app, deployment())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, client.ObjectKeyFromObject(foreign), &networkingv1.Ingress{})).To(Succeed())
+
+		otherApp := operateApp(other)
+		_, err = applyWebApp(ctx, c, s, nil, otherApp, webAppDeployment(otherApp, zeebe))
+		Expect(err).NotTo(HaveOccurred())
+		err = c.Get(ctx, client.ObjectKeyFromObject(owned), &networkingv1.Ingress{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
+
+	It("is ready once all replicas are rolled out", func() {
+		operateDeployment := deployment()
+		operateDeployment.Status = v1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1}
+		setWebAppStatus(operateApp(operate), operateDeployment, camundacloudv1.DefaultZeebeVersion, nil)
+		Expect(operate.Status.Phase).To(Equal(camundacloudv1.WebAppPhasePending))
+
+		operateDeployment.Status.ReadyReplicas = 1
+		setWebAppStatus(operateApp(operate), operateDeployment, camundacloudv1.DefaultZeebeVersion, nil)
+		Expect(operate.Status.Phase).To(Equal(camundacloudv1.WebAppPhaseRunning))
+		Expect(operate.Status.Version).To(Equal(camundacloudv1.DefaultZeebeVersion))
+
+		setWebAppStatus(operateApp(operate), nil, "", errors.New("forbidden"))
+		Expect(operate.Status.Phase).To(Equal(camundacloudv1.WebAppPhaseDegraded))
+	})
+
+	reconcileWith := func(objects ...client.Object) *camundacloudv1.Operate {
+		ctx := context.Background()
+		s := testScheme()
+		reconciler := &OperateReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(append(objects, operate)...).Build(),
+			Scheme: s,
+		}
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(operate)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(requeueInterval))
+
+		var updated camundacloudv1.Operate
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(operate), &updated)).To(Succeed())
+		return &updated
+	}
+
+	It("waits for the Zeebe cluster", func() {
+		updated := reconcileWith()
+
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.WebAppPhaseDegraded))
+		condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.WebAppConditionDegraded)
+		Expect(condition.Reason).To(Equal("ZeebeNotFound"))
+	})
+
+	It("waits for an Elasticsearch exporter of the Zeebe cluster", func() {
+		zeebe.Spec.Broker.Exporters = nil
+
+		updated := reconcileWith(zeebe)
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.WebAppPhaseDegraded))
+		condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.WebAppConditionDegraded)
+		Expect(condition.Message).To(Equal("Zeebe cluster cluster-1 has no Elasticsearch or OpenSearch exporter"))
+	})
+})
+
+var _ = Describe("Tasklist", func() {
+	It("runs the version of the brokers with the Elasticsearch of the Zeebe cluster", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Backend.ImageTag = "8.4.0"
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{{
+			Name:          "elasticsearch",
+			Type:          camundacloudv1.ExporterElasticsearch,
+			Elasticsearch: &camundacloudv1.SearchExporter{URL: "http://elastic:9200"},
+		}}
+		tasklist := &camundacloudv1.Tasklist{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.TasklistSpec{ZeebeRef: zeebe.Name},
+		}
+
+		deployment := webAppDeployment(tasklistApp(tasklist), zeebe)
+		Expect(deployment.Name).To(Equal("cluster-1-tasklist"))
+		container := deployment.Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("camunda/tasklist:8.4.0"))
+		Expect(findEnv(container.Env, "CAMUNDA_TASKLIST_ZEEBE_GATEWAYADDRESS").Value).To(Equal("cluster-1-broker.team-1.svc.cluster.local:26500"))
+		Expect(findEnv(container.Env, "CAMUNDA_TASKLIST_ZEEBEELASTICSEARCH_URL").Value).To(Equal("http://elastic:9200"))
+	})
+})
+
+var _ = Describe("Optimize", func() {
+	var (
+		zeebe    *camundacloudv1.Zeebe
+		optimize *camundacloudv1.Optimize
+	)
+
+	BeforeEach(func() {
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Backend.ImageTag = "8.2.5"
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{{
+			Name: "elasticsearch",
+			Type: camundacloudv1.ExporterElasticsearch,
+			Elasticsearch: &camundacloudv1.SearchExporter{
+				URL:         "https://elastic",
+				IndexPrefix: "team-1",
+				Authentication: &camundacloudv1.BasicAuthentication{
+					Username: secretKey("elastic", "username"),
+					Password: secretKey("elastic", "password"),
+				},
+			},
+		}}
+		optimize = &camundacloudv1.Optimize{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-1", Namespace: zeebe.Namespace},
+			Spec: camundacloudv1.OptimizeSpec{
+				ZeebeRef: zeebe.Name,
+				Identity: &camundacloudv1.OptimizeIdentity{
+					IssuerURL:    "https://keycloak.example.com/auth/realms/camunda-platform",
+					ClientID:     "optimize",
+					ClientSecret: secretKey("optimize-identity", "client-secret"),
+				},
+			},
+		}
+	})
+
+	It("imports the records of all partitions from the Elasticsearch of the Zeebe cluster", func() {
+		container := webAppDeployment(optimizeApp(optimize), zeebe).Spec.Template.Spec.Containers[0]
+
+		Expect(container.Image).To(Equal("camunda/optimize:3.10.0"))
+		Expect(container.Ports[0].ContainerPort).To(BeEquivalentTo(8090))
+		Expect(findEnv(container.Env, "OPTIMIZE_ELASTICSEARCH_HOST").Value).To(Equal("elastic"))
+		Expect(findEnv(container.Env, "OPTIMIZE_ELASTICSEARCH_HTTP_PORT").Value).To(Equal("443"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_ELASTICSEARCH_SECURITY_SSL_ENABLED").Value).To(Equal("true"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_ELASTICSEARCH_SECURITY_PASSWORD").ValueFrom.SecretKeyRef.Name).To(Equal("elastic"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_ZEEBE_NAME").Value).To(Equal("team-1"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_ZEEBE_PARTITION_COUNT").Value).To(Equal("3"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_ZEEBE_GATEWAYADDRESS")).To(BeNil())
+	})
+
+	It("authenticates users with the identity provider", func() {
+		container := webAppDeployment(optimizeApp(optimize), zeebe).Spec.Template.Spec.Containers[0]
+
+		Expect(findEnv(container.Env, "SPRING_PROFILES_ACTIVE").Value).To(Equal("ccsm"))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_IDENTITY_ISSUER_BACKEND_URL").Value).To(Equal(optimize.Spec.Identity.IssuerURL))
+		Expect(findEnv(container.Env, "CAMUNDA_OPTIMIZE_IDENTITY_CLIENTSECRET").ValueFrom.SecretKeyRef.Key).To(Equal("client-secret"))
+	})
+
+	It("runs the version of the brokers from 8.3 on", func() {
+		zeebe.Spec.Broker.Backend.ImageTag = "8.4.2"
+
+		container := webAppDeployment(optimizeApp(optimize), zeebe).Spec.Template.Spec.Containers[0]
+		Expect(container.Image).To(Equal("camunda/optimize:8.4.0"))
+	})
+
+	It("waits for the partitions of a Zeebe cluster which was not defaulted", func() {
+		zeebe.Spec.Broker.Partitions.Count = nil
+
+		ctx := context.Background()
+		s := testScheme()
+		reconciler := &OptimizeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe, optimize).Build(),
+			Scheme: s,
+		}
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(optimize)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(requeueInterval))
+
+		var updated camundacloudv1.Optimize
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(optimize), &updated)).To(Succeed())
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.WebAppPhaseDegraded))
+		condition := meta.FindStatusCondition(updated.Status.Conditions, camundacloudv1.WebAppConditionDegraded)
+		Expect(condition.Reason).To(Equal("ZeebeInvalid"))
+	})
+
+	It("refuses a version which cannot import the records of the brokers", func() {
+		optimize.Spec.Backend.ImageTag = "3.9.4"
+
+		_, err := optimizeVersion(optimizeApp(optimize), zeebe)
+		Expect(err).To(MatchError("Optimize 3.9.4 cannot import the records of Zeebe 8.2.5, use Optimize 3.10"))
+	})
+
+	It("takes custom tags as they are", func() {
+		optimize.Spec.Backend.ImageTag = "SNAPSHOT"
+		Expect(optimizeVersion(optimizeApp(optimize), zeebe)).To(Equal("SNAPSHOT"))
+
+		optimize.Spec.Backend.ImageTag = ""
+		zeebe.Spec.Broker.Backend.ImageTag = "SNAPSHOT"
+		_, err := optimizeVersion(optimizeApp(optimize), zeebe)
+		Expect(err).To(HaveOccurred())
+	})
+})
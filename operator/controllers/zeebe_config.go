@@ -0,0 +1,362 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/yaml"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// configHashAnnotation is set on the broker pod template, so that the brokers
+// are rolled whenever their configuration changes
+const configHashAnnotation = "camunda-cloud.io.camunda/config-hash"
+
+// brokerConfigHash hashes the rendered broker ConfigMap together with all
+// Secret and ConfigMap keys the broker environment, the backup store and the
+// exporters refer to, including exporter jars.
+func (r *ZeebeReconciler) brokerConfigHash(ctx context.Context, zeebe *camundacloudv1.Zeebe, brokerConfigMap *v12.ConfigMap) (string, error) {
+	hash := sha256.New()
+	writeData(hash, brokerConfigMap.Data)
+
+	envs := append(backupEnv(zeebe), exporterEnv(zeebe)...)
+	envs = append(envs, zeebe.Spec.Broker.Backend.OverrideEnv...)
+	for _, env := range envs {
+		if env.ValueFrom == nil {
+			continue
+		}
+
+		if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+			if err := r.hashSecretKey(ctx, hash, zeebe.Namespace, ref); err != nil {
+				return "", fmt.Errorf("unable to read secret %s referenced by %s: %w", ref.Name, env.Name, err)
+			}
+		}
+
+		if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+			var configMap v12.ConfigMap
+			err := r.Get(ctx, types.NamespacedName{Namespace: zeebe.Namespace, Name: ref.Name}, &configMap)
+			if err != nil && !(errors.IsNotFound(err) && ref.Optional != nil && *ref.Optional) {
+				return "", fmt.Errorf("unable to read config map %s referenced by %s: %w", ref.Name, env.Name, err)
+			}
+			fmt.Fprintf(hash, "configmap/%s/%s=%s", ref.Name, ref.Key, configMap.Data[ref.Key])
+		}
+	}
+
+	if ref := gcsCredentials(zeebe); ref != nil {
+		if err := r.hashSecretKey(ctx, hash, zeebe.Namespace, ref); err != nil {
+			return "", fmt.Errorf("unable to read secret %s with the backup credentials: %w", ref.Name, err)
+		}
+	}
+
+	for i := range zeebe.Spec.Broker.Exporters {
+		exporter := &zeebe.Spec.Broker.Exporters[i]
+		if search := searchExporter(exporter); search != nil && search.CACertificate != nil {
+			if err := r.hashSecretKey(ctx, hash, zeebe.Namespace, search.CACertificate); err != nil {
+				return "", fmt.Errorf("unable to read secret %s with the CA certificate of exporter %s: %w", search.CACertificate.Name, exporter.Name, err)
+			}
+		}
+
+		if exporter.Generic == nil || exporter.Generic.Jar == nil {
+			continue
+		}
+		if ref := exporter.Generic.Jar.Secret; ref != nil {
+			if err := r.hashSecretKey(ctx, hash, zeebe.Namespace, ref); err != nil {
+				return "", fmt.Errorf("unable to read secret %s with the jar of exporter %s: %w", ref.Name, exporter.Name, err)
+			}
+		}
+		if ref := exporter.Generic.Jar.ConfigMap; ref != nil {
+			var configMap v12.ConfigMap
+			if err := r.Get(ctx, types.NamespacedName{Namespace: zeebe.Namespace, Name: ref.Name}, &configMap); err != nil {
+				return "", fmt.Errorf("unable to read config map %s with the jar of exporter %s: %w", ref.Name, exporter.Name, err)
+			}
+			fmt.Fprintf(hash, "configmap/%s/%s=%s", ref.Name, ref.Key, configMap.Data[ref.Key])
+			hash.Write(configMap.BinaryData[ref.Key])
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// secretRefIndex and configMapRefIndex index Zeebe resources by the names of
+// the Secrets and ConfigMaps their broker configuration hash covers
+const (
+	secretRefIndex    = "spec.broker.secretRefs"
+	configMapRefIndex = "spec.broker.configMapRefs"
+)
+
+// brokerConfigRefs returns the names of the Secrets and ConfigMaps whose keys
+// brokerConfigHash covers, so that their changes roll the brokers.
+func brokerConfigRefs(zeebe *camundacloudv1.Zeebe) (secrets, configMaps []string) {
+	envs := append(backupEnv(zeebe), exporterEnv(zeebe)...)
+	envs = append(envs, zeebe.Spec.Broker.Backend.OverrideEnv...)
+	for _, env := range envs {
+		if env.ValueFrom == nil {
+			continue
+		}
+		if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+			secrets = append(secrets, ref.Name)
+		}
+		if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+			configMaps = append(configMaps, ref.Name)
+		}
+	}
+
+	if ref := gcsCredentials(zeebe); ref != nil {
+		secrets = append(secrets, ref.Name)
+	}
+
+	for i := range zeebe.Spec.Broker.Exporters {
+		exporter := &zeebe.Spec.Broker.Exporters[i]
+		if search := searchExporter(exporter); search != nil && search.CACertificate != nil {
+			secrets = append(secrets, search.CACertificate.Name)
+		}
+		if exporter.Generic == nil || exporter.Generic.Jar == nil {
+			continue
+		}
+		if ref := exporter.Generic.Jar.Secret; ref != nil {
+			secrets = append(secrets, ref.Name)
+		}
+		if ref := exporter.Generic.Jar.ConfigMap; ref != nil {
+			configMaps = append(configMaps, ref.Name)
+		}
+	}
+	return secrets, configMaps
+}
+
+// hashSecretKey writes the value of the referenced Secret key. Missing optional
+// keys are hashed as empty.
+func (r *ZeebeReconciler) hashSecretKey(ctx context.Context, hash io.Writer, namespace string, ref *v12.SecretKeySelector) error {
+	var secret v12.Secret
+	err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret)
+	if err != nil && !(errors.IsNotFound(err) && ref.Optional != nil && *ref.Optional) {
+		return err
+	}
+	fmt.Fprintf(hash, "secret/%s/%s=", ref.Name, ref.Key)
+	hash.Write(secret.Data[ref.Key])
+	return nil
+}
+
+// writeData writes the entries of the map in a stable order
+func writeData(hash io.Writer, data map[string]string) {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s=%s\n", key, data[key])
+	}
+}
+
+// renderBrokerConfig renders the typed broker configuration of the Zeebe
+// resource and merges the raw configuration over it. The result is used as
+// application.yaml of the brokers.
+func renderBrokerConfig(zeebe *camundacloudv1.Zeebe) (string, error) {
+	config := zeebe.Spec.Broker.Config
+
+	threads := map[string]interface{}{}
+	putInt32(threads, "cpuThreadCount", config.Threads.CpuThreadCount)
+	putInt32(threads, "ioThreadCount", config.Threads.IoThreadCount)
+
+	data := map[string]interface{}{}
+	putString(data, "snapshotPeriod", config.Data.SnapshotPeriod)
+	putString(data, "logSegmentSize", config.Data.LogSegmentSize)
+	putBool(data, "diskUsageMonitoringEnabled", config.Data.DiskUsageMonitoringEnabled)
+	if err := putFraction(data, "diskUsageCommandWatermark", config.Data.DiskUsageCommandWatermark); err != nil {
+		return "", err
+	}
+	if err := putFraction(data, "diskUsageReplicationWatermark", config.Data.DiskUsageReplicationWatermark); err != nil {
+		return "", err
+	}
+
+	backpressure := map[string]interface{}{}
+	putBool(backpressure, "enabled", config.Backpressure.Enabled)
+	putString(backpressure, "algorithm", config.Backpressure.Algorithm)
+
+	network := map[string]interface{}{}
+	putString(network, "maxMessageSize", config.Network.MaxMessageSize)
+	putString(network, "socketSendBuffer", config.Network.SocketSendBuffer)
+	putString(network, "socketReceiveBuffer", config.Network.SocketReceiveBuffer)
+
+	putSection(data, "backup", renderBackupStore(zeebe.Spec.Broker.Backup))
+
+	exporters, err := renderExporters(zeebe.Spec.Broker.Exporters)
+	if err != nil {
+		return "", err
+	}
+
+	broker := map[string]interface{}{}
+	putSection(broker, "threads", threads)
+	putSection(broker, "data", data)
+	putSection(broker, "backpressure", backpressure)
+	putSection(broker, "network", network)
+	putSection(broker, "exporters", exporters)
+
+	rendered := map[string]interface{}{}
+	if len(broker) > 0 {
+		rendered["zeebe"] = map[string]interface{}{"broker": broker}
+	}
+
+	if config.RawConfig != "" {
+		raw := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(config.RawConfig), &raw); err != nil {
+			return "", fmt.Errorf("invalid broker rawConfig: %w", err)
+		}
+		mergeConfig(rendered, raw)
+	}
+
+	out, err := yaml.Marshal(rendered)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// renderBackupStore renders the backup store settings which are no secrets.
+// Credentials are passed through the environment, see backupEnv.
+func renderBackupStore(backup *camundacloudv1.BackupStoreSpec) map[string]interface{} {
+	config := map[string]interface{}{}
+	if backup == nil {
+		return config
+	}
+
+	switch {
+	case backup.Store == camundacloudv1.BackupStoreS3 && backup.S3 != nil:
+		s3 := map[string]interface{}{}
+		putString(s3, "bucketName", backup.S3.BucketName)
+		putString(s3, "basePath", backup.S3.BasePath)
+		putString(s3, "region", backup.S3.Region)
+		putString(s3, "endpoint", backup.S3.Endpoint)
+		putBool(s3, "forcePathStyleAccess", backup.S3.ForcePathStyleAccess)
+		config["store"] = "S3"
+		putSection(config, "s3", s3)
+	case backup.Store == camundacloudv1.BackupStoreGCS && backup.GCS != nil:
+		gcs := map[string]interface{}{}
+		putString(gcs, "bucketName", backup.GCS.BucketName)
+		putString(gcs, "basePath", backup.GCS.BasePath)
+		gcs["auth"] = "auto"
+		config["store"] = "GCS"
+		putSection(config, "gcs", gcs)
+	case backup.Store == camundacloudv1.BackupStoreAzure && backup.Azure != nil:
+		azure := map[string]interface{}{}
+		putString(azure, "basePath", backup.Azure.BasePath)
+		putString(azure, "endpoint", backup.Azure.Endpoint)
+		config["store"] = "AZURE"
+		putSection(config, "azure", azure)
+	}
+	return config
+}
+
+// gcsCredentialsPath is where the service account key for GCS backups is mounted
+const gcsCredentialsPath = "/usr/local/zeebe/backup/credentials.json"
+
+// backupEnv returns the environment passing the backup store credentials from
+// their Secrets to the brokers
+func backupEnv(zeebe *camundacloudv1.Zeebe) []v12.EnvVar {
+	backup := zeebe.Spec.Broker.Backup
+	if backup == nil {
+		return nil
+	}
+
+	var envs []v12.EnvVar
+	secretEnv := func(name string, ref *v12.SecretKeySelector) {
+		if ref != nil {
+			envs = append(envs, v12.EnvVar{Name: name, ValueFrom: &v12.EnvVarSource{SecretKeyRef: ref}})
+		}
+	}
+	switch {
+	case backup.Store == camundacloudv1.BackupStoreS3 && backup.S3 != nil:
+		secretEnv("ZEEBE_BROKER_DATA_BACKUP_S3_ACCESSKEY", backup.S3.AccessKey)
+		secretEnv("ZEEBE_BROKER_DATA_BACKUP_S3_SECRETKEY", backup.S3.SecretKey)
+	case backup.Store == camundacloudv1.BackupStoreAzure && backup.Azure != nil:
+		secretEnv("ZEEBE_BROKER_DATA_BACKUP_AZURE_CONNECTIONSTRING", backup.Azure.ConnectionString)
+	}
+	if gcsCredentials(zeebe) != nil {
+		envs = append(envs, v12.EnvVar{Name: "GOOGLE_APPLICATION_CREDENTIALS", Value: gcsCredentialsPath})
+	}
+	return envs
+}
+
+// gcsCredentials returns the Secret key with the service account key for GCS
+// backups, if any
+func gcsCredentials(zeebe *camundacloudv1.Zeebe) *v12.SecretKeySelector {
+	backup := zeebe.Spec.Broker.Backup
+	if backup == nil || backup.Store != camundacloudv1.BackupStoreGCS || backup.GCS == nil {
+		return nil
+	}
+	return backup.GCS.Credentials
+}
+
+// mergeConfig merges the source map recursively into the target map. Values of
+// the source win, unless both sides hold a nested map.
+func mergeConfig(target, source map[string]interface{}) {
+	for key, value := range source {
+		sourceSection, sourceIsMap := value.(map[string]interface{})
+		targetSection, targetIsMap := target[key].(map[string]interface{})
+		if sourceIsMap && targetIsMap {
+			mergeConfig(targetSection, sourceSection)
+		} else {
+			target[key] = value
+		}
+	}
+}
+
+func putSection(config map[string]interface{}, key string, section map[string]interface{}) {
+	if len(section) > 0 {
+		config[key] = section
+	}
+}
+
+func putString(config map[string]interface{}, key string, value string) {
+	if value != "" {
+		config[key] = value
+	}
+}
+
+func putInt32(config map[string]interface{}, key string, value *int32) {
+	if value != nil {
+		config[key] = *value
+	}
+}
+
+func putBool(config map[string]interface{}, key string, value *bool) {
+	if value != nil {
+		config[key] = *value
+	}
+}
+
+func putFraction(config map[string]interface{}, key string, value string) error {
+	if value == "" {
+		return nil
+	}
+	fraction, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid broker config %s %q: %w", key, value, err)
+	}
+	config[key] = fraction
+	return nil
+}
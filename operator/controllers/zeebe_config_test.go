@@ -0,0 +1,243 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("Broker configuration", func() {
+	var zeebe *camundacloudv1.Zeebe
+
+	BeforeEach(func() {
+		zeebe = testZeebe("cluster-1", 3)
+	})
+
+	// brokerConfig renders the configuration and returns its zeebe.broker section
+	brokerConfig := func() map[string]interface{} {
+		config, err := renderBrokerConfig(zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		var parsed map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(config), &parsed)).To(Succeed())
+		return parsed["zeebe"].(map[string]interface{})["broker"].(map[string]interface{})
+	}
+
+	It("renders nothing without settings", func() {
+		config, err := renderBrokerConfig(zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(Equal("{}\n"))
+	})
+
+	It("renders the typed settings", func() {
+		enabled := false
+		zeebe.Spec.Broker.Config = camundacloudv1.BrokerConfigSpec{
+			Threads: camundacloudv1.ThreadsConfig{CpuThreadCount: getIntPointer(4)},
+			Data: camundacloudv1.DataConfig{
+				SnapshotPeriod:            "5m",
+				DiskUsageCommandWatermark: "0.97",
+			},
+			Backpressure: camundacloudv1.BackpressureConfig{Enabled: &enabled, Algorithm: "vegas"},
+			Network:      camundacloudv1.NetworkConfig{MaxMessageSize: "4MB"},
+		}
+
+		Expect(brokerConfig()).To(Equal(map[string]interface{}{
+			"threads": map[string]interface{}{"cpuThreadCount": float64(4)},
+			"data": map[string]interface{}{
+				"snapshotPeriod":            "5m",
+				"diskUsageCommandWatermark": 0.97,
+			},
+			"backpressure": map[string]interface{}{"enabled": false, "algorithm": "vegas"},
+			"network":      map[string]interface{}{"maxMessageSize": "4MB"},
+		}))
+	})
+
+	It("rejects watermarks which are no fractions", func() {
+		zeebe.Spec.Broker.Config.Data.DiskUsageReplicationWatermark = "most"
+		_, err := renderBrokerConfig(zeebe)
+		Expect(err).To(MatchError(ContainSubstring("diskUsageReplicationWatermark")))
+	})
+
+	It("merges the raw configuration over the typed settings", func() {
+		zeebe.Spec.Broker.Config = camundacloudv1.BrokerConfigSpec{
+			Threads: camundacloudv1.ThreadsConfig{CpuThreadCount: getIntPointer(4), IoThreadCount: getIntPointer(2)},
+			RawConfig: "zeebe:\n" +
+				"  broker:\n" +
+				"    threads:\n" +
+				"      cpuThreadCount: 8\n" +
+				"    flowControl:\n" +
+				"      request:\n" +
+				"        enabled: false\n",
+		}
+
+		broker := brokerConfig()
+		Expect(broker["threads"]).To(Equal(map[string]interface{}{"cpuThreadCount": float64(8), "ioThreadCount": float64(2)}))
+		Expect(broker["flowControl"]).To(Equal(map[string]interface{}{"request": map[string]interface{}{"enabled": false}}))
+	})
+
+	It("rejects an invalid raw configuration", func() {
+		zeebe.Spec.Broker.Config.RawConfig = "zeebe: [broker"
+		_, err := renderBrokerConfig(zeebe)
+		Expect(err).To(MatchError(ContainSubstring("invalid broker rawConfig")))
+	})
+
+	Describe("merging", func() {
+		It("merges nested sections key by key", func() {
+			target := map[string]interface{}{
+				"data": map[string]interface{}{"snapshotPeriod": "5m", "logSegmentSize": "128MB"},
+			}
+			mergeConfig(target, map[string]interface{}{
+				"data": map[string]interface{}{"snapshotPeriod": "15m"},
+			})
+			Expect(target).To(Equal(map[string]interface{}{
+				"data": map[string]interface{}{"snapshotPeriod": "15m", "logSegmentSize": "128MB"},
+			}))
+		})
+
+		It("replaces lists instead of merging them", func() {
+			target := map[string]interface{}{
+				"contactPoints": []interface{}{"broker-0", "broker-1"},
+			}
+			mergeConfig(target, map[string]interface{}{
+				"contactPoints": []interface{}{"broker-2"},
+			})
+			Expect(target).To(Equal(map[string]interface{}{
+				"contactPoints": []interface{}{"broker-2"},
+			}))
+		})
+
+		It("replaces sections and values of a different kind", func() {
+			target := map[string]interface{}{
+				"backup":  map[string]interface{}{"store": "S3"},
+				"threads": float64(4),
+			}
+			mergeConfig(target, map[string]interface{}{
+				"backup":  "none",
+				"threads": map[string]interface{}{"cpuThreadCount": float64(2)},
+			})
+			Expect(target).To(Equal(map[string]interface{}{
+				"backup":  "none",
+				"threads": map[string]interface{}{"cpuThreadCount": float64(2)},
+			}))
+		})
+	})
+})
+
+var _ = Describe("Broker configuration hash", func() {
+	var (
+		ctx        context.Context
+		zeebe      *camundacloudv1.Zeebe
+		reconciler *ZeebeReconciler
+		configMap  *v12.ConfigMap
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		zeebe = testZeebe("cluster-1", 3)
+		caCertificate := secretKey("elastic-ca", "ca.crt")
+		zeebe.Spec.Broker.Backend.OverrideEnv = []v12.EnvVar{
+			{Name: "JAVA_TOOL_OPTIONS", ValueFrom: &v12.EnvVarSource{ConfigMapKeyRef: &v12.ConfigMapKeySelector{
+				LocalObjectReference: v12.LocalObjectReference{Name: "jvm"}, Key: "options",
+			}}},
+		}
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{{
+			Name: "elasticsearch",
+			Type: camundacloudv1.ExporterElasticsearch,
+			Elasticsearch: &camundacloudv1.SearchExporter{
+				URL: "https://elastic:9200",
+				Authentication: &camundacloudv1.BasicAuthentication{
+					Username: secretKey("elastic", "username"),
+					Password: secretKey("elastic", "password"),
+				},
+				CACertificate: &caCertificate,
+			},
+		}}
+
+		configMap = &v12.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "jvm", Namespace: zeebe.Namespace},
+			Data:       map[string]string{"options": "-Xmx1g", "unused": "a"},
+		}
+		s := testScheme()
+		reconciler = &ZeebeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(
+				configMap,
+				&v12.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "elastic", Namespace: zeebe.Namespace},
+					Data:       map[string][]byte{"username": []byte("zeebe"), "password": []byte("secret")},
+				},
+				&v12.Secret{
+					ObjectMeta: metav1.ObjectMeta{Name: "elastic-ca", Namespace: zeebe.Namespace},
+					Data:       map[string][]byte{"ca.crt": []byte("certificate")},
+				},
+			).Build(),
+			Scheme: s,
+		}
+	})
+
+	hash := func() string {
+		brokerConfigMap, err := createBrokerConfigMap(zeebe, brokerLabels(zeebe))
+		Expect(err).NotTo(HaveOccurred())
+		configHash, err := reconciler.brokerConfigHash(ctx, zeebe, brokerConfigMap)
+		Expect(err).NotTo(HaveOccurred())
+		return configHash
+	}
+
+	updateSecret := func(name, key, value string) {
+		var secret v12.Secret
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: name}, &secret)).To(Succeed())
+		secret.Data[key] = []byte(value)
+		Expect(reconciler.Update(ctx, &secret)).To(Succeed())
+	}
+
+	It("changes with a referenced Secret key", func() {
+		before := hash()
+		updateSecret("elastic", "password", "rotated")
+		Expect(hash()).NotTo(Equal(before))
+	})
+
+	It("changes with the CA certificate of an exporter", func() {
+		before := hash()
+		updateSecret("elastic-ca", "ca.crt", "renewed")
+		Expect(hash()).NotTo(Equal(before))
+	})
+
+	It("changes with a referenced ConfigMap key only", func() {
+		before := hash()
+		configMap.Data["unused"] = "b"
+		Expect(reconciler.Update(ctx, configMap)).To(Succeed())
+		Expect(hash()).To(Equal(before))
+
+		configMap.Data["options"] = "-Xmx2g"
+		Expect(reconciler.Update(ctx, configMap)).To(Succeed())
+		Expect(hash()).NotTo(Equal(before))
+	})
+
+	It("indexes the referenced Secrets and ConfigMaps", func() {
+		secrets, configMaps := brokerConfigRefs(zeebe)
+		Expect(secrets).To(ConsistOf("elastic", "elastic", "elastic-ca"))
+		Expect(configMaps).To(ConsistOf("jvm"))
+	})
+})
@@ -19,17 +19,24 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	v1 "k8s.io/api/apps/v1"
 	v12 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	camundacloudv1 "io.camnda/operator/api/v1"
 )
@@ -37,10 +44,18 @@ import (
 // ZeebeReconciler reconciles a Zeebe object
 type ZeebeReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme     *runtime.Scheme
+	Management ManagementClient
+	Recorder   record.EventRecorder
 }
 
-const statefulset_name = "zeebe"
+const app_name = "zeebe"
+
+// requeueInterval is used to check back on clusters which are not running yet
+const requeueInterval = 10 * time.Second
+
+// fieldOwner identifies the operator as field manager for server-side apply
+const fieldOwner = client.FieldOwner("camunda-platform-operator")
 
 //+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebes,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebes/status,verbs=get;update;patch
@@ -50,12 +65,35 @@ const statefulset_name = "zeebe"
 // +kubebuilder:rbac:groups=apps,resources=statefulsets;deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=apps,resources=statefulsets/status;deployments/status,verbs=get
 
+// CRUD core: services and configmaps
+// +kubebuilder:rbac:groups="",resources=services;configmaps,verbs=get;list;watch;create;update;patch;delete
+
+// Read core: broker pods and secrets referenced by the broker environment
+// +kubebuilder:rbac:groups="",resources=pods;secrets,verbs=get;list;watch
+
+// Read restores which hold the brokers stopped
+// +kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeeberestores,verbs=get;list;watch
+
+// Expand broker data volumes and delete them with the cluster
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;update;patch;delete
+
+// Remove the backups of a deleted cluster
+// +kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackups,verbs=get;list;watch;delete
+
+// Read the Identity the gateway authenticates clients with
+// +kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=identities,verbs=get;list;watch
+
+// Read the secondary storages the exporters refer to
+// +kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=secondarystorages,verbs=get;list;watch
+
+// Report lifecycle actions and corrected drift of the owned objects
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Zeebe object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// The desired child objects are computed from the ZeebeSpec on every run and
+// server-side applied, so the API server diffs them against the live state and
+// only writes the fields which actually changed.
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.10.0/pkg/reconcile
@@ -72,122 +110,274 @@ func (r *ZeebeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
-	labels := map[string]string{
-		"app.kubernetes.io/managed-by": "Operator",
-		"app.kubernetes.io/name":       "zeebe-cluster",
-		"app.kubernetes.io/app":        statefulset_name,
-		"app.kubernetes.io/component":  "broker",
-		"app":                          statefulset_name,
+	if err := r.detectLegacyNames(ctx, &zeebe); err != nil {
+		logger.Error(err, "unable to detect broker objects of Zeebe created before the rename")
+		return ctrl.Result{}, err
 	}
 
-	brokerConfigMap := &v12.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "zeebe-configmap",
-			Labels:    labels,
-			Namespace: req.Namespace,
-		},
-		Data: map[string]string{
-			"startup.sh": "" +
-				"#!/usr/bin/env bash\n" +
-				"set -eux -o pipefail\n" +
-				"export ZEEBE_BROKER_CLUSTER_NODEID=$(echo $K8S_NAME | tr -d \"[:alpha:]-\")\n" +
-				"exec /usr/local/zeebe/bin/broker",
-		},
+	if !zeebe.DeletionTimestamp.IsZero() {
+		result, err := r.cleanUp(ctx, &zeebe)
+		if err != nil {
+			r.event(&zeebe, v12.EventTypeWarning, "CleanupFailed", "Unable to clean up the deleted cluster: %s", err)
+		}
+		return result, err
+	}
+	if err := r.ensureFinalizer(ctx, &zeebe); err != nil {
+		logger.Error(err, "unable to update finalizers of Zeebe")
+		return ctrl.Result{}, err
 	}
 
-	if err := ctrl.SetControllerReference(&zeebe, brokerConfigMap, r.Scheme); err != nil {
-		logger.Error(err, "unable to construct config map from zeebe CRD")
+	if err := zeebe.ValidateSpec(); err != nil {
+		logger.Error(err, "invalid Zeebe resource")
+		r.event(&zeebe, v12.EventTypeWarning, "InvalidSpec", err.Error())
 		// don't bother requeuing until we get a change to the spec
-		return ctrl.Result{}, nil
+		return ctrl.Result{}, r.updateStatus(ctx, &zeebe, nil, err)
 	}
 
-	if err := r.Create(ctx, brokerConfigMap); err != nil {
-		logger.Error(err, "unable to create config map for Zeebe", "configmap", brokerConfigMap)
+	var brokerStatefulSet *v1.StatefulSet
+	identity, err := r.gatewayIdentity(ctx, &zeebe)
+	if err == nil {
+		var pending string
+		pending, err = r.resolveExporterStorages(ctx, &zeebe)
+		if err == nil && pending != "" {
+			// the storage reconciles the cluster once it publishes its endpoint
+			setStorageNotReadyStatus(&zeebe, pending)
+			if err := r.Status().Update(ctx, &zeebe); err != nil {
+				logger.Error(err, "unable to update status of Zeebe")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+		}
+	}
+	if err == nil {
+		brokerStatefulSet, err = r.reconcileBroker(ctx, &zeebe, identity)
+	}
+	if err == nil {
+		err = r.reconcileGateway(ctx, &zeebe, identity)
+	}
+	if statusErr := r.updateStatus(ctx, &zeebe, brokerStatefulSet, err); statusErr != nil {
+		logger.Error(statusErr, "unable to update status of Zeebe")
+		if err == nil {
+			err = statusErr
+		}
+	}
+	if err != nil {
+		r.event(&zeebe, v12.EventTypeWarning, "ReconcileFailed", err.Error())
 		return ctrl.Result{}, err
 	}
 
-	logger.V(1).Info("created configmap for Zeebe", "configmap", brokerConfigMap)
+	if zeebe.Status.Phase != camundacloudv1.ZeebePhaseRunning ||
+		meta.IsStatusConditionTrue(zeebe.Status.Conditions, camundacloudv1.ZeebeConditionProgressing) {
+		// check back until the brokers are rolled out and ready
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+
+	// We return an empty result and no error,
+	// which indicates to controller-runtime that we’ve successfully reconciled
+	// this object and don’t need to try again until there’s some changes.
+	return ctrl.Result{}, nil
+}
+
+// resolveExporterStorages points the exporters referring to a SecondaryStorage
+// at its endpoint and returns the name of a storage which has not published its
+// endpoint yet, if any. The brokers are not rolled out before the endpoint is
+// known.
+func (r *ZeebeReconciler) resolveExporterStorages(ctx context.Context, zeebe *camundacloudv1.Zeebe) (string, error) {
+	pending, err := resolveSecondaryStorages(ctx, r.Client, zeebe)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "unable to fetch secondary storages of the exporters")
+		return "", err
+	}
+	return pending, nil
+}
+
+// reconcileBroker applies the ConfigMap, headless Service and StatefulSet of the
+// brokers and returns the StatefulSet as seen by the API server. An embedded
+// gateway authenticates clients with the given Identity, if any.
+func (r *ZeebeReconciler) reconcileBroker(ctx context.Context, zeebe *camundacloudv1.Zeebe, identity *camundacloudv1.Identity) (*v1.StatefulSet, error) {
+	logger := log.FromContext(ctx)
+
+	labels := brokerLabels(zeebe)
+
+	brokerConfigMap, err := createBrokerConfigMap(zeebe, labels)
+	if err != nil {
+		logger.Error(err, "unable to render broker configuration")
+		return nil, err
+	}
+
+	if err := ctrl.SetControllerReference(zeebe, brokerConfigMap, r.Scheme); err != nil {
+		logger.Error(err, "unable to construct config map from zeebe CRD")
+		return nil, err
+	}
+
+	if err := r.apply(ctx, zeebe, brokerConfigMap); err != nil {
+		logger.Error(err, "unable to apply config map for Zeebe", "configmap", brokerConfigMap.Name)
+		return nil, err
+	}
+
+	logger.V(1).Info("applied configmap for Zeebe", "configmap", brokerConfigMap.Name)
 
-	brokerService := r.createBrokerService(labels, req.Namespace)
+	if hasLegacyNames(zeebe) {
+		if err := r.labelLegacyBrokers(ctx, zeebe); err != nil {
+			logger.Error(err, "unable to label broker pods created before the rename")
+			return nil, err
+		}
+	}
 
-	if err := ctrl.SetControllerReference(&zeebe, brokerService, r.Scheme); err != nil {
+	brokerService := r.createBrokerService(zeebe, labels)
+
+	if err := ctrl.SetControllerReference(zeebe, brokerService, r.Scheme); err != nil {
 		logger.Error(err, "unable to construct service from zeebe CRD")
-		// don't bother requeuing until we get a change to the spec
-		return ctrl.Result{}, nil
+		return nil, err
 	}
 
-	if err := r.Create(ctx, brokerService); err != nil {
-		logger.Error(err, "unable to create service for Zeebe", "service", brokerService)
-		return ctrl.Result{}, err
+	if err := r.apply(ctx, zeebe, brokerService); err != nil {
+		logger.Error(err, "unable to apply service for Zeebe", "service", brokerService.Name)
+		return nil, err
+	}
+
+	logger.V(1).Info("applied service for Zeebe", "service", brokerService.Name)
+
+	configHash, err := r.brokerConfigHash(ctx, zeebe, brokerConfigMap)
+	if err != nil {
+		logger.Error(err, "unable to compute configuration hash for Zeebe")
+		return nil, err
 	}
 
-	logger.V(1).Info("created service for Zeebe", "service", brokerService)
+	var existingStatefulSet *v1.StatefulSet
+	var statefulSet v1.StatefulSet
+	err = r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerName(zeebe)}, &statefulSet)
+	if err == nil {
+		existingStatefulSet = &statefulSet
+	} else if !errors.IsNotFound(err) {
+		logger.Error(err, "unable to fetch statefulset for Zeebe", "statefulset", brokerName(zeebe))
+		return nil, err
+	}
+
+	restore, err := activeRestore(ctx, r, zeebe)
+	if err != nil {
+		logger.Error(err, "unable to list restores of Zeebe")
+		return nil, err
+	}
 
-	brokerStatefulSet := r.createBrokerStatefulset(zeebe, labels, req)
+	var replicas, partition int32
+	if restore != nil {
+		// the brokers stay stopped while their data is replaced, which makes
+		// scaling and upgrading them moot
+		zeebe.Status.Restore = restore.Name
+		zeebe.Status.Scaling = nil
+		zeebe.Status.Upgrade = nil
+	} else {
+		zeebe.Status.Restore = ""
+
+		replicas, err = r.scaleBrokers(ctx, zeebe, existingStatefulSet)
+		if err != nil {
+			logger.Error(err, "unable to scale brokers")
+			return nil, err
+		}
+
+		partition, err = r.upgradeBrokers(ctx, zeebe, existingStatefulSet, replicas)
+		if err != nil {
+			logger.Error(err, "unable to upgrade brokers")
+			return nil, err
+		}
+	}
+
+	brokerStatefulSet := r.createBrokerStatefulset(zeebe, labels, configHash, replicas, partition)
+	if identity != nil && !zeebe.Spec.Gateway.Standalone {
+		addGatewayAuthentication(&brokerStatefulSet.Spec.Template.Spec.Containers[0], "ZEEBE_BROKER_GATEWAY_", identity)
+	}
+	if existingStatefulSet != nil {
+		keepVolumeClaimTemplates(brokerStatefulSet, existingStatefulSet)
+	}
 
-	if err := ctrl.SetControllerReference(&zeebe, brokerStatefulSet, r.Scheme); err != nil {
+	if err := ctrl.SetControllerReference(zeebe, brokerStatefulSet, r.Scheme); err != nil {
 		logger.Error(err, "unable to construct statefulset from zeebe CRD")
-		// don't bother requeuing until we get a change to the spec
-		return ctrl.Result{}, nil
+		return nil, err
 	}
 
-	if err := r.Create(ctx, brokerStatefulSet); err != nil {
-		logger.Error(err, "unable to create statefulset for Zeebe", "statefulset", brokerStatefulSet)
-		return ctrl.Result{}, err
+	if err := r.apply(ctx, zeebe, brokerStatefulSet); err != nil {
+		logger.Error(err, "unable to apply statefulset for Zeebe", "statefulset", brokerStatefulSet.Name)
+		return nil, err
 	}
 
-	logger.V(1).Info("created statefulset for Zeebe", "statefulset", brokerStatefulSet)
+	logger.V(1).Info("applied statefulset for Zeebe", "statefulset", brokerStatefulSet.Name)
 
-	// We return an empty result and no error,
-	// which indicates to controller-runtime that we’ve successfully reconciled
-	// this object and don’t need to try again until there’s some changes.
-	return ctrl.Result{}, nil
+	if err := r.expandBrokerVolumes(ctx, zeebe, storageSize(zeebe, existingStatefulSet)); err != nil {
+		return nil, err
+	}
+
+	return brokerStatefulSet, nil
 }
 
-func (r *ZeebeReconciler) createBrokerStatefulset(zeebe camundacloudv1.Zeebe, labels map[string]string, req ctrl.Request) *v1.StatefulSet {
-	storageClassName := "ssd"
-	backendSpec := zeebe.Spec.Broker.Backend
+// brokerStartupScript starts a broker with the ordinal of its pod as node id.
+// The ordinal is the suffix after the last dash of the pod name, which keeps
+// digits in the name of the Zeebe resource out of the node id. CA certificates
+// mounted for the exporters are added to a copy of the default truststore.
+const brokerStartupScript = "" +
+	"#!/usr/bin/env bash\n" +
+	"set -eux -o pipefail\n" +
+	"export ZEEBE_BROKER_CLUSTER_NODEID=\"${K8S_NAME##*-}\"\n" +
+	"if [ -d " + exporterCertificatesPath + " ]; then\n" +
+	"  cp \"${JAVA_HOME}/lib/security/cacerts\" /tmp/truststore.jks\n" +
+	"  for cert in " + exporterCertificatesPath + "/*.crt; do\n" +
+	"    keytool -importcert -noprompt -keystore /tmp/truststore.jks -storepass changeit -alias \"$(basename \"$cert\" .crt)\" -file \"$cert\"\n" +
+	"  done\n" +
+	"  export JAVA_OPTS=\"${JAVA_OPTS:-} -Djavax.net.ssl.trustStore=/tmp/truststore.jks -Djavax.net.ssl.trustStorePassword=changeit\"\n" +
+	"fi\n" +
+	"exec /usr/local/zeebe/bin/broker"
+
+func createBrokerConfigMap(zeebe *camundacloudv1.Zeebe, labels map[string]string) (*v12.ConfigMap, error) {
+	applicationYaml, err := renderBrokerConfig(zeebe)
+	if err != nil {
+		return nil, err
+	}
+
+	return &v12.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName(zeebe),
+			Labels:    labels,
+			Namespace: zeebe.Namespace,
+		},
+		Data: map[string]string{
+			"startup.sh":       brokerStartupScript,
+			"application.yaml": applicationYaml,
+		},
+	}, nil
+}
+
+func (r *ZeebeReconciler) createBrokerStatefulset(zeebe *camundacloudv1.Zeebe, labels map[string]string, configHash string, replicas, partition int32) *v1.StatefulSet {
 	brokerStatefulSet := &v1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:    labels,
-			Name:      statefulset_name,
-			Namespace: req.Namespace,
+			Name:      brokerName(zeebe),
+			Namespace: zeebe.Namespace,
 		},
 		Spec: v1.StatefulSetSpec{
 			Selector: &metav1.LabelSelector{
-				MatchLabels: labels,
+				MatchLabels: brokerSelectorLabels(zeebe),
 			},
-			Replicas: backendSpec.Replicas,
-			Template: createPodSpecTemplate(labels, zeebe.Spec, req.Namespace),
-			VolumeClaimTemplates: []v12.PersistentVolumeClaim{
-				{
-					ObjectMeta: metav1.ObjectMeta{
-						Name: "data",
-					},
-					Spec: v12.PersistentVolumeClaimSpec{
-						AccessModes: []v12.PersistentVolumeAccessMode{
-							v12.ReadWriteOnce,
-						},
-						StorageClassName: &storageClassName,
-						Resources: v12.ResourceRequirements{
-							Requests: v12.ResourceList{
-								"storage": *resource.NewQuantity(128*1024*1024, resource.DecimalExponent),
-							},
-						},
-					},
+			ServiceName: brokerName(zeebe),
+			Replicas:    &replicas,
+			UpdateStrategy: v1.StatefulSetUpdateStrategy{
+				Type: v1.RollingUpdateStatefulSetStrategyType,
+				RollingUpdate: &v1.RollingUpdateStatefulSetStrategy{
+					Partition: &partition,
 				},
 			},
+			Template:             createPodSpecTemplate(zeebe, labels, configHash),
+			VolumeClaimTemplates: createVolumeClaimTemplates(zeebe),
 		},
 	}
 	return brokerStatefulSet
 }
 
-func (r *ZeebeReconciler) createBrokerService(labels map[string]string, namespace string) *v12.Service {
+func (r *ZeebeReconciler) createBrokerService(zeebe *camundacloudv1.Zeebe, labels map[string]string) *v12.Service {
 	brokerService := &v12.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels:    labels,
-			Name:      statefulset_name,
-			Namespace: namespace,
+			Name:      brokerName(zeebe),
+			Namespace: zeebe.Namespace,
 		},
 		Spec: v12.ServiceSpec{
 			ClusterIP:                v12.ClusterIPNone,
@@ -216,14 +406,13 @@ func (r *ZeebeReconciler) createBrokerService(labels map[string]string, namespac
 	return brokerService
 }
 
-func createPodSpecTemplate(labels map[string]string, zeebeSpec camundacloudv1.ZeebeSpec, namespace string) v12.PodTemplateSpec {
-	podAddressFormat := "%s-%d.%s.%s.svc.cluster.local:26502"
-
-	replicas := *zeebeSpec.Broker.Backend.Replicas
-	podAddresses := make([]string, replicas)
-	var podIndex int32
-	for podIndex = 0; podIndex < replicas; podIndex++ {
-		podAddresses[podIndex] = fmt.Sprintf(podAddressFormat, statefulset_name, podIndex, statefulset_name, namespace)
+func createPodSpecTemplate(zeebe *camundacloudv1.Zeebe, labels map[string]string, configHash string) v12.PodTemplateSpec {
+	zeebeSpec := zeebe.Spec
+	size := clusterSize(zeebe)
+	podAddresses := make([]string, size)
+	var nodeID int32
+	for nodeID = 0; nodeID < size; nodeID++ {
+		podAddresses[nodeID] = fmt.Sprintf("%s:26502", brokerPodAddress(zeebe, nodeID))
 	}
 
 	backendSpec := zeebeSpec.Broker.Backend
@@ -240,13 +429,9 @@ func createPodSpecTemplate(labels map[string]string, zeebeSpec camundacloudv1.Ze
 			Name:  "ZEEBE_BROKER_CLUSTER_REPLICATIONFACTOR",
 			Value: fmt.Sprintf("%d", *zeebeSpec.Broker.Partitions.Replication),
 		},
-		{ // TODO SUPPORT BIGGER CLUSTER
-			Name:  "ZEEBE_BROKER_CLUSTER_NODEID",
-			Value: fmt.Sprintf("%d", 0),
-		},
 		{
 			Name:  "ZEEBE_BROKER_CLUSTER_CLUSTERSIZE",
-			Value: fmt.Sprintf("%d", *backendSpec.Replicas),
+			Value: fmt.Sprintf("%d", size),
 		},
 		{
 			Name: "K8S_NAME",
@@ -259,7 +444,7 @@ func createPodSpecTemplate(labels map[string]string, zeebeSpec camundacloudv1.Ze
 		},
 		{
 			Name:  "K8S_SERVICE_NAME",
-			Value: statefulset_name,
+			Value: brokerName(zeebe),
 		},
 		{
 			Name: "K8S_NAMESPACE",
@@ -276,7 +461,7 @@ func createPodSpecTemplate(labels map[string]string, zeebeSpec camundacloudv1.Ze
 		},
 		{
 			Name:  "ZEEBE_BROKER_CLUSTER_CLUSTERNAME",
-			Value: "$(K8S_NAMESPACE)",
+			Value: clusterName(zeebe),
 		},
 		{
 			Name:  "ZEEBE_BROKER_GATEWAY_CLUSTER_HOST",
@@ -296,18 +481,83 @@ func createPodSpecTemplate(labels map[string]string, zeebeSpec camundacloudv1.Ze
 		},
 	}
 
+	envs = append(envs, backupEnv(zeebe)...)
+	envs = append(envs, exporterEnv(zeebe)...)
+
 	for _, env := range backendSpec.OverrideEnv {
 		envs = append(envs, env)
 	}
 
+	volumeMounts := []v12.VolumeMount{
+		{
+			Name:      "config",
+			MountPath: "/usr/local/zeebe/config/application.yaml",
+			SubPath:   "application.yaml",
+		},
+		{
+			Name:      "config",
+			MountPath: "/usr/local/bin/startup.sh",
+			SubPath:   "startup.sh",
+		},
+		{
+			Name:      dataVolumeName,
+			MountPath: "/usr/local/zeebe/data",
+		},
+	}
+	volumes := []v12.Volume{
+		{
+			Name: "config",
+			VolumeSource: v12.VolumeSource{
+				ConfigMap: &v12.ConfigMapVolumeSource{
+					LocalObjectReference: v12.LocalObjectReference{
+						Name: configMapName(zeebe),
+					},
+					DefaultMode: getIntPointer(0744),
+				},
+			},
+		},
+	}
+
+	if credentials := gcsCredentials(zeebe); credentials != nil {
+		volumeMounts = append(volumeMounts, v12.VolumeMount{
+			Name:      "backup-credentials",
+			MountPath: gcsCredentialsPath,
+			SubPath:   credentials.Key,
+			ReadOnly:  true,
+		})
+		volumes = append(volumes, v12.Volume{
+			Name: "backup-credentials",
+			VolumeSource: v12.VolumeSource{
+				Secret: &v12.SecretVolumeSource{
+					SecretName: credentials.Name,
+					Optional:   credentials.Optional,
+				},
+			},
+		})
+	}
+
+	certificateVolumes, certificateMounts := exporterCertificates(zeebe)
+	volumes = append(volumes, certificateVolumes...)
+	volumeMounts = append(volumeMounts, certificateMounts...)
+
+	initContainers, jarVolumes := exporterJars(zeebe)
+	volumes = append(volumes, jarVolumes...)
+	if len(initContainers) > 0 {
+		volumeMounts = append(volumeMounts, v12.VolumeMount{Name: "exporters", MountPath: exportersPath})
+	}
+
 	return v12.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: labels,
+			Annotations: map[string]string{
+				configHashAnnotation: configHash,
+			},
 		},
 		Spec: v12.PodSpec{
+			InitContainers: initContainers,
 			Containers: []v12.Container{
 				{
-					Name:            statefulset_name,
+					Name:            app_name,
 					Image:           fmt.Sprintf("%s:%s", backendSpec.ImageName, backendSpec.ImageTag),
 					ImagePullPolicy: v12.PullAlways,
 					Env:             envs,
@@ -338,50 +588,281 @@ func createPodSpecTemplate(labels map[string]string, zeebeSpec camundacloudv1.Ze
 						SuccessThreshold: 1,
 						TimeoutSeconds:   1,
 					},
-					Resources: backendSpec.Resources,
-					VolumeMounts: []v12.VolumeMount{
-						// TODO add config map
-						//{
-						//	Name:      "config",
-						//	MountPath: " /usr/local/zeebe/config/application.yaml",
-						//	SubPath:   "application.yaml",
-						//},
-						{
-							Name:      "config",
-							MountPath: "/usr/local/bin/startup.sh",
-							SubPath:   "startup.sh",
-						},
-						{
-							Name:      "data",
-							MountPath: "/usr/local/zeebe/data",
-						},
-					},
-				},
-			},
-			Volumes: []v12.Volume{
-				{
-					Name: "config",
-					VolumeSource: v12.VolumeSource{
-						ConfigMap: &v12.ConfigMapVolumeSource{
-							LocalObjectReference: v12.LocalObjectReference{
-								Name: "zeebe-configmap",
-							},
-							DefaultMode: getIntPointer(0744),
-						},
-					},
+					Resources:    backendSpec.Resources,
+					VolumeMounts: volumeMounts,
 				},
 			},
+			Volumes: volumes,
 		},
 	}
 }
 
+// apply creates or patches the given object of the cluster with server-side
+// apply and reports out of band changes it reverted. Fields which are not part
+// of the desired object are left to their current owners.
+func (r *ZeebeReconciler) apply(ctx context.Context, zeebe *camundacloudv1.Zeebe, obj client.Object) error {
+	result, err := applyObject(ctx, r.Client, r.Scheme, obj)
+	if err != nil {
+		return err
+	}
+	if !recordDrift(r.Recorder, zeebe, zeebe.Status.ObservedGeneration, obj, result) {
+		switch {
+		case result.created:
+			r.event(zeebe, v12.EventTypeNormal, "Created", "Created %s %s", result.kind, obj.GetName())
+		case result.updated:
+			r.event(zeebe, v12.EventTypeNormal, "Updated", "Updated %s %s", result.kind, obj.GetName())
+		}
+	}
+	return nil
+}
+
+// event records an event on the cluster, which shows up when describing it.
+// Reconcilers without a recorder record nothing.
+func (r *ZeebeReconciler) event(zeebe *camundacloudv1.Zeebe, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(zeebe, eventType, reason, messageFmt, args...)
+	}
+}
+
+// legacyNamesAnnotation marks clusters created before the broker objects were
+// named after the Zeebe resource. Their StatefulSet keeps its fixed name and
+// selector, which cannot be changed without orphaning the pods and volumes.
+const legacyNamesAnnotation = "camunda-cloud.io.camunda/legacy-names"
+
+// legacyBrokerName is the fixed name of the broker StatefulSet and Service of
+// clusters created before the rename
+const legacyBrokerName = "zeebe"
+
+// hasLegacyNames reports whether the cluster keeps the names of its broker
+// objects from before the rename
+func hasLegacyNames(zeebe *camundacloudv1.Zeebe) bool {
+	return zeebe.Annotations[legacyNamesAnnotation] == "true"
+}
+
+// detectLegacyNames marks the cluster with the legacy names annotation if it
+// controls a broker StatefulSet created before the rename. The annotation stays,
+// so the other reconcilers address the brokers the same way.
+func (r *ZeebeReconciler) detectLegacyNames(ctx context.Context, zeebe *camundacloudv1.Zeebe) error {
+	if hasLegacyNames(zeebe) {
+		return nil
+	}
+
+	var statefulSet v1.StatefulSet
+	err := r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: legacyBrokerName}, &statefulSet)
+	if client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err != nil || !metav1.IsControlledBy(&statefulSet, zeebe) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("keeping the broker names of a cluster created before the rename", "statefulset", legacyBrokerName)
+	if zeebe.Annotations == nil {
+		zeebe.Annotations = map[string]string{}
+	}
+	zeebe.Annotations[legacyNamesAnnotation] = "true"
+	return r.Update(ctx, zeebe)
+}
+
+// brokerName returns the name of the broker StatefulSet and its headless Service
+func brokerName(zeebe *camundacloudv1.Zeebe) string {
+	if hasLegacyNames(zeebe) {
+		return legacyBrokerName
+	}
+	return zeebe.Name + "-broker"
+}
+
+// brokerPodName returns the name of the broker pod with the given node id, which
+// is the ordinal of the pod in the broker StatefulSet
+func brokerPodName(zeebe *camundacloudv1.Zeebe, nodeID int32) string {
+	return fmt.Sprintf("%s-%d", brokerName(zeebe), nodeID)
+}
+
+// brokerPodAddress returns the DNS name of a broker pod behind the headless
+// broker Service
+func brokerPodAddress(zeebe *camundacloudv1.Zeebe, nodeID int32) string {
+	return fmt.Sprintf("%s.%s.%s.svc.cluster.local", brokerPodName(zeebe, nodeID), brokerName(zeebe), zeebe.Namespace)
+}
+
+// brokerNodeID returns the node id of a broker pod, derived from its name the
+// same way as the broker startup script does
+func brokerNodeID(podName string) (int32, error) {
+	ordinal := podName[strings.LastIndex(podName, "-")+1:]
+	nodeID, err := strconv.ParseInt(ordinal, 10, 32)
+	if err != nil || nodeID < 0 {
+		return 0, fmt.Errorf("pod %s has no StatefulSet ordinal", podName)
+	}
+	return int32(nodeID), nil
+}
+
+// isBrokerObject reports whether the name is the given base name of a broker
+// object followed by a StatefulSet ordinal, such as the name of a broker pod or
+// of its data volume claim. Labels do not tell the brokers of clusters created
+// before the rename apart from those of other clusters.
+func isBrokerObject(name, base string) bool {
+	ordinal := strings.TrimPrefix(name, base+"-")
+	if ordinal == name {
+		return false
+	}
+	_, err := strconv.ParseUint(ordinal, 10, 31)
+	return err == nil
+}
+
+// configMapName returns the name of the ConfigMap holding the broker configuration
+func configMapName(zeebe *camundacloudv1.Zeebe) string {
+	if hasLegacyNames(zeebe) {
+		return legacyBrokerName + "-configmap"
+	}
+	return zeebe.Name + "-configmap"
+}
+
+// clusterName returns the name the brokers and gateways of the cluster form
+// their cluster under. Clusters created before the rename were named after
+// their namespace.
+func clusterName(zeebe *camundacloudv1.Zeebe) string {
+	if hasLegacyNames(zeebe) {
+		return zeebe.Namespace
+	}
+	return zeebe.Name
+}
+
+// brokerLabels returns the labels of all broker objects and pods, which also
+// select the pods of the broker Service. The instance label keeps the brokers of
+// different Zeebe resources in the same namespace apart.
+func brokerLabels(zeebe *camundacloudv1.Zeebe) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "Operator",
+		"app.kubernetes.io/name":       "zeebe-cluster",
+		"app.kubernetes.io/instance":   zeebe.Name,
+		"app.kubernetes.io/app":        app_name,
+		"app.kubernetes.io/component":  "broker",
+		"app":                          app_name,
+	}
+}
+
+// brokerSelectorLabels returns the selector of the broker StatefulSet. Clusters
+// created before the rename keep their selector without the instance label,
+// since the selector of a StatefulSet is immutable.
+func brokerSelectorLabels(zeebe *camundacloudv1.Zeebe) map[string]string {
+	labels := brokerLabels(zeebe)
+	if hasLegacyNames(zeebe) {
+		delete(labels, "app.kubernetes.io/instance")
+	}
+	return labels
+}
+
+// labelLegacyBrokers adds the instance label to the running broker pods of a
+// cluster created before the rename, so the broker Service keeps selecting them
+// until the StatefulSet rolls the label into its pods.
+func (r *ZeebeReconciler) labelLegacyBrokers(ctx context.Context, zeebe *camundacloudv1.Zeebe) error {
+	var brokerPods v12.PodList
+	if err := r.List(ctx, &brokerPods, client.InNamespace(zeebe.Namespace), client.MatchingLabels(brokerSelectorLabels(zeebe))); err != nil {
+		return err
+	}
+
+	for i := range brokerPods.Items {
+		pod := &brokerPods.Items[i]
+		if !isBrokerObject(pod.Name, brokerName(zeebe)) || pod.Labels["app.kubernetes.io/instance"] == zeebe.Name {
+			continue
+		}
+		patch := client.MergeFrom(pod.DeepCopy())
+		pod.Labels["app.kubernetes.io/instance"] = zeebe.Name
+		if err := r.Patch(ctx, pod, patch); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getIntPointer(val int32) *int32 {
 	return &val
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// zeebesReferencing returns a map function enqueueing the Zeebe resources whose
+// broker configuration refers to the mapped object through the given index
+func (r *ZeebeReconciler) zeebesReferencing(index string) handler.MapFunc {
+	return func(obj client.Object) []reconcile.Request {
+		var zeebes camundacloudv1.ZeebeList
+		if err := r.List(context.Background(), &zeebes, client.InNamespace(obj.GetNamespace()), client.MatchingFields{index: obj.GetName()}); err != nil {
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, zeebe := range zeebes.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&zeebe)})
+		}
+		return requests
+	}
+}
+
+// zeebeForRestore enqueues the cluster a ZeebeRestore restores, which stops its
+// brokers as soon as the restore is created
+func (r *ZeebeReconciler) zeebeForRestore(obj client.Object) []reconcile.Request {
+	restore, ok := obj.(*camundacloudv1.ZeebeRestore)
+	if !ok || restore.Spec.ZeebeRef == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.ZeebeRef}}}
+}
+
+// zeebesForIdentity enqueues the clusters whose gateway authenticates clients
+// with the Identity, so they pick up its address once it is known
+func (r *ZeebeReconciler) zeebesForIdentity(obj client.Object) []reconcile.Request {
+	var zeebes camundacloudv1.ZeebeList
+	if err := r.List(context.Background(), &zeebes, client.InNamespace(obj.GetNamespace())); err != nil {
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, zeebe := range zeebes.Items {
+		if zeebe.Spec.Gateway.IdentityRef == obj.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&zeebe)})
+		}
+	}
+	return requests
+}
+
+// zeebesForStorage enqueues the clusters exporting to a SecondaryStorage, so
+// their brokers roll out once it publishes its endpoint
+func (r *ZeebeReconciler) zeebesForStorage(obj client.Object) []reconcile.Request {
+	var requests []reconcile.Request
+	for _, zeebe := range zeebesUsingStorage(r.Client, obj) {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&zeebe)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. Changes of the
+// owned objects, including their deletion, reconcile the cluster they belong to,
+// as do changes of the restores of the cluster, of the Identity of its gateway
+// and of the secondary storages of its exporters.
+// Changes of the Secrets and ConfigMaps the broker configuration refers to
+// reconcile the clusters using them, which rolls their brokers.
 func (r *ZeebeReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &camundacloudv1.Zeebe{}, secretRefIndex, func(obj client.Object) []string {
+		secrets, _ := brokerConfigRefs(obj.(*camundacloudv1.Zeebe))
+		return secrets
+	}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &camundacloudv1.Zeebe{}, configMapRefIndex, func(obj client.Object) []string {
+		_, configMaps := brokerConfigRefs(obj.(*camundacloudv1.Zeebe))
+		return configMaps
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&camundacloudv1.Zeebe{}).
+		Owns(&v1.StatefulSet{}).
+		Owns(&v1.Deployment{}).
+		Owns(&v12.Service{}).
+		Owns(&v12.ConfigMap{}).
+		Watches(&source.Kind{Type: &camundacloudv1.ZeebeRestore{}}, handler.EnqueueRequestsFromMapFunc(r.zeebeForRestore)).
+		Watches(&source.Kind{Type: &camundacloudv1.Identity{}}, handler.EnqueueRequestsFromMapFunc(r.zeebesForIdentity)).
+		Watches(&source.Kind{Type: &camundacloudv1.SecondaryStorage{}}, handler.EnqueueRequestsFromMapFunc(r.zeebesForStorage)).
+		Watches(&source.Kind{Type: &v12.Secret{}}, handler.EnqueueRequestsFromMapFunc(r.zeebesReferencing(secretRefIndex))).
+		Watches(&source.Kind{Type: &v12.ConfigMap{}}, handler.EnqueueRequestsFromMapFunc(r.zeebesReferencing(configMapRefIndex))).
 		Complete(r)
 }
@@ -0,0 +1,293 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+func testZeebe(name string, brokers int32) *camundacloudv1.Zeebe {
+	zeebe := &camundacloudv1.Zeebe{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "team-1",
+		},
+		Spec: camundacloudv1.ZeebeSpec{
+			Broker: camundacloudv1.BrokerSpec{
+				Backend: camundacloudv1.BackendSpec{
+					Replicas: getIntPointer(brokers),
+				},
+			},
+		},
+	}
+	zeebe.Default()
+	return zeebe
+}
+
+// recordedEvents drains the events recorded so far
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func findEnv(envs []v12.EnvVar, name string) *v12.EnvVar {
+	for i := range envs {
+		if envs[i].Name == name {
+			return &envs[i]
+		}
+	}
+	return nil
+}
+
+// runStartupScript runs the broker startup script for the given pod, printing
+// the node id instead of starting the broker
+func runStartupScript(podName string) string {
+	script := strings.Replace(brokerStartupScript, "exec /usr/local/zeebe/bin/broker", `printf %s "$ZEEBE_BROKER_CLUSTER_NODEID"`, 1)
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Env = append(os.Environ(), "K8S_NAME="+podName)
+	out, err := cmd.Output()
+	Expect(err).NotTo(HaveOccurred())
+	return string(out)
+}
+
+var _ = Describe("Broker node ids", func() {
+	It("uses the StatefulSet ordinal as node id", func() {
+		Expect(brokerNodeID("zeebe-broker-0")).To(Equal(int32(0)))
+		Expect(brokerNodeID("zeebe-broker-12")).To(Equal(int32(12)))
+	})
+
+	It("ignores digits in the name of the Zeebe resource", func() {
+		Expect(brokerNodeID("zeebe-2-broker-1")).To(Equal(int32(1)))
+		Expect(brokerNodeID("team42-7-broker-10")).To(Equal(int32(10)))
+	})
+
+	It("rejects pods without an ordinal", func() {
+		_, err := brokerNodeID("zeebe-broker")
+		Expect(err).To(HaveOccurred())
+		_, err = brokerNodeID("zeebe-broker-")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("derives the same node ids in the startup script", func() {
+		zeebe := testZeebe("cluster-3", 5)
+		var nodeID int32
+		for nodeID = 0; nodeID < 5; nodeID++ {
+			podName := brokerPodName(zeebe, nodeID)
+			Expect(brokerNodeID(podName)).To(Equal(nodeID))
+			Expect(runStartupScript(podName)).To(Equal(fmt.Sprint(nodeID)))
+		}
+	})
+
+	It("does not pin the node id in the pod template", func() {
+		zeebe := testZeebe("cluster-3", 5)
+		template := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash")
+		envs := template.Spec.Containers[0].Env
+		Expect(findEnv(envs, "ZEEBE_BROKER_CLUSTER_NODEID")).To(BeNil())
+		Expect(findEnv(envs, "ZEEBE_BROKER_CLUSTER_CLUSTERSIZE").Value).To(Equal("5"))
+	})
+
+	It("lists every broker of a multi-broker cluster as contact point", func() {
+		zeebe := testZeebe("cluster-3", 3)
+		template := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash")
+		contactPoints := findEnv(template.Spec.Containers[0].Env, "ZEEBE_BROKER_CLUSTER_INITIALCONTACTPOINTS")
+		Expect(strings.Split(contactPoints.Value, ",")).To(Equal([]string{
+			"cluster-3-broker-0.cluster-3-broker.team-1.svc.cluster.local:26502",
+			"cluster-3-broker-1.cluster-3-broker.team-1.svc.cluster.local:26502",
+			"cluster-3-broker-2.cluster-3-broker.team-1.svc.cluster.local:26502",
+		}))
+	})
+
+	It("reports brokers by node id", func() {
+		zeebe := testZeebe("cluster-3", 3)
+		var pods []v12.Pod
+		for _, name := range []string{"cluster-3-broker-2", "cluster-3-broker-10", "cluster-3-broker-1"} {
+			pods = append(pods, v12.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}})
+		}
+		setBrokerStatus(zeebe, (&ZeebeReconciler{}).createBrokerStatefulset(zeebe, brokerLabels(zeebe), "hash", 3, 0), pods)
+
+		var nodeIDs []int32
+		for _, broker := range zeebe.Status.Brokers {
+			nodeIDs = append(nodeIDs, broker.NodeID)
+		}
+		Expect(nodeIDs).To(Equal([]int32{1, 2, 10}))
+	})
+})
+
+var _ = Describe("Zeebe events", func() {
+	It("reports an invalid spec", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Partitions.Count = nil
+		recorder := record.NewFakeRecorder(10)
+
+		s := testScheme()
+		reconciler := &ZeebeReconciler{
+			Client:   fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe).Build(),
+			Scheme:   s,
+			Recorder: recorder,
+		}
+		_, err := reconciler.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(zeebe)})
+		Expect(err).NotTo(HaveOccurred())
+
+		events := recordedEvents(recorder)
+		Expect(events).To(HaveLen(1))
+		Expect(events[0]).To(HavePrefix("Warning InvalidSpec "))
+		Expect(events[0]).To(ContainSubstring("spec.broker.partitions.count"))
+	})
+})
+
+var _ = Describe("Clusters created before the rename", func() {
+	var (
+		ctx   context.Context
+		zeebe *camundacloudv1.Zeebe
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.UID = "cluster-1-uid"
+	})
+
+	// detect marks the cluster if the given StatefulSet shows it predates the rename
+	detect := func(statefulSet *v1.StatefulSet) {
+		s := testScheme()
+		reconciler := &ZeebeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe, statefulSet).Build(),
+			Scheme: s,
+		}
+		Expect(reconciler.detectLegacyNames(ctx, zeebe)).To(Succeed())
+	}
+
+	legacyStatefulSet := func() *v1.StatefulSet {
+		return &v1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "zeebe", Namespace: zeebe.Namespace}}
+	}
+
+	It("keeps the names, selector and cluster name of existing clusters", func() {
+		statefulSet := legacyStatefulSet()
+		Expect(ctrl.SetControllerReference(zeebe, statefulSet, testScheme())).To(Succeed())
+		detect(statefulSet)
+
+		Expect(hasLegacyNames(zeebe)).To(BeTrue())
+		Expect(brokerName(zeebe)).To(Equal("zeebe"))
+		Expect(brokerPodName(zeebe, 1)).To(Equal("zeebe-1"))
+		Expect(configMapName(zeebe)).To(Equal("zeebe-configmap"))
+		Expect(brokerSelectorLabels(zeebe)).NotTo(HaveKey("app.kubernetes.io/instance"))
+		Expect(brokerLabels(zeebe)).To(HaveKeyWithValue("app.kubernetes.io/instance", "cluster-1"))
+
+		template := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash")
+		Expect(findEnv(template.Spec.Containers[0].Env, "ZEEBE_BROKER_CLUSTER_CLUSTERNAME").Value).To(Equal("team-1"))
+		Expect(findEnv(template.Spec.Containers[0].Env, "K8S_SERVICE_NAME").Value).To(Equal("zeebe"))
+	})
+
+	It("labels the running brokers so that other clusters' brokers stay apart", func() {
+		zeebe.Annotations = map[string]string{legacyNamesAnnotation: "true"}
+		legacyPod := &v12.Pod{ObjectMeta: metav1.ObjectMeta{Name: "zeebe-0", Namespace: zeebe.Namespace, Labels: brokerSelectorLabels(zeebe)}}
+		other := testZeebe("cluster-2", 3)
+		otherPod := &v12.Pod{ObjectMeta: metav1.ObjectMeta{Name: brokerPodName(other, 0), Namespace: zeebe.Namespace, Labels: brokerLabels(other)}}
+		s := testScheme()
+		reconciler := &ZeebeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe, legacyPod, otherPod).Build(),
+			Scheme: s,
+		}
+
+		Expect(reconciler.labelLegacyBrokers(ctx, zeebe)).To(Succeed())
+		var brokerPods v12.PodList
+		Expect(reconciler.List(ctx, &brokerPods, client.MatchingLabels(brokerLabels(zeebe)))).To(Succeed())
+		Expect(brokerPods.Items).To(HaveLen(1))
+		Expect(brokerPods.Items[0].Name).To(Equal("zeebe-0"))
+
+		statefulSet := reconciler.createBrokerStatefulset(zeebe, brokerLabels(zeebe), "hash", 3, 0)
+		Expect(statefulSet.Spec.Selector.MatchLabels).To(Equal(brokerSelectorLabels(zeebe)))
+		Expect(statefulSet.Spec.Template.Labels).To(Equal(brokerLabels(zeebe)))
+		Expect(reconciler.createBrokerService(zeebe, brokerLabels(zeebe)).Spec.Selector).To(Equal(brokerLabels(zeebe)))
+	})
+
+	It("names the objects of new clusters after the Zeebe resource", func() {
+		detect(legacyStatefulSet())
+
+		Expect(hasLegacyNames(zeebe)).To(BeFalse())
+		Expect(brokerName(zeebe)).To(Equal("cluster-1-broker"))
+		Expect(configMapName(zeebe)).To(Equal("cluster-1-configmap"))
+		Expect(brokerLabels(zeebe)).To(HaveKeyWithValue("app.kubernetes.io/instance", "cluster-1"))
+		Expect(clusterName(zeebe)).To(Equal("cluster-1"))
+	})
+})
+
+var _ = Describe("Embedded gateway", func() {
+	It("removes only the standalone gateway objects of the cluster", func() {
+		ctx := context.Background()
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.UID = "cluster-1-uid"
+		s := testScheme()
+
+		owned := &v1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: gatewayName(zeebe), Namespace: zeebe.Namespace}}
+		Expect(ctrl.SetControllerReference(zeebe, owned, s)).To(Succeed())
+		foreign := &v12.Service{ObjectMeta: metav1.ObjectMeta{Name: gatewayName(zeebe), Namespace: zeebe.Namespace}}
+		reconciler := &ZeebeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe, owned, foreign).Build(),
+			Scheme: s,
+		}
+
+		Expect(reconciler.reconcileGateway(ctx, zeebe, nil)).To(Succeed())
+		err := reconciler.Get(ctx, client.ObjectKeyFromObject(owned), &v1.Deployment{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(foreign), &v12.Service{})).To(Succeed())
+	})
+})
+
+var _ = Describe("Gateway Identity", func() {
+	It("reconciles the clusters whose gateway uses a changed Identity", func() {
+		authenticated := testZeebe("cluster-1", 3)
+		authenticated.Spec.Gateway.IdentityRef = "identity"
+		other := testZeebe("cluster-2", 3)
+		identity := &camundacloudv1.Identity{ObjectMeta: metav1.ObjectMeta{Name: "identity", Namespace: authenticated.Namespace}}
+
+		s := testScheme()
+		reconciler := &ZeebeReconciler{
+			Client: fake.NewClientBuilder().WithScheme(s).WithObjects(authenticated, other).Build(),
+			Scheme: s,
+		}
+
+		Expect(reconciler.zeebesForIdentity(identity)).To(Equal([]reconcile.Request{
+			{NamespacedName: client.ObjectKeyFromObject(authenticated)},
+		}))
+	})
+})
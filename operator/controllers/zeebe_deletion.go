@@ -0,0 +1,224 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// cleanupFinalizer holds a deleted Zeebe resource back until the broker data
+// and backups were cleaned up according to its deletion policy
+const cleanupFinalizer = "camunda-cloud.io.camunda/cleanup"
+
+// deletionPolicy returns the deletion policy of the cluster, Retain if unset
+func deletionPolicy(zeebe *camundacloudv1.Zeebe) camundacloudv1.DeletionPolicy {
+	if zeebe.Spec.DeletionPolicy == "" {
+		return camundacloudv1.DeletionPolicyRetain
+	}
+	return zeebe.Spec.DeletionPolicy
+}
+
+// ensureFinalizer adds the cleanup finalizer if the deletion policy removes
+// data, and drops it again for Retain, so that retained clusters can always be
+// deleted without the operator.
+func (r *ZeebeReconciler) ensureFinalizer(ctx context.Context, zeebe *camundacloudv1.Zeebe) error {
+	retain := deletionPolicy(zeebe) == camundacloudv1.DeletionPolicyRetain
+	if retain != controllerutil.ContainsFinalizer(zeebe, cleanupFinalizer) {
+		return nil
+	}
+
+	if retain {
+		controllerutil.RemoveFinalizer(zeebe, cleanupFinalizer)
+	} else {
+		controllerutil.AddFinalizer(zeebe, cleanupFinalizer)
+	}
+	return r.Update(ctx, zeebe)
+}
+
+// cleanUp applies the deletion policy of a deleted cluster and releases the
+// resource afterwards. The brokers keep running until then, since the owned
+// objects are only garbage collected once the resource is gone, which lets
+// the final backup be taken and old backups be removed through them.
+func (r *ZeebeReconciler) cleanUp(ctx context.Context, zeebe *camundacloudv1.Zeebe) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if !controllerutil.ContainsFinalizer(zeebe, cleanupFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	switch deletionPolicy(zeebe) {
+	case camundacloudv1.DeletionPolicySnapshotThenDelete:
+		completed, err := r.takeFinalBackup(ctx, zeebe)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if !completed {
+			if finalBackupFailed(zeebe) {
+				// keep the data until the deletion policy is changed
+				return ctrl.Result{}, nil
+			}
+			return ctrl.Result{RequeueAfter: requeueInterval}, nil
+		}
+		if err := r.deleteBrokerVolumes(ctx, zeebe); err != nil {
+			return ctrl.Result{}, err
+		}
+	case camundacloudv1.DeletionPolicyDelete:
+		if err := r.deleteBackups(ctx, zeebe); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.deleteBrokerVolumes(ctx, zeebe); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	logger.Info("cleaned up deleted Zeebe cluster", "policy", deletionPolicy(zeebe))
+	r.event(zeebe, v12.EventTypeNormal, "CleanedUp", "Cleaned up the deleted cluster with the %s policy", deletionPolicy(zeebe))
+	controllerutil.RemoveFinalizer(zeebe, cleanupFinalizer)
+	return ctrl.Result{}, r.Update(ctx, zeebe)
+}
+
+// takeFinalBackup triggers a backup of the deleted cluster and reports whether
+// it completed. A failed backup degrades the cluster instead, which keeps its
+// data until the deletion policy is changed.
+func (r *ZeebeReconciler) takeFinalBackup(ctx context.Context, zeebe *camundacloudv1.Zeebe) (bool, error) {
+	logger := log.FromContext(ctx)
+	status := &zeebe.Status
+	if finalBackupFailed(zeebe) {
+		return false, nil
+	}
+
+	if status.FinalBackupID == 0 {
+		// later than the ids of all backups taken while the cluster existed
+		backupID := zeebe.DeletionTimestamp.Unix()
+		if err := r.Management.TakeBackup(ctx, zeebe, backupID); err != nil {
+			if !isRejected(err) {
+				logger.Error(err, "unable to trigger final backup", "backup", backupID)
+				return false, err
+			}
+			r.failFinalBackup(zeebe, err.Error())
+			return false, r.Status().Update(ctx, zeebe)
+		}
+
+		logger.Info("triggered final backup", "backup", backupID)
+		r.event(zeebe, v12.EventTypeNormal, "FinalBackup", "Taking backup %d before the broker data is deleted", backupID)
+		status.FinalBackupID = backupID
+		status.Phase = camundacloudv1.ZeebePhaseDeleting
+		setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionTrue, "FinalBackup",
+			fmt.Sprintf("Taking backup %d before the broker data is deleted", backupID))
+		return false, r.Status().Update(ctx, zeebe)
+	}
+
+	info, err := r.Management.Backup(ctx, zeebe, status.FinalBackupID)
+	if err != nil {
+		logger.Error(err, "unable to query final backup", "backup", status.FinalBackupID)
+		return false, err
+	}
+	switch info.State {
+	case backupCompleted:
+		logger.Info("completed final backup", "backup", status.FinalBackupID)
+		return true, nil
+	case backupFailed, backupIncomplete, backupDoesNotExist:
+		reason := info.FailureReason
+		if reason == "" {
+			reason = fmt.Sprintf("Backup is %s", info.State)
+		}
+		r.failFinalBackup(zeebe, reason)
+		return false, r.Status().Update(ctx, zeebe)
+	}
+	return false, nil
+}
+
+// finalBackupFailed reports whether the final backup of a deleted cluster failed
+func finalBackupFailed(zeebe *camundacloudv1.Zeebe) bool {
+	condition := meta.FindStatusCondition(zeebe.Status.Conditions, camundacloudv1.ZeebeConditionDegraded)
+	return condition != nil && condition.Status == metav1.ConditionTrue && condition.Reason == "FinalBackupFailed"
+}
+
+func (r *ZeebeReconciler) failFinalBackup(zeebe *camundacloudv1.Zeebe, reason string) {
+	r.event(zeebe, v12.EventTypeWarning, "FinalBackupFailed", "The broker data is kept since the final backup failed: %s", reason)
+	zeebe.Status.Phase = camundacloudv1.ZeebePhaseDegraded
+	setCondition(zeebe, camundacloudv1.ZeebeConditionDegraded, metav1.ConditionTrue, "FinalBackupFailed",
+		fmt.Sprintf("The broker data is kept since the final backup failed: %s. Change the deletion policy to delete the cluster without a backup.", reason))
+}
+
+// deleteBackups removes the backups of all ZeebeBackups of the cluster and the
+// final backup, if any, from the backup store and deletes the ZeebeBackups.
+func (r *ZeebeReconciler) deleteBackups(ctx context.Context, zeebe *camundacloudv1.Zeebe) error {
+	logger := log.FromContext(ctx)
+
+	var backupList camundacloudv1.ZeebeBackupList
+	if err := r.List(ctx, &backupList, client.InNamespace(zeebe.Namespace)); err != nil {
+		return err
+	}
+
+	backupIDs := []int64{}
+	if zeebe.Status.FinalBackupID != 0 {
+		backupIDs = append(backupIDs, zeebe.Status.FinalBackupID)
+	}
+	for _, backup := range backupList.Items {
+		if backup.Spec.ZeebeRef == zeebe.Name && backup.Status.BackupID != 0 {
+			backupIDs = append(backupIDs, backup.Status.BackupID)
+		}
+	}
+	if zeebe.Spec.Broker.Backup != nil {
+		for _, backupID := range backupIDs {
+			err := r.Management.DeleteBackup(ctx, zeebe, backupID)
+			if err != nil && !isNotFound(err) {
+				logger.Error(err, "unable to delete backup from the backup store", "backup", backupID)
+				return err
+			}
+		}
+	}
+
+	for i := range backupList.Items {
+		backup := &backupList.Items[i]
+		if backup.Spec.ZeebeRef != zeebe.Name {
+			continue
+		}
+		if err := r.Delete(ctx, backup); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteBrokerVolumes deletes the data volume claims of the brokers, which are
+// not owned by the Zeebe resource and would outlive it otherwise. Claims in use
+// are only removed once their broker pod is gone.
+func (r *ZeebeReconciler) deleteBrokerVolumes(ctx context.Context, zeebe *camundacloudv1.Zeebe) error {
+	claims, err := r.listBrokerVolumes(ctx, zeebe)
+	if err != nil {
+		return err
+	}
+	for i := range claims {
+		if err := r.Delete(ctx, &claims[i]); client.IgnoreNotFound(err) != nil {
+			log.FromContext(ctx).Error(err, "unable to delete broker volume", "volume", claims[i].Name)
+			return err
+		}
+	}
+	return nil
+}
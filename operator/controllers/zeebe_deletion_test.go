@@ -0,0 +1,194 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("Zeebe deletion", func() {
+	var (
+		ctx        context.Context
+		zeebe      *camundacloudv1.Zeebe
+		management *fakeManagement
+		reconciler *ZeebeReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Backup = &camundacloudv1.BackupStoreSpec{
+			Store: camundacloudv1.BackupStoreS3,
+			S3:    &camundacloudv1.S3BackupStore{BucketName: "backups"},
+		}
+		zeebe.Finalizers = []string{cleanupFinalizer}
+		management = &fakeManagement{}
+	})
+
+	// brokerVolume returns the data volume claim of a broker of the cluster
+	brokerVolume := func(zeebe *camundacloudv1.Zeebe, nodeID int32) *v12.PersistentVolumeClaim {
+		return &v12.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      brokerVolumeName(zeebe, nodeID),
+				Namespace: zeebe.Namespace,
+				Labels:    brokerLabels(zeebe),
+			},
+		}
+	}
+
+	// deleteZeebe deletes the cluster, which the finalizer holds back, and
+	// reconciles it
+	deleteZeebe := func(objects ...client.Object) ctrl.Result {
+		s := testScheme()
+		reconciler = &ZeebeReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(s).WithObjects(append(objects, zeebe)...).Build(),
+			Scheme:     s,
+			Management: management,
+		}
+		Expect(reconciler.Delete(ctx, zeebe)).To(Succeed())
+		return reconcileDeleted(ctx, reconciler, zeebe)
+	}
+
+	volumeExists := func(name string) bool {
+		err := reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: name}, &v12.PersistentVolumeClaim{})
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		return err == nil
+	}
+
+	zeebeExists := func() bool {
+		err := reconciler.Get(ctx, client.ObjectKeyFromObject(zeebe), &camundacloudv1.Zeebe{})
+		Expect(client.IgnoreNotFound(err)).NotTo(HaveOccurred())
+		return err == nil
+	}
+
+	It("only holds back clusters whose data is deleted", func() {
+		zeebe.Finalizers = nil
+		s := testScheme()
+		reconciler = &ZeebeReconciler{Client: fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe).Build(), Scheme: s}
+
+		Expect(reconciler.ensureFinalizer(ctx, zeebe)).To(Succeed())
+		Expect(zeebe.Finalizers).To(BeEmpty())
+
+		zeebe.Spec.DeletionPolicy = camundacloudv1.DeletionPolicyDelete
+		Expect(reconciler.ensureFinalizer(ctx, zeebe)).To(Succeed())
+		Expect(zeebe.Finalizers).To(ConsistOf(cleanupFinalizer))
+
+		zeebe.Spec.DeletionPolicy = camundacloudv1.DeletionPolicyRetain
+		Expect(reconciler.ensureFinalizer(ctx, zeebe)).To(Succeed())
+		Expect(zeebe.Finalizers).To(BeEmpty())
+	})
+
+	It("keeps the broker volumes and backups with the Retain policy", func() {
+		deleteZeebe(brokerVolume(zeebe, 0))
+
+		Expect(zeebeExists()).To(BeFalse())
+		Expect(volumeExists(brokerVolumeName(zeebe, 0))).To(BeTrue())
+		Expect(management.deleted).To(BeEmpty())
+	})
+
+	It("deletes the broker volumes and backups with the Delete policy", func() {
+		zeebe.Spec.DeletionPolicy = camundacloudv1.DeletionPolicyDelete
+		backup := &camundacloudv1.ZeebeBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.ZeebeBackupSpec{ZeebeRef: zeebe.Name},
+			Status:     camundacloudv1.ZeebeBackupStatus{BackupID: 7},
+		}
+		other := &camundacloudv1.ZeebeBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.ZeebeBackupSpec{ZeebeRef: "cluster-2"},
+			Status:     camundacloudv1.ZeebeBackupStatus{BackupID: 8},
+		}
+
+		deleteZeebe(brokerVolume(zeebe, 0), brokerVolume(zeebe, 1), backup, other)
+
+		Expect(zeebeExists()).To(BeFalse())
+		Expect(volumeExists(brokerVolumeName(zeebe, 0))).To(BeFalse())
+		Expect(volumeExists(brokerVolumeName(zeebe, 1))).To(BeFalse())
+		Expect(management.deleted).To(Equal([]int64{7}))
+		err := reconciler.Get(ctx, client.ObjectKeyFromObject(backup), &camundacloudv1.ZeebeBackup{})
+		Expect(errors.IsNotFound(err)).To(BeTrue())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(other), &camundacloudv1.ZeebeBackup{})).To(Succeed())
+	})
+
+	It("deletes only its own broker volumes", func() {
+		zeebe.Spec.DeletionPolicy = camundacloudv1.DeletionPolicyDelete
+		zeebe.Annotations = map[string]string{legacyNamesAnnotation: "true"}
+		other := testZeebe("cluster-2", 3)
+
+		deleteZeebe(brokerVolume(zeebe, 0), brokerVolume(other, 0), brokerVolume(other, 1))
+
+		Expect(zeebeExists()).To(BeFalse())
+		Expect(volumeExists(brokerVolumeName(zeebe, 0))).To(BeFalse())
+		Expect(volumeExists(brokerVolumeName(other, 0))).To(BeTrue())
+		Expect(volumeExists(brokerVolumeName(other, 1))).To(BeTrue())
+	})
+
+	It("deletes the broker volumes once the final backup completed", func() {
+		zeebe.Spec.DeletionPolicy = camundacloudv1.DeletionPolicySnapshotThenDelete
+
+		result := deleteZeebe(brokerVolume(zeebe, 0))
+		Expect(result.RequeueAfter).To(Equal(requeueInterval))
+		Expect(management.backups).To(HaveLen(1))
+		Expect(zeebe.Status.Phase).To(Equal(camundacloudv1.ZeebePhaseDeleting))
+		Expect(volumeExists(brokerVolumeName(zeebe, 0))).To(BeTrue())
+
+		reconcileDeleted(ctx, reconciler, zeebe)
+		Expect(zeebeExists()).To(BeTrue())
+
+		management.backups[zeebe.Status.FinalBackupID].State = backupCompleted
+		reconcileDeleted(ctx, reconciler, zeebe)
+		Expect(zeebeExists()).To(BeFalse())
+		Expect(volumeExists(brokerVolumeName(zeebe, 0))).To(BeFalse())
+		Expect(management.backups).To(HaveLen(1))
+	})
+
+	It("keeps the broker volumes if the final backup failed", func() {
+		zeebe.Spec.DeletionPolicy = camundacloudv1.DeletionPolicySnapshotThenDelete
+
+		deleteZeebe(brokerVolume(zeebe, 0))
+		management.backups[zeebe.Status.FinalBackupID].State = backupFailed
+		result := reconcileDeleted(ctx, reconciler, zeebe)
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(zeebe.Status.Phase).To(Equal(camundacloudv1.ZeebePhaseDegraded))
+		Expect(meta.FindStatusCondition(zeebe.Status.Conditions, camundacloudv1.ZeebeConditionDegraded).Reason).To(Equal("FinalBackupFailed"))
+
+		reconcileDeleted(ctx, reconciler, zeebe)
+		Expect(zeebeExists()).To(BeTrue())
+		Expect(volumeExists(brokerVolumeName(zeebe, 0))).To(BeTrue())
+	})
+})
+
+// reconcileDeleted reconciles the deleted cluster and reads it back, if it
+// still exists
+func reconcileDeleted(ctx context.Context, reconciler *ZeebeReconciler, zeebe *camundacloudv1.Zeebe) ctrl.Result {
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(zeebe)})
+	Expect(err).NotTo(HaveOccurred())
+	Expect(client.IgnoreNotFound(reconciler.Get(ctx, client.ObjectKeyFromObject(zeebe), zeebe))).To(Succeed())
+	return result
+}
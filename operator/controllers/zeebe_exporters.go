@@ -0,0 +1,245 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	v12 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// classes of the exporters which ship with the brokers
+const (
+	elasticsearchExporterClass = "io.camunda.zeebe.exporter.ElasticsearchExporter"
+	opensearchExporterClass    = "io.camunda.zeebe.exporter.opensearch.OpensearchExporter"
+)
+
+// exportersPath is where the exporters volume with the exporter jars is
+// mounted, in the init containers as well as in the broker container
+const exportersPath = "/usr/local/zeebe/exporters"
+
+// exporterInitImage copies exporter jars from URLs, ConfigMaps and Secrets into
+// the exporters volume
+const exporterInitImage = "busybox:1.36"
+
+// exporterJarScript downloads the jar from $JAR_URL and verifies it against
+// $JAR_SHA256 before moving it to $JAR_PATH, so brokers never load a partial jar
+const exporterJarScript = "" +
+	"set -eu\n" +
+	"wget -O \"$JAR_PATH.download\" \"$JAR_URL\"\n" +
+	"echo \"$JAR_SHA256  $JAR_PATH.download\" | sha256sum -c -\n" +
+	"mv \"$JAR_PATH.download\" \"$JAR_PATH\""
+
+// exporterCertificatesPath is where the CA certificates of the exporters are
+// mounted. The broker startup script adds them to the truststore of the broker.
+const exporterCertificatesPath = "/usr/local/zeebe/certs"
+
+// renderExporters renders the exporters into the exporters section of the
+// broker configuration, keyed by their id. Credentials are passed through the
+// environment, see exporterEnv.
+func renderExporters(exporters []camundacloudv1.ExporterSpec) (map[string]interface{}, error) {
+	config := map[string]interface{}{}
+	for _, exporter := range exporters {
+		rendered := map[string]interface{}{}
+		switch {
+		case exporter.Type == camundacloudv1.ExporterElasticsearch && exporter.Elasticsearch != nil:
+			rendered["className"] = elasticsearchExporterClass
+			putSection(rendered, "args", renderSearchExporterArgs(exporter.Elasticsearch))
+		case exporter.Type == camundacloudv1.ExporterOpenSearch && exporter.OpenSearch != nil:
+			rendered["className"] = opensearchExporterClass
+			putSection(rendered, "args", renderSearchExporterArgs(exporter.OpenSearch))
+		case exporter.Type == camundacloudv1.ExporterGeneric && exporter.Generic != nil:
+			generic := exporter.Generic
+			putString(rendered, "className", generic.ClassName)
+			putString(rendered, "jarPath", exporterJarPath(&exporter))
+			if generic.Args != "" {
+				args := map[string]interface{}{}
+				if err := yaml.Unmarshal([]byte(generic.Args), &args); err != nil {
+					return nil, fmt.Errorf("invalid args of exporter %s: %w", exporter.Name, err)
+				}
+				putSection(rendered, "args", args)
+			}
+		default:
+			continue
+		}
+		config[exporter.Name] = rendered
+	}
+	return config, nil
+}
+
+func renderSearchExporterArgs(exporter *camundacloudv1.SearchExporter) map[string]interface{} {
+	index := map[string]interface{}{}
+	putString(index, "prefix", exporter.IndexPrefix)
+
+	bulk := map[string]interface{}{}
+	putInt32(bulk, "size", exporter.Bulk.Size)
+	putInt32(bulk, "delay", exporter.Bulk.Delay)
+
+	args := map[string]interface{}{}
+	putString(args, "url", exporter.URL)
+	putSection(args, "index", index)
+	putSection(args, "bulk", bulk)
+	return args
+}
+
+// searchExporter returns the settings of an Elasticsearch or OpenSearch
+// exporter, or nil for other exporters
+func searchExporter(exporter *camundacloudv1.ExporterSpec) *camundacloudv1.SearchExporter {
+	switch exporter.Type {
+	case camundacloudv1.ExporterElasticsearch:
+		return exporter.Elasticsearch
+	case camundacloudv1.ExporterOpenSearch:
+		return exporter.OpenSearch
+	}
+	return nil
+}
+
+// exporterEnv returns the environment passing the exporter credentials from
+// their Secrets to the brokers
+func exporterEnv(zeebe *camundacloudv1.Zeebe) []v12.EnvVar {
+	var envs []v12.EnvVar
+	for i := range zeebe.Spec.Broker.Exporters {
+		exporter := &zeebe.Spec.Broker.Exporters[i]
+		search := searchExporter(exporter)
+		if search == nil || search.Authentication == nil {
+			continue
+		}
+
+		prefix := fmt.Sprintf("ZEEBE_BROKER_EXPORTERS_%s_ARGS_AUTHENTICATION_", strings.ToUpper(exporter.Name))
+		username := search.Authentication.Username
+		password := search.Authentication.Password
+		envs = append(envs,
+			v12.EnvVar{Name: prefix + "USERNAME", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &username}},
+			v12.EnvVar{Name: prefix + "PASSWORD", ValueFrom: &v12.EnvVarSource{SecretKeyRef: &password}},
+		)
+	}
+	return envs
+}
+
+// exporterCertificates returns the volumes and mounts of the CA certificates
+// the exporters trust
+func exporterCertificates(zeebe *camundacloudv1.Zeebe) ([]v12.Volume, []v12.VolumeMount) {
+	var volumes []v12.Volume
+	var volumeMounts []v12.VolumeMount
+	for i := range zeebe.Spec.Broker.Exporters {
+		exporter := &zeebe.Spec.Broker.Exporters[i]
+		search := searchExporter(exporter)
+		if search == nil || search.CACertificate == nil {
+			continue
+		}
+
+		name := "exporter-ca-" + exporter.Name
+		volumes = append(volumes, v12.Volume{
+			Name: name,
+			VolumeSource: v12.VolumeSource{
+				Secret: &v12.SecretVolumeSource{
+					SecretName: search.CACertificate.Name,
+					Optional:   search.CACertificate.Optional,
+				},
+			},
+		})
+		volumeMounts = append(volumeMounts, v12.VolumeMount{
+			Name:      name,
+			MountPath: path.Join(exporterCertificatesPath, exporter.Name+".crt"),
+			SubPath:   search.CACertificate.Key,
+			ReadOnly:  true,
+		})
+	}
+	return volumes, volumeMounts
+}
+
+// exporterJarPath returns the path of the jar a generic exporter is loaded from,
+// or an empty path if it is loaded from the classpath
+func exporterJarPath(exporter *camundacloudv1.ExporterSpec) string {
+	if exporter.Generic == nil {
+		return ""
+	}
+	if exporter.Generic.Jar != nil {
+		return path.Join(exportersPath, exporter.Name+".jar")
+	}
+	return exporter.Generic.JarPath
+}
+
+// exporterJars returns the init containers which copy the jars of the generic
+// exporters into the exporters volume, together with the volumes they need.
+// The exporters volume is only added if there is a jar to copy.
+func exporterJars(zeebe *camundacloudv1.Zeebe) ([]v12.Container, []v12.Volume) {
+	var initContainers []v12.Container
+	var volumes []v12.Volume
+	exportersMount := v12.VolumeMount{Name: "exporters", MountPath: exportersPath}
+
+	for i := range zeebe.Spec.Broker.Exporters {
+		exporter := &zeebe.Spec.Broker.Exporters[i]
+		if exporter.Type != camundacloudv1.ExporterGeneric || exporter.Generic == nil || exporter.Generic.Jar == nil {
+			continue
+		}
+
+		jar := exporter.Generic.Jar
+		jarPath := exporterJarPath(exporter)
+		container := v12.Container{
+			Name:         "exporter-" + exporter.Name,
+			Image:        exporterInitImage,
+			VolumeMounts: []v12.VolumeMount{exportersMount},
+		}
+
+		switch {
+		case jar.URL != "":
+			container.Command = []string{"sh", "-c", exporterJarScript}
+			container.Env = []v12.EnvVar{
+				{Name: "JAR_URL", Value: jar.URL},
+				{Name: "JAR_SHA256", Value: jar.SHA256},
+				{Name: "JAR_PATH", Value: jarPath},
+			}
+		case jar.ConfigMap != nil || jar.Secret != nil:
+			source := v12.VolumeSource{}
+			if jar.ConfigMap != nil {
+				source.ConfigMap = &v12.ConfigMapVolumeSource{
+					LocalObjectReference: jar.ConfigMap.LocalObjectReference,
+					Items:                []v12.KeyToPath{{Key: jar.ConfigMap.Key, Path: "exporter.jar"}},
+				}
+			} else {
+				source.Secret = &v12.SecretVolumeSource{
+					SecretName: jar.Secret.Name,
+					Items:      []v12.KeyToPath{{Key: jar.Secret.Key, Path: "exporter.jar"}},
+				}
+			}
+			volumeName := "exporter-jar-" + exporter.Name
+			volumes = append(volumes, v12.Volume{Name: volumeName, VolumeSource: source})
+			container.VolumeMounts = append(container.VolumeMounts, v12.VolumeMount{Name: volumeName, MountPath: "/jar", ReadOnly: true})
+			container.Command = []string{"cp", "/jar/exporter.jar", jarPath}
+		case jar.Image != nil:
+			container.Image = jar.Image.Image
+			container.Command = []string{"cp", jar.Image.Path, jarPath}
+		default:
+			continue
+		}
+		initContainers = append(initContainers, container)
+	}
+
+	if len(initContainers) > 0 {
+		volumes = append(volumes, v12.Volume{
+			Name:         "exporters",
+			VolumeSource: v12.VolumeSource{EmptyDir: &v12.EmptyDirVolumeSource{}},
+		})
+	}
+	return initContainers, volumes
+}
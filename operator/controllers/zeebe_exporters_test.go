@@ -0,0 +1,196 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v12 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+func secretKey(name, key string) v12.SecretKeySelector {
+	return v12.SecretKeySelector{LocalObjectReference: v12.LocalObjectReference{Name: name}, Key: key}
+}
+
+var _ = Describe("Broker exporters", func() {
+	var zeebe *camundacloudv1.Zeebe
+
+	BeforeEach(func() {
+		caCertificate := secretKey("elastic-ca", "ca.crt")
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{
+			{
+				Name: "elasticsearch",
+				Type: camundacloudv1.ExporterElasticsearch,
+				Elasticsearch: &camundacloudv1.SearchExporter{
+					URL:         "https://elastic:9200",
+					IndexPrefix: "team-1",
+					Bulk:        camundacloudv1.BulkConfig{Size: getIntPointer(1000), Delay: getIntPointer(5)},
+					Authentication: &camundacloudv1.BasicAuthentication{
+						Username: secretKey("elastic", "username"),
+						Password: secretKey("elastic", "password"),
+					},
+					CACertificate: &caCertificate,
+				},
+			},
+			{
+				Name:       "opensearch",
+				Type:       camundacloudv1.ExporterOpenSearch,
+				OpenSearch: &camundacloudv1.SearchExporter{URL: "http://opensearch:9200"},
+			},
+			{
+				Name: "audit",
+				Type: camundacloudv1.ExporterGeneric,
+				Generic: &camundacloudv1.GenericExporter{
+					ClassName: "com.example.AuditExporter",
+					JarPath:   "/usr/local/zeebe/exporters/audit.jar",
+					Args:      "topic: audit\nbatch:\n  size: 10\n",
+				},
+			},
+		}
+	})
+
+	exporters := func() map[string]interface{} {
+		config, err := renderBrokerConfig(zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		var parsed map[string]interface{}
+		Expect(yaml.Unmarshal([]byte(config), &parsed)).To(Succeed())
+		broker := parsed["zeebe"].(map[string]interface{})["broker"].(map[string]interface{})
+		return broker["exporters"].(map[string]interface{})
+	}
+
+	It("renders the built-in exporters into the broker configuration", func() {
+		Expect(exporters()).To(HaveKeyWithValue("elasticsearch", map[string]interface{}{
+			"className": elasticsearchExporterClass,
+			"args": map[string]interface{}{
+				"url":   "https://elastic:9200",
+				"index": map[string]interface{}{"prefix": "team-1"},
+				"bulk":  map[string]interface{}{"size": float64(1000), "delay": float64(5)},
+			},
+		}))
+		Expect(exporters()).To(HaveKeyWithValue("opensearch", map[string]interface{}{
+			"className": opensearchExporterClass,
+			"args":      map[string]interface{}{"url": "http://opensearch:9200"},
+		}))
+	})
+
+	It("renders generic exporters with their args", func() {
+		Expect(exporters()).To(HaveKeyWithValue("audit", map[string]interface{}{
+			"className": "com.example.AuditExporter",
+			"jarPath":   "/usr/local/zeebe/exporters/audit.jar",
+			"args": map[string]interface{}{
+				"topic": "audit",
+				"batch": map[string]interface{}{"size": float64(10)},
+			},
+		}))
+	})
+
+	It("passes the credentials through the environment", func() {
+		envs := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec.Containers[0].Env
+		username := findEnv(envs, "ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_AUTHENTICATION_USERNAME")
+		Expect(username.ValueFrom.SecretKeyRef.Key).To(Equal("username"))
+		Expect(findEnv(envs, "ZEEBE_BROKER_EXPORTERS_ELASTICSEARCH_ARGS_AUTHENTICATION_PASSWORD")).NotTo(BeNil())
+
+		config, err := renderBrokerConfig(zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).NotTo(ContainSubstring("authentication"))
+	})
+
+	It("mounts the CA certificates for the truststore of the brokers", func() {
+		podSpec := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(v12.VolumeMount{
+			Name:      "exporter-ca-elasticsearch",
+			MountPath: exporterCertificatesPath + "/elasticsearch.crt",
+			SubPath:   "ca.crt",
+			ReadOnly:  true,
+		}))
+		Expect(podSpec.Volumes).To(ContainElement(v12.Volume{
+			Name: "exporter-ca-elasticsearch",
+			VolumeSource: v12.VolumeSource{
+				Secret: &v12.SecretVolumeSource{SecretName: "elastic-ca"},
+			},
+		}))
+	})
+
+	Context("with exporter jars", func() {
+		var checksum string
+
+		BeforeEach(func() {
+			checksum = strings.Repeat("ab", 32)
+			zeebe.Spec.Broker.Exporters = []camundacloudv1.ExporterSpec{
+				{Name: "kafka", Type: camundacloudv1.ExporterGeneric, Generic: &camundacloudv1.GenericExporter{
+					ClassName: "com.example.KafkaExporter",
+					Jar:       &camundacloudv1.ExporterJarSource{URL: "https://example.com/kafka.jar", SHA256: checksum},
+				}},
+				{Name: "hazelcast", Type: camundacloudv1.ExporterGeneric, Generic: &camundacloudv1.GenericExporter{
+					ClassName: "com.example.HazelcastExporter",
+					Jar: &camundacloudv1.ExporterJarSource{ConfigMap: &v12.ConfigMapKeySelector{
+						LocalObjectReference: v12.LocalObjectReference{Name: "hazelcast-exporter"},
+						Key:                  "exporter.jar",
+					}},
+				}},
+				{Name: "audit", Type: camundacloudv1.ExporterGeneric, Generic: &camundacloudv1.GenericExporter{
+					ClassName: "com.example.AuditExporter",
+					Jar:       &camundacloudv1.ExporterJarSource{Image: &camundacloudv1.ExporterJarImage{Image: "example/audit-exporter:1.0", Path: "/audit.jar"}},
+				}},
+			}
+		})
+
+		It("loads the exporters from the exporters volume", func() {
+			Expect(exporters()).To(HaveKeyWithValue("kafka", HaveKeyWithValue("jarPath", exportersPath+"/kafka.jar")))
+			Expect(exporters()).To(HaveKeyWithValue("audit", HaveKeyWithValue("jarPath", exportersPath+"/audit.jar")))
+
+			podSpec := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec
+			Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(v12.VolumeMount{Name: "exporters", MountPath: exportersPath}))
+			Expect(podSpec.Volumes).To(ContainElement(v12.Volume{
+				Name:         "exporters",
+				VolumeSource: v12.VolumeSource{EmptyDir: &v12.EmptyDirVolumeSource{}},
+			}))
+		})
+
+		It("copies every jar with an init container", func() {
+			initContainers := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec.InitContainers
+			Expect(initContainers).To(HaveLen(3))
+
+			download := initContainers[0]
+			Expect(download.Name).To(Equal("exporter-kafka"))
+			Expect(findEnv(download.Env, "JAR_URL").Value).To(Equal("https://example.com/kafka.jar"))
+			Expect(findEnv(download.Env, "JAR_SHA256").Value).To(Equal(checksum))
+
+			fromConfigMap := initContainers[1]
+			Expect(fromConfigMap.Command).To(Equal([]string{"cp", "/jar/exporter.jar", exportersPath + "/hazelcast.jar"}))
+
+			fromImage := initContainers[2]
+			Expect(fromImage.Image).To(Equal("example/audit-exporter:1.0"))
+			Expect(fromImage.Command).To(Equal([]string{"cp", "/audit.jar", exportersPath + "/audit.jar"}))
+		})
+
+		It("does not add the exporters volume without jars", func() {
+			zeebe.Spec.Broker.Exporters = nil
+			podSpec := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec
+			Expect(podSpec.InitContainers).To(BeEmpty())
+			for _, volume := range podSpec.Volumes {
+				Expect(volume.Name).NotTo(Equal("exporters"))
+			}
+		})
+	})
+})
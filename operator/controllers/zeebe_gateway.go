@@ -0,0 +1,320 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// reconcileGateway applies the Deployment and Service of a standalone gateway.
+// If the gateway is embedded into the brokers, left over standalone gateway
+// objects are removed. The gateway authenticates clients with the given
+// Identity, if any.
+func (r *ZeebeReconciler) reconcileGateway(ctx context.Context, zeebe *camundacloudv1.Zeebe, identity *camundacloudv1.Identity) error {
+	logger := log.FromContext(ctx)
+
+	labels := gatewayLabels(zeebe)
+	gatewayDeployment := createGatewayDeployment(zeebe, labels)
+	if identity != nil {
+		addGatewayAuthentication(&gatewayDeployment.Spec.Template.Spec.Containers[0], "ZEEBE_GATEWAY_", identity)
+	}
+	gatewayService := createGatewayService(zeebe, labels)
+
+	if !zeebe.Spec.Gateway.Standalone {
+		for _, obj := range []client.Object{gatewayDeployment, gatewayService} {
+			err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+			if client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			// an object of the same name the cluster did not create is left alone
+			if err != nil || !metav1.IsControlledBy(obj, zeebe) {
+				continue
+			}
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				logger.Error(err, "unable to delete standalone gateway object", "name", obj.GetName())
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := ctrl.SetControllerReference(zeebe, gatewayDeployment, r.Scheme); err != nil {
+		logger.Error(err, "unable to construct gateway deployment from zeebe CRD")
+		return err
+	}
+
+	if err := r.apply(ctx, zeebe, gatewayDeployment); err != nil {
+		logger.Error(err, "unable to apply gateway deployment for Zeebe", "deployment", gatewayDeployment.Name)
+		return err
+	}
+
+	logger.V(1).Info("applied gateway deployment for Zeebe", "deployment", gatewayDeployment.Name)
+
+	if err := ctrl.SetControllerReference(zeebe, gatewayService, r.Scheme); err != nil {
+		logger.Error(err, "unable to construct gateway service from zeebe CRD")
+		return err
+	}
+
+	if err := r.apply(ctx, zeebe, gatewayService); err != nil {
+		logger.Error(err, "unable to apply gateway service for Zeebe", "service", gatewayService.Name)
+		return err
+	}
+
+	logger.V(1).Info("applied gateway service for Zeebe", "service", gatewayService.Name)
+
+	return nil
+}
+
+func createGatewayDeployment(zeebe *camundacloudv1.Zeebe, labels map[string]string) *v1.Deployment {
+	backendSpec := zeebe.Spec.Gateway.Backend
+
+	// the gateway ships with the broker image, so fall back to the broker image
+	imageName := backendSpec.ImageName
+	if imageName == "" {
+		imageName = zeebe.Spec.Broker.Backend.ImageName
+	}
+	imageTag := backendSpec.ImageTag
+	if imageTag == "" {
+		imageTag = zeebe.Spec.Broker.Backend.ImageTag
+	}
+
+	envs := []v12.EnvVar{
+		{
+			Name:  "ZEEBE_STANDALONE_GATEWAY",
+			Value: "true",
+		},
+		{
+			Name: "K8S_NAME",
+			ValueFrom: &v12.EnvVarSource{
+				FieldRef: &v12.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "metadata.name",
+				},
+			},
+		},
+		{
+			Name: "K8S_POD_IP",
+			ValueFrom: &v12.EnvVarSource{
+				FieldRef: &v12.ObjectFieldSelector{
+					APIVersion: "v1",
+					FieldPath:  "status.podIP",
+				},
+			},
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_NETWORK_HOST",
+			Value: "0.0.0.0",
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_CLUSTER_CLUSTERNAME",
+			Value: clusterName(zeebe),
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_CLUSTER_MEMBERID",
+			Value: "$(K8S_NAME)",
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_CLUSTER_HOST",
+			Value: "$(K8S_POD_IP)",
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_CLUSTER_CONTACTPOINT",
+			Value: fmt.Sprintf("%s.%s.svc.cluster.local:26502", brokerName(zeebe), zeebe.Namespace),
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_MONITORING_ENABLED",
+			Value: "true",
+		},
+		{
+			Name:  "ZEEBE_GATEWAY_MONITORING_HOST",
+			Value: "0.0.0.0",
+		},
+		{
+			Name:  "ZEEBE_LOG_STACKDRIVER_SERVICENAME",
+			Value: "zeebe-gateway",
+		},
+		{
+			Name:  "ZEEBE_LOG_STACKDRIVER_SERVICEVERSION",
+			Value: imageTag,
+		},
+	}
+
+	for _, env := range backendSpec.OverrideEnv {
+		envs = append(envs, env)
+	}
+
+	return &v1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      gatewayName(zeebe),
+			Namespace: zeebe.Namespace,
+		},
+		Spec: v1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Replicas: backendSpec.Replicas,
+			Template: v12.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v12.PodSpec{
+					Containers: []v12.Container{
+						{
+							Name:            "zeebe-gateway",
+							Image:           fmt.Sprintf("%s:%s", imageName, imageTag),
+							ImagePullPolicy: v12.PullAlways,
+							Env:             envs,
+							Ports: []v12.ContainerPort{
+								{
+									ContainerPort: 9600,
+									Name:          "http",
+								},
+								{
+									ContainerPort: 26500,
+									Name:          "gateway",
+								},
+								{
+									ContainerPort: 26502,
+									Name:          "internal",
+								},
+							},
+							ReadinessProbe: &v12.Probe{
+								Handler: v12.Handler{
+									TCPSocket: &v12.TCPSocketAction{
+										Port: intstr.IntOrString{
+											IntVal: 26500,
+										},
+									},
+								},
+								PeriodSeconds:    10,
+								SuccessThreshold: 1,
+								TimeoutSeconds:   1,
+							},
+							Resources: backendSpec.Resources,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func createGatewayService(zeebe *camundacloudv1.Zeebe, labels map[string]string) *v12.Service {
+	return &v12.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:    labels,
+			Name:      gatewayName(zeebe),
+			Namespace: zeebe.Namespace,
+		},
+		Spec: v12.ServiceSpec{
+			Type: v12.ServiceTypeClusterIP,
+			Ports: []v12.ServicePort{
+				{
+					Port:     26500,
+					Protocol: v12.ProtocolTCP,
+					Name:     "gateway",
+				},
+				{
+					Port:     9600,
+					Protocol: v12.ProtocolTCP,
+					Name:     "http",
+				},
+			},
+			Selector: labels,
+		},
+	}
+}
+
+// gatewayAddress returns the address clients reach the gateway of the cluster
+// at. An embedded gateway is reached through the headless broker Service.
+func gatewayAddress(zeebe *camundacloudv1.Zeebe) string {
+	service := brokerName(zeebe)
+	if zeebe.Spec.Gateway.Standalone {
+		service = gatewayName(zeebe)
+	}
+	return fmt.Sprintf("%s.%s.svc.cluster.local:26500", service, zeebe.Namespace)
+}
+
+// gatewayIdentity returns the Identity the gateway authenticates clients with, or
+// nil if clients are not authenticated
+func (r *ZeebeReconciler) gatewayIdentity(ctx context.Context, zeebe *camundacloudv1.Zeebe) (*camundacloudv1.Identity, error) {
+	identityRef := zeebe.Spec.Gateway.IdentityRef
+	if identityRef == "" {
+		return nil, nil
+	}
+
+	var identity camundacloudv1.Identity
+	if err := r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: identityRef}, &identity); err != nil {
+		log.FromContext(ctx).Error(err, "unable to fetch Identity of the gateway", "identity", identityRef)
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// addGatewayAuthentication makes the gateway only accept requests with a token
+// of the Identity. The environment goes before the existing one, so overrides
+// of the spec still win.
+func addGatewayAuthentication(container *v12.Container, prefix string, identity *camundacloudv1.Identity) {
+	prefix += "SECURITY_AUTHENTICATION_"
+	envs := []v12.EnvVar{
+		{
+			Name:  prefix + "MODE",
+			Value: "identity",
+		},
+		{
+			Name:  prefix + "IDENTITY_ISSUERBACKENDURL",
+			Value: identityIssuerBackendURL(identity),
+		},
+		{
+			Name:  prefix + "IDENTITY_AUDIENCE",
+			Value: "zeebe-api",
+		},
+		{
+			Name:  prefix + "IDENTITY_BASEURL",
+			Value: identityURL(identity),
+		},
+	}
+	container.Env = append(envs, container.Env...)
+}
+
+// gatewayName returns the name of the standalone gateway Deployment and Service
+func gatewayName(zeebe *camundacloudv1.Zeebe) string {
+	return zeebe.Name + "-gateway"
+}
+
+func gatewayLabels(zeebe *camundacloudv1.Zeebe) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "Operator",
+		"app.kubernetes.io/name":       "zeebe-cluster",
+		"app.kubernetes.io/instance":   zeebe.Name,
+		"app.kubernetes.io/app":        app_name,
+		"app.kubernetes.io/component":  "gateway",
+		"app":                          app_name,
+	}
+}
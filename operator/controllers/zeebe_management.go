@@ -0,0 +1,297 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// managementPort serves the actuator endpoints of the brokers
+const managementPort = 9600
+
+// Status of a cluster topology change as reported by the brokers
+const (
+	topologyChangeCompleted = "COMPLETED"
+	topologyChangeFailed    = "FAILED"
+)
+
+// ClusterTopology is the cluster topology as reported by the cluster management
+// API of the brokers
+type ClusterTopology struct {
+	Version       int64            `json:"version"`
+	Brokers       []BrokerTopology `json:"brokers"`
+	LastChange    *TopologyChange  `json:"lastChange,omitempty"`
+	PendingChange *TopologyChange  `json:"pendingChange,omitempty"`
+}
+
+// BrokerTopology is a single broker of the cluster topology
+type BrokerTopology struct {
+	ID         int32               `json:"id"`
+	State      string              `json:"state"`
+	Partitions []PartitionTopology `json:"partitions"`
+}
+
+// PartitionTopology is a partition replica hosted by a broker
+type PartitionTopology struct {
+	ID    int32  `json:"id"`
+	State string `json:"state"`
+}
+
+// TopologyChange is a change of the cluster topology
+type TopologyChange struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+// PartitionStatus is the state of a partition replica on a single broker
+type PartitionStatus struct {
+	Role                 string           `json:"role"`
+	StreamProcessorPhase string           `json:"streamProcessorPhase,omitempty"`
+	Health               *PartitionHealth `json:"health,omitempty"`
+}
+
+// PartitionHealth is the health of a partition replica, reported by newer brokers
+type PartitionHealth struct {
+	Status string `json:"status"`
+}
+
+// Healthy returns whether the replica takes part in the replication of its
+// partition and processes records
+func (p PartitionStatus) Healthy() bool {
+	if p.Role != "LEADER" && p.Role != "FOLLOWER" {
+		return false
+	}
+	if p.StreamProcessorPhase == "FAILED" {
+		return false
+	}
+	return p.Health == nil || p.Health.Status == "HEALTHY"
+}
+
+// BackupInfo is the state of a backup as reported by the backup API
+type BackupInfo struct {
+	BackupID      int64                 `json:"backupId"`
+	State         string                `json:"state"`
+	FailureReason string                `json:"failureReason,omitempty"`
+	Details       []PartitionBackupInfo `json:"details,omitempty"`
+}
+
+// PartitionBackupInfo is the state of the backup of a single partition
+type PartitionBackupInfo struct {
+	PartitionID        int32  `json:"partitionId"`
+	State              string `json:"state"`
+	CheckpointPosition int64  `json:"checkpointPosition,omitempty"`
+	BrokerVersion      string `json:"brokerVersion,omitempty"`
+	FailureReason      string `json:"failureReason,omitempty"`
+}
+
+// State of a backup as reported by the backup API
+const (
+	backupCompleted    = "COMPLETED"
+	backupFailed       = "FAILED"
+	backupIncomplete   = "INCOMPLETE"
+	backupDoesNotExist = "DOES_NOT_EXIST"
+)
+
+// managementError is returned if the management API answers with an error
+type managementError struct {
+	statusCode int
+	message    string
+}
+
+func (e *managementError) Error() string {
+	return e.message
+}
+
+// isNotFound returns whether the management API does not know the requested
+// object
+func isNotFound(err error) bool {
+	var managementErr *managementError
+	return errors.As(err, &managementErr) && managementErr.statusCode == http.StatusNotFound
+}
+
+// isRejected returns whether the management API rejected a request, which
+// will not succeed if it is repeated
+func isRejected(err error) bool {
+	var managementErr *managementError
+	return errors.As(err, &managementErr) && managementErr.statusCode >= 400 && managementErr.statusCode < 500
+}
+
+// ManagementClient talks to the management port of the brokers of a Zeebe
+// cluster
+type ManagementClient interface {
+	// ScaleBrokers asks the cluster to redistribute its partitions to the given
+	// brokers and returns the id of the resulting topology change
+	ScaleBrokers(ctx context.Context, zeebe *camundacloudv1.Zeebe, brokerIDs []int32) (int64, error)
+
+	// ClusterTopology returns the current cluster topology
+	ClusterTopology(ctx context.Context, zeebe *camundacloudv1.Zeebe) (*ClusterTopology, error)
+
+	// BrokerReady returns whether the broker with the given node id reports ready
+	BrokerReady(ctx context.Context, zeebe *camundacloudv1.Zeebe, nodeID int32) (bool, error)
+
+	// BrokerPartitions returns the partition replicas of the broker with the
+	// given node id by partition id
+	BrokerPartitions(ctx context.Context, zeebe *camundacloudv1.Zeebe, nodeID int32) (map[string]PartitionStatus, error)
+
+	// TakeBackup triggers a backup of all partitions with the given id
+	TakeBackup(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) error
+
+	// Backup returns the state of the backup with the given id
+	Backup(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) (*BackupInfo, error)
+
+	// DeleteBackup removes the backup with the given id from the backup store
+	DeleteBackup(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) error
+}
+
+// NewManagementClient returns a ManagementClient which reaches the brokers and
+// the gateway through their Services
+func NewManagementClient() ManagementClient {
+	return &httpManagementClient{
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type httpManagementClient struct {
+	client *http.Client
+}
+
+func (c *httpManagementClient) ScaleBrokers(ctx context.Context, zeebe *camundacloudv1.Zeebe, brokerIDs []int32) (int64, error) {
+	var response struct {
+		ChangeID int64 `json:"changeId"`
+	}
+	if err := c.do(ctx, http.MethodPost, gatewayManagementURL(zeebe, "/actuator/cluster/brokers"), brokerIDs, &response); err != nil {
+		return 0, err
+	}
+	return response.ChangeID, nil
+}
+
+func (c *httpManagementClient) ClusterTopology(ctx context.Context, zeebe *camundacloudv1.Zeebe) (*ClusterTopology, error) {
+	var topology ClusterTopology
+	if err := c.do(ctx, http.MethodGet, gatewayManagementURL(zeebe, "/actuator/cluster"), nil, &topology); err != nil {
+		return nil, err
+	}
+	return &topology, nil
+}
+
+func (c *httpManagementClient) BrokerReady(ctx context.Context, zeebe *camundacloudv1.Zeebe, nodeID int32) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, brokerURL(zeebe, nodeID, "/ready"), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return false, fmt.Errorf("unable to reach broker %d: %w", nodeID, err)
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300, nil
+}
+
+func (c *httpManagementClient) BrokerPartitions(ctx context.Context, zeebe *camundacloudv1.Zeebe, nodeID int32) (map[string]PartitionStatus, error) {
+	partitions := map[string]PartitionStatus{}
+	if err := c.do(ctx, http.MethodGet, brokerURL(zeebe, nodeID, "/actuator/partitions"), nil, &partitions); err != nil {
+		return nil, err
+	}
+	return partitions, nil
+}
+
+func (c *httpManagementClient) TakeBackup(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) error {
+	body := map[string]int64{"backupId": backupID}
+	return c.do(ctx, http.MethodPost, gatewayManagementURL(zeebe, "/actuator/backups"), body, nil)
+}
+
+func (c *httpManagementClient) Backup(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) (*BackupInfo, error) {
+	var backup BackupInfo
+	path := fmt.Sprintf("/actuator/backups/%d", backupID)
+	if err := c.do(ctx, http.MethodGet, gatewayManagementURL(zeebe, path), nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+func (c *httpManagementClient) DeleteBackup(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) error {
+	path := fmt.Sprintf("/actuator/backups/%d", backupID)
+	return c.do(ctx, http.MethodDelete, gatewayManagementURL(zeebe, path), nil, nil)
+}
+
+// do sends the request body as JSON and decodes the JSON response into the
+// given value
+func (c *httpManagementClient) do(ctx context.Context, method, url string, body interface{}, response interface{}) error {
+	var requestBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		requestBody = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, url, requestBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to reach brokers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &managementError{
+			statusCode: resp.StatusCode,
+			message:    fmt.Sprintf("%s %s failed with status %d: %s", method, url, resp.StatusCode, message),
+		}
+	}
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// clusterURL returns the URL of a management endpoint on any broker of the
+// cluster
+func clusterURL(zeebe *camundacloudv1.Zeebe, path string) string {
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d%s", brokerName(zeebe), zeebe.Namespace, managementPort, path)
+}
+
+// brokerURL returns the URL of a management endpoint of a single broker
+func brokerURL(zeebe *camundacloudv1.Zeebe, nodeID int32, path string) string {
+	return fmt.Sprintf("http://%s:%d%s", brokerPodAddress(zeebe, nodeID), managementPort, path)
+}
+
+// gatewayManagementURL returns the URL of a management endpoint of the gateway,
+// which serves the cluster API and forwards requests like backups to all
+// brokers. The embedded gateway is reached through the brokers.
+func gatewayManagementURL(zeebe *camundacloudv1.Zeebe, path string) string {
+	if !zeebe.Spec.Gateway.Standalone {
+		return clusterURL(zeebe, path)
+	}
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d%s", gatewayName(zeebe), zeebe.Namespace, managementPort, path)
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// clusterSize returns the number of brokers the cluster is bootstrapped with.
+// It stays fixed after the cluster was created, so that scaling does not
+// restart all brokers.
+func clusterSize(zeebe *camundacloudv1.Zeebe) int32 {
+	if zeebe.Status.InitialClusterSize > 0 {
+		return zeebe.Status.InitialClusterSize
+	}
+	return *zeebe.Spec.Broker.Backend.Replicas
+}
+
+// scaleBrokers advances the scaling operation of the brokers and returns the
+// number of replicas the broker StatefulSet should have. Brokers are added
+// before and removed after the partitions are redistributed, so partitions are
+// never assigned to brokers which are not running. Changes of the broker count
+// during a scaling operation are picked up once it completed.
+func (r *ZeebeReconciler) scaleBrokers(ctx context.Context, zeebe *camundacloudv1.Zeebe, existingStatefulSet *v1.StatefulSet) (int32, error) {
+	logger := log.FromContext(ctx)
+	status := &zeebe.Status
+	desired := *zeebe.Spec.Broker.Backend.Replicas
+
+	current := desired
+	if existingStatefulSet != nil && existingStatefulSet.Spec.Replicas != nil {
+		current = *existingStatefulSet.Spec.Replicas
+	}
+
+	if existingStatefulSet == nil || current == 0 {
+		// a new cluster, or one whose brokers were stopped to restore their
+		// data, is bootstrapped with all brokers
+		status.InitialClusterSize = desired
+		status.Scaling = nil
+		return desired, nil
+	}
+	if status.InitialClusterSize == 0 {
+		status.InitialClusterSize = current
+	}
+
+	scaling := status.Scaling
+	if scaling == nil {
+		if current == desired {
+			return current, nil
+		}
+		if status.Upgrade != nil {
+			// keep the broker count until the upgrade completed
+			return current, nil
+		}
+		scaling = &camundacloudv1.ScalingStatus{
+			Step:        camundacloudv1.ScalingStepRedistributingPartitions,
+			FromBrokers: current,
+			ToBrokers:   desired,
+			StartTime:   metav1.Now(),
+		}
+		if desired > current {
+			scaling.Step = camundacloudv1.ScalingStepAddingBrokers
+		}
+		status.Scaling = scaling
+		logger.Info("scaling brokers", "from", current, "to", desired)
+		r.event(zeebe, v12.EventTypeNormal, "ScalingStarted", "Scaling from %d to %d brokers", current, desired)
+	}
+
+	switch scaling.Step {
+	case camundacloudv1.ScalingStepAddingBrokers:
+		if current != scaling.ToBrokers || existingStatefulSet.Status.ReadyReplicas < scaling.ToBrokers {
+			return scaling.ToBrokers, nil
+		}
+		scaling.Step = camundacloudv1.ScalingStepRedistributingPartitions
+		r.event(zeebe, v12.EventTypeNormal, "Scaling", "All %d brokers are ready, redistributing partitions", scaling.ToBrokers)
+		fallthrough
+
+	case camundacloudv1.ScalingStepRedistributingPartitions:
+		replicas := scaling.FromBrokers
+		if scaling.ToBrokers > replicas {
+			replicas = scaling.ToBrokers
+		}
+		done, err := r.redistributePartitions(ctx, zeebe, scaling)
+		if err != nil || !done {
+			return replicas, err
+		}
+		if scaling.ToBrokers > scaling.FromBrokers {
+			logger.Info("scaled brokers", "from", scaling.FromBrokers, "to", scaling.ToBrokers)
+			r.event(zeebe, v12.EventTypeNormal, "ScalingCompleted", "Scaled from %d to %d brokers", scaling.FromBrokers, scaling.ToBrokers)
+			status.Scaling = nil
+			return scaling.ToBrokers, nil
+		}
+		scaling.Step = camundacloudv1.ScalingStepRemovingBrokers
+		r.event(zeebe, v12.EventTypeNormal, "Scaling", "Partitions left the removed brokers, stopping %d brokers", scaling.FromBrokers-scaling.ToBrokers)
+		fallthrough
+
+	case camundacloudv1.ScalingStepRemovingBrokers:
+		if current != scaling.ToBrokers || existingStatefulSet.Status.Replicas > scaling.ToBrokers {
+			return scaling.ToBrokers, nil
+		}
+		logger.Info("scaled brokers", "from", scaling.FromBrokers, "to", scaling.ToBrokers)
+		r.event(zeebe, v12.EventTypeNormal, "ScalingCompleted", "Scaled from %d to %d brokers", scaling.FromBrokers, scaling.ToBrokers)
+		status.Scaling = nil
+		return scaling.ToBrokers, nil
+	}
+
+	return current, fmt.Errorf("unknown scaling step %s", scaling.Step)
+}
+
+// redistributePartitions submits the topology change which assigns the
+// partitions to the target brokers and returns whether it completed. A failed
+// change is submitted again on the next reconciliation.
+func (r *ZeebeReconciler) redistributePartitions(ctx context.Context, zeebe *camundacloudv1.Zeebe, scaling *camundacloudv1.ScalingStatus) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	if scaling.ChangeID == nil {
+		brokerIDs := make([]int32, scaling.ToBrokers)
+		for i := range brokerIDs {
+			brokerIDs[i] = int32(i)
+		}
+		changeID, err := r.Management.ScaleBrokers(ctx, zeebe, brokerIDs)
+		if err != nil {
+			return false, fmt.Errorf("unable to redistribute partitions to %d brokers: %w", scaling.ToBrokers, err)
+		}
+		scaling.ChangeID = &changeID
+		logger.Info("redistributing partitions", "brokers", scaling.ToBrokers, "change", changeID)
+		r.event(zeebe, v12.EventTypeNormal, "Scaling", "Redistributing partitions to %d brokers in topology change %d", scaling.ToBrokers, changeID)
+		return false, nil
+	}
+
+	topology, err := r.Management.ClusterTopology(ctx, zeebe)
+	if err != nil {
+		return false, fmt.Errorf("unable to query cluster topology: %w", err)
+	}
+
+	changeID := *scaling.ChangeID
+	change := topology.LastChange
+	if change == nil || change.ID < changeID {
+		// the change is still pending
+		return false, nil
+	}
+	if change.ID > changeID {
+		// changes are applied one after another, so ours completed before
+		return true, nil
+	}
+
+	switch change.Status {
+	case topologyChangeCompleted:
+		return true, nil
+	case topologyChangeFailed:
+		scaling.ChangeID = nil
+		return false, fmt.Errorf("redistribution of partitions to %d brokers failed in topology change %d", scaling.ToBrokers, changeID)
+	}
+	return false, nil
+}
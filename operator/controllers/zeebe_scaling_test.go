@@ -0,0 +1,275 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/record"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// fakeManagement records the requested topology changes and reports the
+// configured topology and broker health
+type fakeManagement struct {
+	scaledTo   [][]int32
+	topology   ClusterTopology
+	notReady   map[int32]bool
+	partitions map[int32]map[string]PartitionStatus
+	backups    map[int64]*BackupInfo
+	deleted    []int64
+}
+
+func (f *fakeManagement) ScaleBrokers(_ context.Context, _ *camundacloudv1.Zeebe, brokerIDs []int32) (int64, error) {
+	f.scaledTo = append(f.scaledTo, brokerIDs)
+	changeID := int64(len(f.scaledTo))
+	f.topology.PendingChange = &TopologyChange{ID: changeID, Status: "IN_PROGRESS"}
+	return changeID, nil
+}
+
+func (f *fakeManagement) ClusterTopology(_ context.Context, _ *camundacloudv1.Zeebe) (*ClusterTopology, error) {
+	topology := f.topology
+	return &topology, nil
+}
+
+func (f *fakeManagement) BrokerReady(_ context.Context, _ *camundacloudv1.Zeebe, nodeID int32) (bool, error) {
+	return !f.notReady[nodeID], nil
+}
+
+func (f *fakeManagement) BrokerPartitions(_ context.Context, _ *camundacloudv1.Zeebe, nodeID int32) (map[string]PartitionStatus, error) {
+	return f.partitions[nodeID], nil
+}
+
+func (f *fakeManagement) TakeBackup(_ context.Context, _ *camundacloudv1.Zeebe, backupID int64) error {
+	for id := range f.backups {
+		if id >= backupID {
+			return &managementError{statusCode: 409, message: "backup id is not larger than the previous backup id"}
+		}
+	}
+	if f.backups == nil {
+		f.backups = map[int64]*BackupInfo{}
+	}
+	f.backups[backupID] = &BackupInfo{BackupID: backupID, State: "IN_PROGRESS"}
+	return nil
+}
+
+func (f *fakeManagement) Backup(_ context.Context, _ *camundacloudv1.Zeebe, backupID int64) (*BackupInfo, error) {
+	if backup, ok := f.backups[backupID]; ok {
+		return backup, nil
+	}
+	return &BackupInfo{BackupID: backupID, State: backupDoesNotExist}, nil
+}
+
+func (f *fakeManagement) DeleteBackup(_ context.Context, _ *camundacloudv1.Zeebe, backupID int64) error {
+	f.deleted = append(f.deleted, backupID)
+	delete(f.backups, backupID)
+	return nil
+}
+
+// completeChange finishes the pending topology change with the given status
+func (f *fakeManagement) completeChange(status string) {
+	change := f.topology.PendingChange
+	change.Status = status
+	f.topology.LastChange = change
+	f.topology.PendingChange = nil
+}
+
+// recordingTransport answers the requests of the management client with an
+// empty topology change and records them
+type recordingTransport struct {
+	requests []string
+}
+
+func (t *recordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, request.Method+" "+request.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"changeId": 1}`)),
+		Request:    request,
+	}, nil
+}
+
+func brokerStatefulSet(replicas, ready int32) *v1.StatefulSet {
+	return &v1.StatefulSet{
+		Spec: v1.StatefulSetSpec{
+			Replicas: &replicas,
+		},
+		Status: v1.StatefulSetStatus{
+			Replicas:      replicas,
+			ReadyReplicas: ready,
+		},
+	}
+}
+
+var _ = Describe("Broker scaling", func() {
+	var (
+		ctx        context.Context
+		management *fakeManagement
+		reconciler *ZeebeReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		management = &fakeManagement{}
+		reconciler = &ZeebeReconciler{Management: management}
+	})
+
+	scale := func(zeebe *camundacloudv1.Zeebe, existing *v1.StatefulSet) int32 {
+		replicas, err := reconciler.scaleBrokers(ctx, zeebe, existing)
+		Expect(err).NotTo(HaveOccurred())
+		return replicas
+	}
+
+	It("bootstraps new clusters with all brokers", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		Expect(scale(zeebe, nil)).To(Equal(int32(3)))
+		Expect(zeebe.Status.InitialClusterSize).To(Equal(int32(3)))
+		Expect(zeebe.Status.Scaling).To(BeNil())
+	})
+
+	It("keeps the cluster size of the brokers while scaling", func() {
+		zeebe := testZeebe("cluster-1", 5)
+		zeebe.Status.InitialClusterSize = 3
+		template := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash")
+		Expect(findEnv(template.Spec.Containers[0].Env, "ZEEBE_BROKER_CLUSTER_CLUSTERSIZE").Value).To(Equal("3"))
+	})
+
+	It("adds brokers before redistributing partitions", func() {
+		zeebe := testZeebe("cluster-1", 5)
+		zeebe.Status.InitialClusterSize = 3
+
+		Expect(scale(zeebe, brokerStatefulSet(3, 3))).To(Equal(int32(5)))
+		Expect(zeebe.Status.Scaling.Step).To(Equal(camundacloudv1.ScalingStepAddingBrokers))
+		Expect(management.scaledTo).To(BeEmpty())
+
+		By("waiting for the new brokers")
+		Expect(scale(zeebe, brokerStatefulSet(5, 4))).To(Equal(int32(5)))
+		Expect(management.scaledTo).To(BeEmpty())
+
+		Expect(scale(zeebe, brokerStatefulSet(5, 5))).To(Equal(int32(5)))
+		Expect(zeebe.Status.Scaling.Step).To(Equal(camundacloudv1.ScalingStepRedistributingPartitions))
+		Expect(management.scaledTo).To(Equal([][]int32{{0, 1, 2, 3, 4}}))
+
+		By("waiting for the redistribution")
+		Expect(scale(zeebe, brokerStatefulSet(5, 5))).To(Equal(int32(5)))
+		Expect(zeebe.Status.Scaling).NotTo(BeNil())
+
+		management.completeChange(topologyChangeCompleted)
+		Expect(scale(zeebe, brokerStatefulSet(5, 5))).To(Equal(int32(5)))
+		Expect(zeebe.Status.Scaling).To(BeNil())
+		Expect(zeebe.Status.InitialClusterSize).To(Equal(int32(3)))
+	})
+
+	It("redistributes partitions through a standalone gateway", func() {
+		transport := &recordingTransport{}
+		reconciler.Management = &httpManagementClient{client: &http.Client{Transport: transport}}
+		zeebe := testZeebe("cluster-1", 5)
+		zeebe.Spec.Gateway.Standalone = true
+		zeebe.Status.InitialClusterSize = 3
+
+		scale(zeebe, brokerStatefulSet(3, 3))
+		scale(zeebe, brokerStatefulSet(5, 5))
+		Expect(zeebe.Status.Scaling.Step).To(Equal(camundacloudv1.ScalingStepRedistributingPartitions))
+		scale(zeebe, brokerStatefulSet(5, 5))
+
+		Expect(transport.requests).To(ContainElements(
+			"POST http://cluster-1-gateway.team-1.svc.cluster.local:9600/actuator/cluster/brokers",
+			"GET http://cluster-1-gateway.team-1.svc.cluster.local:9600/actuator/cluster",
+		))
+		for _, request := range transport.requests {
+			Expect(request).To(ContainSubstring("cluster-1-gateway"))
+		}
+	})
+
+	It("redistributes partitions before removing brokers", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Status.InitialClusterSize = 5
+
+		Expect(scale(zeebe, brokerStatefulSet(5, 5))).To(Equal(int32(5)))
+		Expect(zeebe.Status.Scaling.Step).To(Equal(camundacloudv1.ScalingStepRedistributingPartitions))
+		Expect(management.scaledTo).To(Equal([][]int32{{0, 1, 2}}))
+
+		management.completeChange(topologyChangeCompleted)
+		Expect(scale(zeebe, brokerStatefulSet(5, 5))).To(Equal(int32(3)))
+		Expect(zeebe.Status.Scaling.Step).To(Equal(camundacloudv1.ScalingStepRemovingBrokers))
+
+		By("waiting for the brokers to stop")
+		existing := brokerStatefulSet(3, 3)
+		existing.Status.Replicas = 4
+		Expect(scale(zeebe, existing)).To(Equal(int32(3)))
+		Expect(zeebe.Status.Scaling).NotTo(BeNil())
+
+		Expect(scale(zeebe, brokerStatefulSet(3, 3))).To(Equal(int32(3)))
+		Expect(zeebe.Status.Scaling).To(BeNil())
+	})
+
+	It("submits a failed redistribution again", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Status.InitialClusterSize = 5
+
+		scale(zeebe, brokerStatefulSet(5, 5))
+		management.completeChange(topologyChangeFailed)
+
+		replicas, err := reconciler.scaleBrokers(ctx, zeebe, brokerStatefulSet(5, 5))
+		Expect(err).To(HaveOccurred())
+		Expect(replicas).To(Equal(int32(5)))
+		Expect(zeebe.Status.Scaling.ChangeID).To(BeNil())
+
+		scale(zeebe, brokerStatefulSet(5, 5))
+		Expect(management.scaledTo).To(HaveLen(2))
+	})
+
+	It("reports the scaling step in the status", func() {
+		zeebe := testZeebe("cluster-1", 5)
+		zeebe.Status.InitialClusterSize = 3
+		existing := brokerStatefulSet(3, 3)
+
+		setZeebeStatus(zeebe, brokerStatefulSet(scale(zeebe, existing), 3), nil)
+		Expect(zeebe.Status.Phase).To(Equal(camundacloudv1.ZeebePhaseScaling))
+		progressing := meta.FindStatusCondition(zeebe.Status.Conditions, camundacloudv1.ZeebeConditionProgressing)
+		Expect(progressing.Reason).To(Equal("Scaling"))
+		Expect(progressing.Message).To(Equal("Scaling from 3 to 5 brokers: AddingBrokers"))
+	})
+
+	It("reports the scaling steps as events", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Status.InitialClusterSize = 5
+
+		scale(zeebe, brokerStatefulSet(5, 5))
+		management.completeChange(topologyChangeCompleted)
+		scale(zeebe, brokerStatefulSet(5, 5))
+		scale(zeebe, brokerStatefulSet(3, 3))
+
+		Expect(recordedEvents(recorder)).To(Equal([]string{
+			"Normal ScalingStarted Scaling from 5 to 3 brokers",
+			"Normal Scaling Redistributing partitions to 3 brokers in topology change 1",
+			"Normal Scaling Partitions left the removed brokers, stopping 2 brokers",
+			"Normal ScalingCompleted Scaled from 5 to 3 brokers",
+		}))
+	})
+})
@@ -0,0 +1,203 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// updateStatus derives the status of the Zeebe resource from the applied broker
+// StatefulSet and its pods and writes it to the status subresource.
+func (r *ZeebeReconciler) updateStatus(ctx context.Context, zeebe *camundacloudv1.Zeebe, brokerStatefulSet *v1.StatefulSet, reconcileErr error) error {
+	if brokerStatefulSet != nil {
+		var brokerPods v12.PodList
+		if err := r.List(ctx, &brokerPods, client.InNamespace(zeebe.Namespace), client.MatchingLabels(brokerLabels(zeebe))); err != nil {
+			return err
+		}
+		setBrokerStatus(zeebe, brokerStatefulSet, brokerPods.Items)
+
+		brokerVolumes, err := r.listBrokerVolumes(ctx, zeebe)
+		if err != nil {
+			return err
+		}
+		setVolumeStatus(zeebe, brokerVolumes)
+	}
+
+	setZeebeStatus(zeebe, brokerStatefulSet, reconcileErr)
+	return r.Status().Update(ctx, zeebe)
+}
+
+// setBrokerStatus records the desired configuration hash and the hash and
+// readiness of every broker pod.
+func setBrokerStatus(zeebe *camundacloudv1.Zeebe, brokerStatefulSet *v1.StatefulSet, brokerPods []v12.Pod) {
+	zeebe.Status.ConfigHash = brokerStatefulSet.Spec.Template.Annotations[configHashAnnotation]
+
+	brokers := make([]camundacloudv1.BrokerStatus, 0, len(brokerPods))
+	for _, pod := range brokerPods {
+		nodeID, err := brokerNodeID(pod.Name)
+		if err != nil {
+			continue
+		}
+		brokers = append(brokers, camundacloudv1.BrokerStatus{
+			Name:       pod.Name,
+			NodeID:     nodeID,
+			ConfigHash: pod.Annotations[configHashAnnotation],
+			Ready:      isPodReady(&pod),
+		})
+	}
+	sort.Slice(brokers, func(i, j int) bool {
+		return brokers[i].NodeID < brokers[j].NodeID
+	})
+	zeebe.Status.Brokers = brokers
+}
+
+func isPodReady(pod *v12.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v12.PodReady {
+			return condition.Status == v12.ConditionTrue
+		}
+	}
+	return false
+}
+
+// setZeebeStatus computes conditions, phase and broker counts. A failed
+// reconciliation is reported as Degraded, keeping the last observed topology.
+func setZeebeStatus(zeebe *camundacloudv1.Zeebe, brokerStatefulSet *v1.StatefulSet, reconcileErr error) {
+	status := &zeebe.Status
+	status.ObservedGeneration = zeebe.Generation
+	if zeebe.Spec.Broker.Backend.Replicas != nil {
+		status.DesiredBrokers = *zeebe.Spec.Broker.Backend.Replicas
+	}
+	imageTag := zeebe.Spec.Broker.Backend.ImageTag
+
+	if reconcileErr != nil {
+		setCondition(zeebe, camundacloudv1.ZeebeConditionDegraded, metav1.ConditionTrue, "ReconcileFailed", reconcileErr.Error())
+		setCondition(zeebe, camundacloudv1.ZeebeConditionReady, metav1.ConditionFalse, "ReconcileFailed", "The cluster could not be reconciled")
+		status.Phase = camundacloudv1.ZeebePhaseDegraded
+		return
+	}
+
+	stsStatus := brokerStatefulSet.Status
+	status.ReadyBrokers = stsStatus.ReadyReplicas
+
+	// while scaling, the StatefulSet runs more or fewer brokers than desired
+	brokers := status.DesiredBrokers
+	if brokerStatefulSet.Spec.Replicas != nil {
+		brokers = *brokerStatefulSet.Spec.Replicas
+	}
+	rolledOut := stsStatus.ObservedGeneration >= brokerStatefulSet.Generation &&
+		stsStatus.Replicas == brokers &&
+		stsStatus.UpdatedReplicas == brokers
+	if rolledOut {
+		status.Version = imageTag
+	}
+
+	if upgrade := status.Upgrade; upgrade != nil {
+		setCondition(zeebe, camundacloudv1.ZeebeConditionUpgrading, metav1.ConditionTrue, "RollingUpdate",
+			fmt.Sprintf("Upgrading broker %d from %s to %s", upgrade.Broker, upgrade.FromVersion, upgrade.ToVersion))
+	} else if status.Version != "" && status.Version != imageTag {
+		setCondition(zeebe, camundacloudv1.ZeebeConditionUpgrading, metav1.ConditionTrue, "RollingUpdate",
+			fmt.Sprintf("Upgrading brokers from %s to %s", status.Version, imageTag))
+	} else {
+		setCondition(zeebe, camundacloudv1.ZeebeConditionUpgrading, metav1.ConditionFalse, "UpToDate",
+			fmt.Sprintf("All brokers run version %s", imageTag))
+	}
+
+	switch {
+	case status.Restore != "":
+		setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionTrue, "Restoring",
+			fmt.Sprintf("Brokers are held stopped by restore %s", status.Restore))
+	case status.Scaling != nil:
+		setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionTrue, "Scaling",
+			fmt.Sprintf("Scaling from %d to %d brokers: %s", status.Scaling.FromBrokers, status.Scaling.ToBrokers, status.Scaling.Step))
+	case !rolledOut:
+		setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionTrue, "RollingOut",
+			fmt.Sprintf("%d of %d brokers updated", stsStatus.UpdatedReplicas, brokers))
+	case volumesExpanding(zeebe):
+		size := storageSize(zeebe, brokerStatefulSet)
+		setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionTrue, "ExpandingVolumes",
+			fmt.Sprintf("Expanding broker volumes to %s", size.String()))
+	default:
+		setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionFalse, "RolloutComplete",
+			"All brokers run the current configuration")
+	}
+
+	brokersMessage := fmt.Sprintf("%d of %d brokers ready", status.ReadyBrokers, brokers)
+	missingBrokers := status.ReadyBrokers < brokers
+	switch {
+	case status.Upgrade != nil && status.Upgrade.Halted:
+		setCondition(zeebe, camundacloudv1.ZeebeConditionDegraded, metav1.ConditionTrue, "UpgradeHalted",
+			fmt.Sprintf("Upgrade to %s halted: %s", status.Upgrade.ToVersion, status.Upgrade.Message))
+	case rolledOut && missingBrokers:
+		setCondition(zeebe, camundacloudv1.ZeebeConditionDegraded, metav1.ConditionTrue, "BrokersUnavailable", brokersMessage)
+	default:
+		setCondition(zeebe, camundacloudv1.ZeebeConditionDegraded, metav1.ConditionFalse, "Reconciled", brokersMessage)
+	}
+
+	if rolledOut && !missingBrokers && status.Scaling == nil && status.Restore == "" {
+		setCondition(zeebe, camundacloudv1.ZeebeConditionReady, metav1.ConditionTrue, "BrokersReady", brokersMessage)
+	} else {
+		setCondition(zeebe, camundacloudv1.ZeebeConditionReady, metav1.ConditionFalse, "BrokersNotReady", brokersMessage)
+	}
+
+	switch {
+	case meta.IsStatusConditionTrue(status.Conditions, camundacloudv1.ZeebeConditionDegraded):
+		status.Phase = camundacloudv1.ZeebePhaseDegraded
+	case status.Restore != "":
+		status.Phase = camundacloudv1.ZeebePhaseRestoring
+	case meta.IsStatusConditionTrue(status.Conditions, camundacloudv1.ZeebeConditionUpgrading):
+		status.Phase = camundacloudv1.ZeebePhaseUpgrading
+	case status.Scaling != nil:
+		status.Phase = camundacloudv1.ZeebePhaseScaling
+	case meta.IsStatusConditionTrue(status.Conditions, camundacloudv1.ZeebeConditionReady):
+		status.Phase = camundacloudv1.ZeebePhaseRunning
+	default:
+		status.Phase = camundacloudv1.ZeebePhasePending
+	}
+}
+
+// setStorageNotReadyStatus reports a cluster whose brokers wait for a
+// SecondaryStorage of their exporters to publish its endpoint.
+func setStorageNotReadyStatus(zeebe *camundacloudv1.Zeebe, storage string) {
+	zeebe.Status.ObservedGeneration = zeebe.Generation
+	message := fmt.Sprintf("Waiting for secondary storage %s to publish its endpoint", storage)
+	setCondition(zeebe, camundacloudv1.ZeebeConditionProgressing, metav1.ConditionTrue, "StorageNotReady", message)
+	setCondition(zeebe, camundacloudv1.ZeebeConditionReady, metav1.ConditionFalse, "StorageNotReady", message)
+	if !meta.IsStatusConditionTrue(zeebe.Status.Conditions, camundacloudv1.ZeebeConditionDegraded) {
+		zeebe.Status.Phase = camundacloudv1.ZeebePhasePending
+	}
+}
+
+func setCondition(zeebe *camundacloudv1.Zeebe, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&zeebe.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: zeebe.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// statusCase is a cluster state together with the status derived from it
+type statusCase struct {
+	// changes the cluster and its StatefulSet, which start out rolled out
+	// with all three brokers ready
+	setUp        func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet)
+	reconcileErr error
+	phase        camundacloudv1.ZeebePhase
+	ready        metav1.ConditionStatus
+	progressing  metav1.ConditionStatus
+	degraded     metav1.ConditionStatus
+	upgrading    metav1.ConditionStatus
+	reason       string
+}
+
+// rolledOutStatefulSet returns a broker StatefulSet which runs the given
+// number of brokers in its current revision
+func rolledOutStatefulSet(replicas, ready int32) *v1.StatefulSet {
+	statefulSet := brokerStatefulSet(replicas, ready)
+	statefulSet.Generation = 2
+	statefulSet.Status.ObservedGeneration = 2
+	statefulSet.Status.UpdatedReplicas = replicas
+	return statefulSet
+}
+
+var _ = Describe("Zeebe status", func() {
+	conditionStatus := func(zeebe *camundacloudv1.Zeebe, conditionType string) metav1.ConditionStatus {
+		condition := meta.FindStatusCondition(zeebe.Status.Conditions, conditionType)
+		Expect(condition).NotTo(BeNil(), conditionType)
+		return condition.Status
+	}
+
+	DescribeTable("conditions and phase",
+		func(c statusCase) {
+			zeebe := testZeebe("cluster-1", 3)
+			zeebe.Generation = 4
+			zeebe.Status.Version = zeebe.Spec.Broker.Backend.ImageTag
+			statefulSet := rolledOutStatefulSet(3, 3)
+			if c.setUp != nil {
+				c.setUp(zeebe, statefulSet)
+			}
+
+			setZeebeStatus(zeebe, statefulSet, c.reconcileErr)
+
+			Expect(zeebe.Status.Phase).To(Equal(c.phase))
+			Expect(zeebe.Status.ObservedGeneration).To(Equal(int64(4)))
+			Expect(conditionStatus(zeebe, camundacloudv1.ZeebeConditionReady)).To(Equal(c.ready))
+			Expect(conditionStatus(zeebe, camundacloudv1.ZeebeConditionDegraded)).To(Equal(c.degraded))
+			if c.reconcileErr == nil {
+				Expect(conditionStatus(zeebe, camundacloudv1.ZeebeConditionProgressing)).To(Equal(c.progressing))
+				Expect(conditionStatus(zeebe, camundacloudv1.ZeebeConditionUpgrading)).To(Equal(c.upgrading))
+			}
+			if c.reason != "" {
+				var reasons []string
+				for _, condition := range zeebe.Status.Conditions {
+					if condition.Status == metav1.ConditionTrue {
+						reasons = append(reasons, condition.Reason)
+					}
+				}
+				Expect(reasons).To(ContainElement(c.reason))
+			}
+		},
+		Entry("running cluster", statusCase{
+			phase:       camundacloudv1.ZeebePhaseRunning,
+			ready:       metav1.ConditionTrue,
+			progressing: metav1.ConditionFalse,
+			degraded:    metav1.ConditionFalse,
+			upgrading:   metav1.ConditionFalse,
+		}),
+		Entry("new cluster waiting for its brokers", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				zeebe.Status.Version = ""
+				statefulSet.Status = v1.StatefulSetStatus{}
+			},
+			phase:       camundacloudv1.ZeebePhasePending,
+			ready:       metav1.ConditionFalse,
+			progressing: metav1.ConditionTrue,
+			degraded:    metav1.ConditionFalse,
+			upgrading:   metav1.ConditionFalse,
+			reason:      "RollingOut",
+		}),
+		Entry("rolled out cluster with a broker down", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				statefulSet.Status.ReadyReplicas = 2
+			},
+			phase:       camundacloudv1.ZeebePhaseDegraded,
+			ready:       metav1.ConditionFalse,
+			progressing: metav1.ConditionFalse,
+			degraded:    metav1.ConditionTrue,
+			upgrading:   metav1.ConditionFalse,
+			reason:      "BrokersUnavailable",
+		}),
+		Entry("failed reconciliation", statusCase{
+			reconcileErr: fmt.Errorf("unable to apply statefulset"),
+			phase:        camundacloudv1.ZeebePhaseDegraded,
+			ready:        metav1.ConditionFalse,
+			degraded:     metav1.ConditionTrue,
+			reason:       "ReconcileFailed",
+		}),
+		Entry("rolling update to a new version", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				zeebe.Spec.Broker.Backend.ImageTag = "8.5.1"
+				zeebe.Status.Version = "8.5.0"
+				zeebe.Status.Upgrade = &camundacloudv1.UpgradeStatus{FromVersion: "8.5.0", ToVersion: "8.5.1", Broker: 2}
+				statefulSet.Status.UpdatedReplicas = 1
+			},
+			phase:       camundacloudv1.ZeebePhaseUpgrading,
+			ready:       metav1.ConditionFalse,
+			progressing: metav1.ConditionTrue,
+			degraded:    metav1.ConditionFalse,
+			upgrading:   metav1.ConditionTrue,
+			reason:      "RollingUpdate",
+		}),
+		Entry("halted upgrade", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				zeebe.Spec.Broker.Backend.ImageTag = "8.5.1"
+				zeebe.Status.Version = "8.5.0"
+				zeebe.Status.Upgrade = &camundacloudv1.UpgradeStatus{
+					FromVersion: "8.5.0", ToVersion: "8.5.1", Broker: 2, Halted: true, Message: "broker 2 did not become ready",
+				}
+				statefulSet.Status.UpdatedReplicas = 1
+				statefulSet.Status.ReadyReplicas = 2
+			},
+			phase:       camundacloudv1.ZeebePhaseDegraded,
+			ready:       metav1.ConditionFalse,
+			progressing: metav1.ConditionTrue,
+			degraded:    metav1.ConditionTrue,
+			upgrading:   metav1.ConditionTrue,
+			reason:      "UpgradeHalted",
+		}),
+		Entry("scaling out", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				zeebe.Spec.Broker.Backend.Replicas = getIntPointer(5)
+				zeebe.Status.Scaling = &camundacloudv1.ScalingStatus{
+					Step: camundacloudv1.ScalingStepRedistributingPartitions, FromBrokers: 3, ToBrokers: 5,
+				}
+				*statefulSet = *rolledOutStatefulSet(5, 5)
+			},
+			phase:       camundacloudv1.ZeebePhaseScaling,
+			ready:       metav1.ConditionFalse,
+			progressing: metav1.ConditionTrue,
+			degraded:    metav1.ConditionFalse,
+			upgrading:   metav1.ConditionFalse,
+			reason:      "Scaling",
+		}),
+		Entry("restoring a backup", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				zeebe.Status.Restore = "restore-1"
+				*statefulSet = *rolledOutStatefulSet(0, 0)
+			},
+			phase:       camundacloudv1.ZeebePhaseRestoring,
+			ready:       metav1.ConditionFalse,
+			progressing: metav1.ConditionTrue,
+			degraded:    metav1.ConditionFalse,
+			upgrading:   metav1.ConditionFalse,
+			reason:      "Restoring",
+		}),
+		Entry("expanding the broker volumes", statusCase{
+			setUp: func(zeebe *camundacloudv1.Zeebe, statefulSet *v1.StatefulSet) {
+				zeebe.Status.Volumes = []camundacloudv1.VolumeStatus{
+					{Name: "data-cluster-1-broker-0", RequestedSize: "2Gi", Capacity: "1Gi"},
+				}
+			},
+			phase:       camundacloudv1.ZeebePhaseRunning,
+			ready:       metav1.ConditionTrue,
+			progressing: metav1.ConditionTrue,
+			degraded:    metav1.ConditionFalse,
+			upgrading:   metav1.ConditionFalse,
+			reason:      "ExpandingVolumes",
+		}),
+	)
+
+	It("keeps the last observed brokers of a failed reconciliation", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		setZeebeStatus(zeebe, rolledOutStatefulSet(3, 3), nil)
+		Expect(zeebe.Status.ReadyBrokers).To(Equal(int32(3)))
+
+		setZeebeStatus(zeebe, nil, fmt.Errorf("unable to apply statefulset"))
+		Expect(zeebe.Status.ReadyBrokers).To(Equal(int32(3)))
+		Expect(zeebe.Status.Phase).To(Equal(camundacloudv1.ZeebePhaseDegraded))
+	})
+})
@@ -0,0 +1,196 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// dataVolumeName is the name of the volume claim template of the brokers
+const dataVolumeName = "data"
+
+// defaultStorageSize is used for the broker data volumes of new clusters if the
+// spec sets no size
+var defaultStorageSize = resource.MustParse(camundacloudv1.DefaultStorageSize)
+
+// storageSize returns the requested size of the broker data volumes. Without a
+// size in the spec, the volumes of an existing StatefulSet keep the size of its
+// volume claim template, so that they are not expanded to the default.
+func storageSize(zeebe *camundacloudv1.Zeebe, existing *v1.StatefulSet) resource.Quantity {
+	if zeebe.Spec.Broker.Storage.Size != nil {
+		return *zeebe.Spec.Broker.Storage.Size
+	}
+	if existing != nil {
+		for _, template := range existing.Spec.VolumeClaimTemplates {
+			if size, ok := template.Spec.Resources.Requests[v12.ResourceStorage]; template.Name == dataVolumeName && ok {
+				return size
+			}
+		}
+	}
+	return defaultStorageSize
+}
+
+func createVolumeClaimTemplates(zeebe *camundacloudv1.Zeebe) []v12.PersistentVolumeClaim {
+	return volumeClaimTemplates(zeebe.Spec.Broker.Storage, storageSize(zeebe, nil))
+}
+
+// volumeClaimTemplates returns the claim template of the data volume of a
+// StatefulSet with the given storage spec and size
+func volumeClaimTemplates(storageSpec camundacloudv1.StorageSpec, size resource.Quantity) []v12.PersistentVolumeClaim {
+	accessModes := storageSpec.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []v12.PersistentVolumeAccessMode{v12.ReadWriteOnce}
+	}
+
+	return []v12.PersistentVolumeClaim{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: dataVolumeName,
+			},
+			Spec: v12.PersistentVolumeClaimSpec{
+				AccessModes:      accessModes,
+				StorageClassName: storageSpec.StorageClassName,
+				Selector:         storageSpec.Selector,
+				Resources: v12.ResourceRequirements{
+					Requests: v12.ResourceList{
+						v12.ResourceStorage: size,
+					},
+				},
+			},
+		},
+	}
+}
+
+// expandBrokerVolumes grows the data volumes of all brokers to the given size.
+// The volume claim templates of a StatefulSet are immutable, so the existing
+// claims are patched directly. Volumes are never shrunk.
+func (r *ZeebeReconciler) expandBrokerVolumes(ctx context.Context, zeebe *camundacloudv1.Zeebe, size resource.Quantity) error {
+	logger := log.FromContext(ctx)
+
+	claims, err := r.listBrokerVolumes(ctx, zeebe)
+	if err != nil {
+		return err
+	}
+
+	for i := range claims {
+		claim := &claims[i]
+		current := claim.Spec.Resources.Requests[v12.ResourceStorage]
+		if current.Cmp(size) >= 0 {
+			continue
+		}
+
+		patch := client.MergeFrom(claim.DeepCopy())
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = v12.ResourceList{}
+		}
+		claim.Spec.Resources.Requests[v12.ResourceStorage] = size
+		if err := r.Patch(ctx, claim, patch); err != nil {
+			logger.Error(err, "unable to expand broker volume", "volume", claim.Name, "size", size.String())
+			return err
+		}
+
+		logger.V(1).Info("expanding broker volume", "volume", claim.Name, "from", current.String(), "to", size.String())
+		r.event(zeebe, v12.EventTypeNormal, "ExpandingVolume", "Expanding volume %s from %s to %s", claim.Name, current.String(), size.String())
+	}
+
+	return nil
+}
+
+// listBrokerVolumes returns the data volume claims created for the broker
+// StatefulSet, including those of brokers removed by scaling in. The claims are
+// told apart by name, since the selector of a cluster created before the rename
+// matches the claims of every cluster in the namespace.
+func (r *ZeebeReconciler) listBrokerVolumes(ctx context.Context, zeebe *camundacloudv1.Zeebe) ([]v12.PersistentVolumeClaim, error) {
+	var claimList v12.PersistentVolumeClaimList
+	if err := r.List(ctx, &claimList, client.InNamespace(zeebe.Namespace)); err != nil {
+		return nil, err
+	}
+
+	base := dataVolumeName + "-" + brokerName(zeebe)
+	claims := make([]v12.PersistentVolumeClaim, 0, len(claimList.Items))
+	for _, claim := range claimList.Items {
+		if isBrokerObject(claim.Name, base) {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+// keepVolumeClaimTemplates carries the volume claim templates of an existing
+// StatefulSet over to the desired one, since they cannot be changed.
+func keepVolumeClaimTemplates(desired, existing *v1.StatefulSet) {
+	desired.Spec.VolumeClaimTemplates = existing.Spec.VolumeClaimTemplates
+}
+
+// setVolumeStatus records requested size, capacity and expansion progress of
+// every broker data volume.
+func setVolumeStatus(zeebe *camundacloudv1.Zeebe, claims []v12.PersistentVolumeClaim) {
+	volumes := make([]camundacloudv1.VolumeStatus, 0, len(claims))
+	for _, claim := range claims {
+		volume := camundacloudv1.VolumeStatus{
+			Name: claim.Name,
+		}
+		if requested, ok := claim.Spec.Resources.Requests[v12.ResourceStorage]; ok {
+			volume.RequestedSize = requested.String()
+		}
+		if capacity, ok := claim.Status.Capacity[v12.ResourceStorage]; ok {
+			volume.Capacity = capacity.String()
+		}
+		for _, condition := range claim.Status.Conditions {
+			if condition.Status != v12.ConditionTrue {
+				continue
+			}
+			if condition.Type == v12.PersistentVolumeClaimResizing || condition.Type == v12.PersistentVolumeClaimFileSystemResizePending {
+				volume.Expansion = string(condition.Type)
+			}
+		}
+		volumes = append(volumes, volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+	zeebe.Status.Volumes = volumes
+}
+
+// volumesExpanding returns whether any broker data volume is still smaller than
+// requested
+func volumesExpanding(zeebe *camundacloudv1.Zeebe) bool {
+	for _, volume := range zeebe.Status.Volumes {
+		if volume.Expansion != "" {
+			return true
+		}
+		requested, err := resource.ParseQuantity(volume.RequestedSize)
+		if err != nil {
+			continue
+		}
+		capacity, err := resource.ParseQuantity(volume.Capacity)
+		if err != nil || capacity.Cmp(requested) < 0 {
+			return true
+		}
+	}
+	return false
+}
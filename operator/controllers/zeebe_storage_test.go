@@ -0,0 +1,162 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("Broker volumes", func() {
+	var zeebe *camundacloudv1.Zeebe
+
+	BeforeEach(func() {
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Storage.Size = nil
+	})
+
+	// statefulSetWithVolumes returns a broker StatefulSet created with volumes
+	// of the given size
+	statefulSetWithVolumes := func(size string) *v1.StatefulSet {
+		statefulSet := brokerStatefulSet(3, 3)
+		statefulSet.Spec.VolumeClaimTemplates = volumeClaimTemplates(zeebe.Spec.Broker.Storage, resource.MustParse(size))
+		return statefulSet
+	}
+
+	sizeOf := func(quantity resource.Quantity) string {
+		return quantity.String()
+	}
+
+	It("sizes the volumes of new clusters by default", func() {
+		Expect(sizeOf(storageSize(zeebe, nil))).To(Equal(camundacloudv1.DefaultStorageSize))
+	})
+
+	It("keeps the size of the volumes of existing clusters without a size", func() {
+		Expect(sizeOf(storageSize(zeebe, statefulSetWithVolumes("128Mi")))).To(Equal("128Mi"))
+
+		size := resource.MustParse("2Gi")
+		zeebe.Spec.Broker.Storage.Size = &size
+		Expect(sizeOf(storageSize(zeebe, statefulSetWithVolumes("128Mi")))).To(Equal("2Gi"))
+	})
+
+	It("keeps the volume claim templates of an existing StatefulSet", func() {
+		storageClass := "ssd"
+		existing := statefulSetWithVolumes("128Mi")
+		existing.Spec.VolumeClaimTemplates[0].Spec.StorageClassName = &storageClass
+
+		size := resource.MustParse("2Gi")
+		zeebe.Spec.Broker.Storage.Size = &size
+		desired := (&ZeebeReconciler{}).createBrokerStatefulset(zeebe, brokerLabels(zeebe), "hash", 3, 0)
+		keepVolumeClaimTemplates(desired, existing)
+
+		Expect(desired.Spec.VolumeClaimTemplates).To(Equal(existing.Spec.VolumeClaimTemplates))
+	})
+
+	Describe("expansion", func() {
+		var (
+			ctx        context.Context
+			reconciler *ZeebeReconciler
+			recorder   *record.FakeRecorder
+		)
+
+		brokerVolume := func(nodeID, size string) *v12.PersistentVolumeClaim {
+			return &v12.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "data-cluster-1-broker-" + nodeID,
+					Namespace: zeebe.Namespace,
+					Labels:    brokerLabels(zeebe),
+				},
+				Spec: v12.PersistentVolumeClaimSpec{
+					Resources: v12.ResourceRequirements{
+						Requests: v12.ResourceList{v12.ResourceStorage: resource.MustParse(size)},
+					},
+				},
+			}
+		}
+
+		requestedSize := func(name string) string {
+			var claim v12.PersistentVolumeClaim
+			Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: name}, &claim)).To(Succeed())
+			return sizeOf(claim.Spec.Resources.Requests[v12.ResourceStorage])
+		}
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			recorder = record.NewFakeRecorder(10)
+			s := testScheme()
+			reconciler = &ZeebeReconciler{
+				Client:   fake.NewClientBuilder().WithScheme(s).WithObjects(brokerVolume("0", "1Gi"), brokerVolume("1", "4Gi")).Build(),
+				Scheme:   s,
+				Recorder: recorder,
+			}
+		})
+
+		It("grows smaller volumes to the requested size", func() {
+			Expect(reconciler.expandBrokerVolumes(ctx, zeebe, resource.MustParse("2Gi"))).To(Succeed())
+
+			Expect(requestedSize("data-cluster-1-broker-0")).To(Equal("2Gi"))
+			Expect(requestedSize("data-cluster-1-broker-1")).To(Equal("4Gi"))
+			Expect(recordedEvents(recorder)).To(Equal([]string{
+				"Normal ExpandingVolume Expanding volume data-cluster-1-broker-0 from 1Gi to 2Gi",
+			}))
+		})
+
+		It("leaves the volumes of other clusters in the namespace alone", func() {
+			zeebe.Annotations = map[string]string{legacyNamesAnnotation: "true"}
+			other := &v12.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "data-cluster-2-broker-0",
+					Namespace: zeebe.Namespace,
+					Labels:    brokerLabels(testZeebe("cluster-2", 3)),
+				},
+				Spec: brokerVolume("0", "1Gi").Spec,
+			}
+			legacy := brokerVolume("0", "1Gi")
+			legacy.Name = "data-zeebe-0"
+			Expect(reconciler.Create(ctx, other)).To(Succeed())
+			Expect(reconciler.Create(ctx, legacy)).To(Succeed())
+
+			Expect(reconciler.expandBrokerVolumes(ctx, zeebe, resource.MustParse("2Gi"))).To(Succeed())
+			Expect(requestedSize("data-zeebe-0")).To(Equal("2Gi"))
+			Expect(requestedSize("data-cluster-2-broker-0")).To(Equal("1Gi"))
+
+			claims, err := reconciler.listBrokerVolumes(ctx, zeebe)
+			Expect(err).NotTo(HaveOccurred())
+			setVolumeStatus(zeebe, claims)
+			Expect(zeebe.Status.Volumes).To(HaveLen(1))
+			Expect(zeebe.Status.Volumes[0].Name).To(Equal("data-zeebe-0"))
+		})
+
+		It("leaves volumes of the requested size alone", func() {
+			Expect(reconciler.expandBrokerVolumes(ctx, zeebe, resource.MustParse("1Gi"))).To(Succeed())
+
+			Expect(requestedSize("data-cluster-1-broker-0")).To(Equal("1Gi"))
+			Expect(recordedEvents(recorder)).To(BeEmpty())
+		})
+	})
+})
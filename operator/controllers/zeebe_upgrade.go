@@ -0,0 +1,188 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// brokerRejoinTimeout is how long an upgraded broker may take to rejoin the
+// cluster before the upgrade is halted
+const brokerRejoinTimeout = 10 * time.Minute
+
+// checkUpgradePath rejects image tags which cannot be reached from the version
+// the brokers run. It repeats the check of the webhook against the running
+// version, since the webhook might not be installed and a previous upgrade
+// might not have completed. Custom tags cannot be checked.
+func checkUpgradePath(zeebe *camundacloudv1.Zeebe) error {
+	running := zeebe.Status.Version
+	if upgrade := zeebe.Status.Upgrade; upgrade != nil {
+		running = upgrade.FromVersion
+	}
+	requested := zeebe.Spec.Broker.Backend.ImageTag
+	if running == "" || running == requested {
+		return nil
+	}
+
+	from, err := camundacloudv1.ParseZeebeVersion(running)
+	if err != nil {
+		return nil
+	}
+	to, err := camundacloudv1.ParseZeebeVersion(requested)
+	if err != nil {
+		return nil
+	}
+	return camundacloudv1.CheckUpgrade(from, to)
+}
+
+// upgradeBrokers advances the upgrade of the brokers to the requested image tag
+// and returns the partition of the rolling update of the broker StatefulSet,
+// which only updates brokers with a node id of at least the partition. Brokers
+// are upgraded one at a time from the highest node id, and the next broker is
+// only upgraded once the previous one rejoined the cluster with healthy
+// partitions.
+func (r *ZeebeReconciler) upgradeBrokers(ctx context.Context, zeebe *camundacloudv1.Zeebe, existingStatefulSet *v1.StatefulSet, replicas int32) (int32, error) {
+	logger := log.FromContext(ctx)
+	status := &zeebe.Status
+	requested := zeebe.Spec.Broker.Backend.ImageTag
+
+	if existingStatefulSet == nil || status.Version == "" {
+		// nothing to upgrade before the cluster was rolled out once
+		status.Upgrade = nil
+		return 0, nil
+	}
+
+	if err := checkUpgradePath(zeebe); err != nil {
+		return replicas, err
+	}
+
+	upgrade := status.Upgrade
+	if upgrade == nil {
+		if status.Version == requested {
+			return 0, nil
+		}
+		if status.Scaling != nil {
+			// keep all brokers at their version until scaling completed
+			return replicas, nil
+		}
+		upgrade = &camundacloudv1.UpgradeStatus{
+			FromVersion: status.Version,
+		}
+		status.Upgrade = upgrade
+	}
+
+	if upgrade.ToVersion != requested {
+		// start over if the requested version changed during the upgrade
+		upgrade.ToVersion = requested
+		upgrade.Broker = replicas - 1
+		upgrade.BrokerStartTime = metav1.Now()
+		upgrade.Halted = false
+		upgrade.Message = ""
+		logger.Info("upgrading brokers", "from", upgrade.FromVersion, "to", upgrade.ToVersion)
+		r.event(zeebe, v12.EventTypeNormal, "UpgradeStarted", "Upgrading brokers from %s to %s, starting with broker %d", upgrade.FromVersion, upgrade.ToVersion, upgrade.Broker)
+		return upgrade.Broker, nil
+	}
+
+	rejoined, reason, err := r.brokerRejoined(ctx, zeebe, existingStatefulSet, upgrade.Broker)
+	if err != nil {
+		return upgrade.Broker, err
+	}
+	if !rejoined {
+		upgrade.Message = reason
+		if !upgrade.Halted && time.Since(upgrade.BrokerStartTime.Time) > brokerRejoinTimeout {
+			logger.Info("halting upgrade, broker did not rejoin the cluster", "broker", upgrade.Broker, "reason", reason)
+			r.event(zeebe, v12.EventTypeWarning, "UpgradeHalted", "Broker %d did not rejoin the cluster after the upgrade to %s: %s", upgrade.Broker, upgrade.ToVersion, reason)
+			upgrade.Halted = true
+		}
+		return upgrade.Broker, nil
+	}
+
+	logger.Info("upgraded broker", "broker", upgrade.Broker, "version", upgrade.ToVersion)
+	upgrade.Halted = false
+	upgrade.Message = ""
+	if upgrade.Broker <= 0 {
+		r.event(zeebe, v12.EventTypeNormal, "UpgradeCompleted", "All brokers run %s", upgrade.ToVersion)
+		status.Version = upgrade.ToVersion
+		status.Upgrade = nil
+		return 0, nil
+	}
+	r.event(zeebe, v12.EventTypeNormal, "BrokerUpgraded", "Broker %d rejoined the cluster with %s, upgrading broker %d", upgrade.Broker, upgrade.ToVersion, upgrade.Broker-1)
+	upgrade.Broker--
+	upgrade.BrokerStartTime = metav1.Now()
+	return upgrade.Broker, nil
+}
+
+// brokerRejoined returns whether the broker with the given node id runs the
+// current revision of the StatefulSet, reports ready on its management port
+// and all its partition replicas are healthy. Otherwise, it describes what the
+// broker is waiting for.
+func (r *ZeebeReconciler) brokerRejoined(ctx context.Context, zeebe *camundacloudv1.Zeebe, existingStatefulSet *v1.StatefulSet, nodeID int32) (bool, string, error) {
+	backendSpec := zeebe.Spec.Broker.Backend
+	image := fmt.Sprintf("%s:%s", backendSpec.ImageName, backendSpec.ImageTag)
+	if existingStatefulSet.Spec.Template.Spec.Containers[0].Image != image ||
+		existingStatefulSet.Status.ObservedGeneration < existingStatefulSet.Generation {
+		return false, "Waiting for the broker StatefulSet to pick up the new version", nil
+	}
+
+	var pod v12.Pod
+	err := r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerPodName(zeebe, nodeID)}, &pod)
+	if errors.IsNotFound(err) {
+		return false, fmt.Sprintf("Broker %d is not running", nodeID), nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if pod.Labels[v1.ControllerRevisionHashLabelKey] != existingStatefulSet.Status.UpdateRevision {
+		return false, fmt.Sprintf("Broker %d is being restarted", nodeID), nil
+	}
+	if !isPodReady(&pod) {
+		return false, fmt.Sprintf("Broker %d is not ready", nodeID), nil
+	}
+
+	// the broker might still be starting, so management errors only delay the upgrade
+	ready, err := r.Management.BrokerReady(ctx, zeebe, nodeID)
+	if err != nil || !ready {
+		return false, fmt.Sprintf("Broker %d does not report ready", nodeID), nil
+	}
+	partitions, err := r.Management.BrokerPartitions(ctx, zeebe, nodeID)
+	if err != nil {
+		return false, fmt.Sprintf("Unable to query the partitions of broker %d: %s", nodeID, err), nil
+	}
+	partitionIDs := make([]string, 0, len(partitions))
+	for partitionID := range partitions {
+		partitionIDs = append(partitionIDs, partitionID)
+	}
+	sort.Strings(partitionIDs)
+	for _, partitionID := range partitionIDs {
+		if partition := partitions[partitionID]; !partition.Healthy() {
+			return false, fmt.Sprintf("Partition %s on broker %d is not healthy, its role is %s", partitionID, nodeID, partition.Role), nil
+		}
+	}
+	return true, "", nil
+}
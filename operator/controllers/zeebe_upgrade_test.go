@@ -0,0 +1,194 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+const updateRevision = "cluster-1-broker-new"
+
+// upgradedStatefulSet returns the broker StatefulSet after it picked up the
+// requested version of the brokers
+func upgradedStatefulSet(zeebe *camundacloudv1.Zeebe) *v1.StatefulSet {
+	replicas := *zeebe.Spec.Broker.Backend.Replicas
+	sts := (&ZeebeReconciler{}).createBrokerStatefulset(zeebe, brokerLabels(zeebe), "hash", replicas, 0)
+	sts.Generation = 2
+	sts.Status = v1.StatefulSetStatus{
+		ObservedGeneration: 2,
+		Replicas:           replicas,
+		ReadyReplicas:      replicas,
+		UpdateRevision:     updateRevision,
+	}
+	return sts
+}
+
+func brokerPod(zeebe *camundacloudv1.Zeebe, nodeID int32, revision string, ready bool) *v12.Pod {
+	readyStatus := v12.ConditionFalse
+	if ready {
+		readyStatus = v12.ConditionTrue
+	}
+	return &v12.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      brokerPodName(zeebe, nodeID),
+			Namespace: zeebe.Namespace,
+			Labels:    map[string]string{v1.ControllerRevisionHashLabelKey: revision},
+		},
+		Status: v12.PodStatus{
+			Conditions: []v12.PodCondition{{Type: v12.PodReady, Status: readyStatus}},
+		},
+	}
+}
+
+var _ = Describe("Broker upgrades", func() {
+	var (
+		ctx        context.Context
+		zeebe      *camundacloudv1.Zeebe
+		management *fakeManagement
+		reconciler *ZeebeReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		zeebe = testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Backend.ImageTag = "8.3.0"
+		zeebe.Status.Version = "8.2.5"
+		management = &fakeManagement{}
+		reconciler = &ZeebeReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(),
+			Management: management,
+		}
+	})
+
+	upgrade := func(existing *v1.StatefulSet) int32 {
+		partition, err := reconciler.upgradeBrokers(ctx, zeebe, existing, 3)
+		Expect(err).NotTo(HaveOccurred())
+		return partition
+	}
+
+	setPod := func(pod *v12.Pod) {
+		_ = reconciler.Delete(ctx, pod)
+		Expect(reconciler.Create(ctx, pod)).To(Succeed())
+	}
+
+	It("rolls brokers one at a time from the highest node id", func() {
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(2)))
+		Expect(zeebe.Status.Upgrade.FromVersion).To(Equal("8.2.5"))
+		Expect(zeebe.Status.Upgrade.ToVersion).To(Equal("8.3.0"))
+
+		By("waiting for the broker to restart")
+		setPod(brokerPod(zeebe, 2, "old", true))
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(2)))
+		Expect(zeebe.Status.Upgrade.Message).To(Equal("Broker 2 is being restarted"))
+
+		setPod(brokerPod(zeebe, 2, updateRevision, true))
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(1)))
+
+		setPod(brokerPod(zeebe, 1, updateRevision, true))
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(0)))
+
+		setPod(brokerPod(zeebe, 0, updateRevision, true))
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(0)))
+		Expect(zeebe.Status.Upgrade).To(BeNil())
+		Expect(zeebe.Status.Version).To(Equal("8.3.0"))
+	})
+
+	It("waits until the partitions of the broker are healthy", func() {
+		upgrade(upgradedStatefulSet(zeebe))
+		setPod(brokerPod(zeebe, 2, updateRevision, true))
+
+		management.notReady = map[int32]bool{2: true}
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(2)))
+		Expect(zeebe.Status.Upgrade.Message).To(Equal("Broker 2 does not report ready"))
+
+		management.notReady = nil
+		management.partitions = map[int32]map[string]PartitionStatus{
+			2: {"1": {Role: "FOLLOWER"}, "2": {Role: "INACTIVE"}},
+		}
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(2)))
+		Expect(zeebe.Status.Upgrade.Message).To(ContainSubstring("Partition 2 on broker 2 is not healthy"))
+
+		management.partitions[2]["2"] = PartitionStatus{Role: "LEADER"}
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(1)))
+	})
+
+	It("halts if a broker does not rejoin in time", func() {
+		upgrade(upgradedStatefulSet(zeebe))
+		setPod(brokerPod(zeebe, 2, updateRevision, false))
+		zeebe.Status.Upgrade.BrokerStartTime = metav1.NewTime(time.Now().Add(-brokerRejoinTimeout - time.Minute))
+
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(2)))
+		Expect(zeebe.Status.Upgrade.Halted).To(BeTrue())
+
+		setZeebeStatus(zeebe, upgradedStatefulSet(zeebe), nil)
+		Expect(zeebe.Status.Phase).To(Equal(camundacloudv1.ZeebePhaseDegraded))
+		degraded := meta.FindStatusCondition(zeebe.Status.Conditions, camundacloudv1.ZeebeConditionDegraded)
+		Expect(degraded.Reason).To(Equal("UpgradeHalted"))
+		Expect(degraded.Message).To(Equal("Upgrade to 8.3.0 halted: Broker 2 is not ready"))
+
+		By("continuing once the broker recovered")
+		setPod(brokerPod(zeebe, 2, updateRevision, true))
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(1)))
+		Expect(zeebe.Status.Upgrade.Halted).To(BeFalse())
+	})
+
+	It("reports the upgrade progress as events", func() {
+		recorder := record.NewFakeRecorder(10)
+		reconciler.Recorder = recorder
+
+		upgrade(upgradedStatefulSet(zeebe))
+		setPod(brokerPod(zeebe, 2, updateRevision, false))
+		zeebe.Status.Upgrade.BrokerStartTime = metav1.NewTime(time.Now().Add(-brokerRejoinTimeout - time.Minute))
+		upgrade(upgradedStatefulSet(zeebe))
+		upgrade(upgradedStatefulSet(zeebe))
+		setPod(brokerPod(zeebe, 2, updateRevision, true))
+		upgrade(upgradedStatefulSet(zeebe))
+
+		Expect(recordedEvents(recorder)).To(Equal([]string{
+			"Normal UpgradeStarted Upgrading brokers from 8.2.5 to 8.3.0, starting with broker 2",
+			"Warning UpgradeHalted Broker 2 did not rejoin the cluster after the upgrade to 8.3.0: Broker 2 is not ready",
+			"Normal BrokerUpgraded Broker 2 rejoined the cluster with 8.3.0, upgrading broker 1",
+		}))
+	})
+
+	It("refuses unsupported upgrade paths", func() {
+		zeebe.Spec.Broker.Backend.ImageTag = "8.4.0"
+		partition, err := reconciler.upgradeBrokers(ctx, zeebe, upgradedStatefulSet(zeebe), 3)
+		Expect(err).To(HaveOccurred())
+		Expect(partition).To(Equal(int32(3)))
+		Expect(zeebe.Status.Upgrade).To(BeNil())
+	})
+
+	It("does not upgrade clusters which were never rolled out", func() {
+		zeebe.Status.Version = ""
+		Expect(upgrade(upgradedStatefulSet(zeebe))).To(Equal(int32(0)))
+		Expect(zeebe.Status.Upgrade).To(BeNil())
+	})
+})
@@ -0,0 +1,219 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// ZeebeBackupReconciler reconciles a ZeebeBackup object
+type ZeebeBackupReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Management ManagementClient
+}
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackups/finalizers,verbs=update
+
+// Reconcile triggers the backup through the management API of the referenced
+// Zeebe cluster and follows its progress until all partitions were backed up
+// or the backup failed. Completed and failed backups are not touched again.
+// Deleting a ZeebeBackup keeps the backup in the backup store.
+func (r *ZeebeBackupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var backup camundacloudv1.ZeebeBackup
+	if err := r.Get(ctx, req.NamespacedName, &backup); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	status := &backup.Status
+	if status.Phase == camundacloudv1.BackupPhaseCompleted || status.Phase == camundacloudv1.BackupPhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	if status.Phase == "" {
+		status.Phase = camundacloudv1.BackupPhasePending
+	}
+	if status.BackupID == 0 {
+		id := backupID(&backup)
+		owner, err := r.backupIDOwner(ctx, &backup, id)
+		if err != nil {
+			logger.Error(err, "unable to list backups of Zeebe", "zeebe", backup.Spec.ZeebeRef)
+			return ctrl.Result{}, err
+		}
+		if owner != "" {
+			failBackup(&backup, fmt.Sprintf("Backup id %d is already used by %s", id, owner))
+			return ctrl.Result{}, r.Status().Update(ctx, &backup)
+		}
+		status.BackupID = id
+	}
+
+	var zeebe camundacloudv1.Zeebe
+	err := r.Get(ctx, client.ObjectKey{Namespace: backup.Namespace, Name: backup.Spec.ZeebeRef}, &zeebe)
+	if errors.IsNotFound(err) {
+		// the cluster might not be created yet
+		status.FailureReason = fmt.Sprintf("Zeebe cluster %s not found", backup.Spec.ZeebeRef)
+		return ctrl.Result{RequeueAfter: requeueInterval}, r.Status().Update(ctx, &backup)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if zeebe.Spec.Broker.Backup == nil {
+		failBackup(&backup, fmt.Sprintf("Zeebe cluster %s has no backup store configured", zeebe.Name))
+		return ctrl.Result{}, r.Status().Update(ctx, &backup)
+	}
+
+	if status.Phase == camundacloudv1.BackupPhasePending {
+		if err := zeebe.ValidateSpec(); err != nil {
+			failBackup(&backup, fmt.Sprintf("Zeebe cluster %s is invalid: %s", zeebe.Name, err))
+			return ctrl.Result{}, r.Status().Update(ctx, &backup)
+		}
+		if err := r.Management.TakeBackup(ctx, &zeebe, status.BackupID); err != nil {
+			if !isRejected(err) {
+				logger.Error(err, "unable to trigger backup", "backup", status.BackupID)
+				return ctrl.Result{}, err
+			}
+			failBackup(&backup, err.Error())
+			return ctrl.Result{}, r.Status().Update(ctx, &backup)
+		}
+
+		logger.Info("triggered backup", "backup", status.BackupID, "zeebe", zeebe.Name)
+		now := metav1.Now()
+		status.Phase = camundacloudv1.BackupPhaseInProgress
+		status.Brokers = *zeebe.Spec.Broker.Backend.Replicas
+		status.StartTime = &now
+		status.FailureReason = ""
+		return ctrl.Result{RequeueAfter: requeueInterval}, r.Status().Update(ctx, &backup)
+	}
+
+	info, err := r.Management.Backup(ctx, &zeebe, status.BackupID)
+	if err != nil {
+		logger.Error(err, "unable to query backup", "backup", status.BackupID)
+		return ctrl.Result{}, err
+	}
+	setBackupStatus(&backup, info)
+
+	switch info.State {
+	case backupCompleted:
+		logger.Info("completed backup", "backup", status.BackupID, "zeebe", zeebe.Name)
+		now := metav1.Now()
+		status.Phase = camundacloudv1.BackupPhaseCompleted
+		status.CompletionTime = &now
+	case backupFailed, backupIncomplete, backupDoesNotExist:
+		reason := info.FailureReason
+		if reason == "" {
+			reason = fmt.Sprintf("Backup is %s", info.State)
+		}
+		failBackup(&backup, reason)
+	}
+
+	if err := r.Status().Update(ctx, &backup); err != nil {
+		return ctrl.Result{}, err
+	}
+	if status.Phase == camundacloudv1.BackupPhaseInProgress {
+		return ctrl.Result{RequeueAfter: requeueInterval}, nil
+	}
+	return ctrl.Result{}, nil
+}
+
+// backupID returns the id of the backup from the spec, or from the creation
+// time so that later backups get larger ids. Backups created within the same
+// second get the same id, see backupIDOwner.
+func backupID(backup *camundacloudv1.ZeebeBackup) int64 {
+	if backup.Spec.BackupID != nil {
+		return *backup.Spec.BackupID
+	}
+	return backup.CreationTimestamp.Unix()
+}
+
+// backupIDOwner returns the name of another backup of the same cluster which
+// holds the given id, if any. Of two backups created within the same second
+// without an id in their spec, the one created first, or named first, keeps it.
+func (r *ZeebeBackupReconciler) backupIDOwner(ctx context.Context, backup *camundacloudv1.ZeebeBackup, id int64) (string, error) {
+	var backupList camundacloudv1.ZeebeBackupList
+	if err := r.List(ctx, &backupList, client.InNamespace(backup.Namespace)); err != nil {
+		return "", err
+	}
+
+	for i := range backupList.Items {
+		other := &backupList.Items[i]
+		if other.Name == backup.Name || other.Spec.ZeebeRef != backup.Spec.ZeebeRef {
+			continue
+		}
+		if other.Status.BackupID != 0 {
+			if other.Status.BackupID == id {
+				return other.Name, nil
+			}
+			continue
+		}
+		// backups rejected for a used id never hold one
+		if other.Status.Phase == camundacloudv1.BackupPhaseFailed || backupID(other) != id {
+			continue
+		}
+		if other.CreationTimestamp.Before(&backup.CreationTimestamp) ||
+			(other.CreationTimestamp.Equal(&backup.CreationTimestamp) && other.Name < backup.Name) {
+			return other.Name, nil
+		}
+	}
+	return "", nil
+}
+
+func failBackup(backup *camundacloudv1.ZeebeBackup, reason string) {
+	now := metav1.Now()
+	backup.Status.Phase = camundacloudv1.BackupPhaseFailed
+	backup.Status.CompletionTime = &now
+	backup.Status.FailureReason = reason
+}
+
+// setBackupStatus records the state of the backup of every partition
+func setBackupStatus(backup *camundacloudv1.ZeebeBackup, info *BackupInfo) {
+	partitions := make([]camundacloudv1.PartitionBackupStatus, 0, len(info.Details))
+	for _, detail := range info.Details {
+		partition := camundacloudv1.PartitionBackupStatus{
+			PartitionID:        detail.PartitionID,
+			State:              detail.State,
+			CheckpointPosition: detail.CheckpointPosition,
+			BrokerVersion:      detail.BrokerVersion,
+			FailureReason:      detail.FailureReason,
+		}
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].PartitionID < partitions[j].PartitionID
+	})
+	backup.Status.Partitions = partitions
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ZeebeBackupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.ZeebeBackup{}).
+		Complete(r)
+}
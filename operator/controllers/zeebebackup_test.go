@@ -0,0 +1,313 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/robfig/cron/v3"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+var _ = Describe("Backup store", func() {
+	It("renders the settings into the broker configuration", func() {
+		config, err := renderBrokerConfig(testZeebeWithBackupStore())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(config).To(ContainSubstring("store: S3"))
+		Expect(config).To(ContainSubstring("bucketName: backups"))
+		Expect(config).NotTo(ContainSubstring("secretKey"))
+	})
+
+	It("passes the credentials through the environment", func() {
+		envs := backupEnv(testZeebeWithBackupStore())
+		Expect(envs).To(HaveLen(2))
+		Expect(findEnv(envs, "ZEEBE_BROKER_DATA_BACKUP_S3_SECRETKEY").ValueFrom.SecretKeyRef.Key).To(Equal("secretKey"))
+	})
+
+	It("mounts the GCS credentials into the brokers", func() {
+		zeebe := testZeebe("cluster-1", 3)
+		zeebe.Spec.Broker.Backup = &camundacloudv1.BackupStoreSpec{
+			Store: camundacloudv1.BackupStoreGCS,
+			GCS: &camundacloudv1.GCSBackupStore{
+				BucketName: "backups",
+				Credentials: &v12.SecretKeySelector{
+					LocalObjectReference: v12.LocalObjectReference{Name: "gcs"},
+					Key:                  "key.json",
+				},
+			},
+		}
+
+		Expect(findEnv(backupEnv(zeebe), "GOOGLE_APPLICATION_CREDENTIALS").Value).To(Equal(gcsCredentialsPath))
+		podSpec := createPodSpecTemplate(zeebe, brokerLabels(zeebe), "hash").Spec
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(v12.VolumeMount{
+			Name:      "backup-credentials",
+			MountPath: gcsCredentialsPath,
+			SubPath:   "key.json",
+			ReadOnly:  true,
+		}))
+	})
+})
+
+var _ = Describe("ZeebeBackup", func() {
+	var (
+		ctx        context.Context
+		zeebe      *camundacloudv1.Zeebe
+		backup     *camundacloudv1.ZeebeBackup
+		management *fakeManagement
+		reconciler *ZeebeBackupReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		zeebe = testZeebeWithBackupStore()
+		backupID := int64(100)
+		backup = &camundacloudv1.ZeebeBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.ZeebeBackupSpec{ZeebeRef: zeebe.Name, BackupID: &backupID},
+		}
+		management = &fakeManagement{}
+		reconciler = &ZeebeBackupReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(testScheme()).WithObjects(zeebe, backup).Build(),
+			Management: management,
+		}
+	})
+
+	reconcile := func() *camundacloudv1.ZeebeBackup {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(backup)})
+		Expect(err).NotTo(HaveOccurred())
+		var updated camundacloudv1.ZeebeBackup
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(backup), &updated)).To(Succeed())
+		return &updated
+	}
+
+	It("follows the backup until all partitions completed", func() {
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.BackupPhaseInProgress))
+		Expect(management.backups).To(HaveKey(int64(100)))
+
+		management.backups[100] = &BackupInfo{
+			BackupID: 100,
+			State:    backupCompleted,
+			Details: []PartitionBackupInfo{
+				{PartitionID: 2, State: backupCompleted},
+				{PartitionID: 1, State: backupCompleted},
+			},
+		}
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.BackupPhaseCompleted))
+		Expect(updated.Status.CompletionTime).NotTo(BeNil())
+		Expect(updated.Status.Partitions).To(HaveLen(2))
+		Expect(updated.Status.Partitions[0].PartitionID).To(Equal(int32(1)))
+	})
+
+	It("rejects a second backup created within the same second", func() {
+		created := metav1.NewTime(time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC))
+		first := &camundacloudv1.ZeebeBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "manual-a", Namespace: zeebe.Namespace, CreationTimestamp: created},
+			Spec:       camundacloudv1.ZeebeBackupSpec{ZeebeRef: zeebe.Name},
+		}
+		second := first.DeepCopy()
+		second.Name = "manual-b"
+		Expect(reconciler.Create(ctx, first)).To(Succeed())
+		Expect(reconciler.Create(ctx, second)).To(Succeed())
+
+		backup = second
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.BackupPhaseFailed))
+		Expect(updated.Status.FailureReason).To(Equal(fmt.Sprintf("Backup id %d is already used by manual-a", created.Unix())))
+
+		backup = first
+		updated = reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.BackupPhaseInProgress))
+		Expect(updated.Status.BackupID).To(Equal(created.Unix()))
+		Expect(management.backups).To(HaveKey(created.Unix()))
+	})
+
+	It("fails when the backup id was already used", func() {
+		management.backups = map[int64]*BackupInfo{200: {BackupID: 200, State: backupCompleted}}
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.BackupPhaseFailed))
+		Expect(updated.Status.FailureReason).To(ContainSubstring("not larger"))
+	})
+
+	It("fails when the cluster has no backup store", func() {
+		zeebe.Spec.Broker.Backup = nil
+		Expect(reconciler.Update(ctx, zeebe)).To(Succeed())
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.BackupPhaseFailed))
+		Expect(management.backups).To(BeEmpty())
+	})
+
+	It("fails when the cluster was not defaulted", func() {
+		zeebe.Spec.Broker.Backend.Replicas = nil
+		Expect(reconciler.Update(ctx, zeebe)).To(Succeed())
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.BackupPhaseFailed))
+		Expect(updated.Status.FailureReason).To(ContainSubstring("Zeebe cluster cluster-1 is invalid"))
+		Expect(management.backups).To(BeEmpty())
+	})
+})
+
+var _ = Describe("ZeebeBackupSchedule", func() {
+	var (
+		ctx        context.Context
+		created    time.Time
+		clock      *fakeClock
+		schedule   *camundacloudv1.ZeebeBackupSchedule
+		management *fakeManagement
+		reconciler *ZeebeBackupScheduleReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		created = time.Date(2021, 11, 1, 10, 30, 0, 0, time.UTC)
+		clock = &fakeClock{now: created}
+		zeebe := testZeebeWithBackupStore()
+		schedule = &camundacloudv1.ZeebeBackupSchedule{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "nightly",
+				Namespace:         zeebe.Namespace,
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			Spec: camundacloudv1.ZeebeBackupScheduleSpec{ZeebeRef: zeebe.Name, Schedule: "0 * * * *"},
+		}
+		management = &fakeManagement{}
+		s := testScheme()
+		reconciler = &ZeebeBackupScheduleReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(s).WithObjects(zeebe, schedule).Build(),
+			Scheme:     s,
+			Management: management,
+			Clock:      clock,
+		}
+	})
+
+	reconcile := func() (ctrl.Result, *camundacloudv1.ZeebeBackupSchedule) {
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(schedule)})
+		Expect(err).NotTo(HaveOccurred())
+		var updated camundacloudv1.ZeebeBackupSchedule
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(schedule), &updated)).To(Succeed())
+		return result, &updated
+	}
+
+	backups := func() []camundacloudv1.ZeebeBackup {
+		var list camundacloudv1.ZeebeBackupList
+		Expect(reconciler.List(ctx, &list)).To(Succeed())
+		return list.Items
+	}
+
+	completeBackups := func() {
+		for _, backup := range backups() {
+			backup.Status.Phase = camundacloudv1.BackupPhaseCompleted
+			backup.Status.BackupID = *backup.Spec.BackupID
+			Expect(reconciler.Status().Update(ctx, &backup)).To(Succeed())
+		}
+	}
+
+	It("returns the latest missed run and the next run", func() {
+		cronSchedule, err := cron.ParseStandard("0 * * * *")
+		Expect(err).NotTo(HaveOccurred())
+
+		missed, next := nextBackupRuns(schedule, cronSchedule, created.Add(150*time.Minute))
+		Expect(missed).To(Equal(time.Date(2021, 11, 1, 13, 0, 0, 0, time.UTC)))
+		Expect(next).To(Equal(time.Date(2021, 11, 1, 14, 0, 0, 0, time.UTC)))
+
+		missed, _ = nextBackupRuns(schedule, cronSchedule, created.Add(10*time.Minute))
+		Expect(missed.IsZero()).To(BeTrue())
+	})
+
+	It("creates a backup when the schedule is due", func() {
+		result, updated := reconcile()
+		Expect(backups()).To(BeEmpty())
+		Expect(result.RequeueAfter).To(Equal(30 * time.Minute))
+		Expect(updated.Status.NextScheduleTime.Time).To(BeTemporally("==", created.Add(30*time.Minute)))
+
+		clock.now = created.Add(31 * time.Minute)
+		_, updated = reconcile()
+		Expect(backups()).To(HaveLen(1))
+		backup := backups()[0]
+		Expect(*backup.Spec.BackupID).To(Equal(created.Add(30 * time.Minute).Unix()))
+		Expect(backup.Labels).To(HaveKeyWithValue(scheduleLabel, "nightly"))
+		Expect(metav1.IsControlledBy(&backup, updated)).To(BeTrue())
+		Expect(updated.Status.Active).To(Equal(backup.Name))
+	})
+
+	It("skips runs while a backup is in progress", func() {
+		clock.now = created.Add(31 * time.Minute)
+		reconcile()
+		clock.now = created.Add(91 * time.Minute)
+		_, updated := reconcile()
+		Expect(backups()).To(HaveLen(1))
+		Expect(updated.Status.LastScheduleTime.Time).To(BeTemporally("==", created.Add(90*time.Minute)))
+	})
+
+	It("does not create backups while suspended", func() {
+		schedule.Spec.Suspend = true
+		Expect(reconciler.Update(ctx, schedule)).To(Succeed())
+
+		clock.now = created.Add(31 * time.Minute)
+		reconcile()
+		Expect(backups()).To(BeEmpty())
+	})
+
+	It("reports an invalid schedule", func() {
+		schedule.Spec.Schedule = "every hour"
+		Expect(reconciler.Update(ctx, schedule)).To(Succeed())
+
+		result, updated := reconcile()
+		Expect(result.RequeueAfter).To(BeZero())
+		Expect(updated.Status.Message).To(ContainSubstring("Invalid schedule"))
+	})
+
+	It("deletes backups beyond the retention", func() {
+		keep := int32(2)
+		schedule.Spec.Retention.KeepLast = &keep
+		Expect(reconciler.Update(ctx, schedule)).To(Succeed())
+
+		for i := 1; i <= 3; i++ {
+			clock.now = created.Add(time.Duration(i)*time.Hour + time.Minute)
+			reconcile()
+			completeBackups()
+		}
+		_, updated := reconcile()
+
+		oldest := created.Add(30 * time.Minute).Unix()
+		Expect(management.deleted).To(Equal([]int64{oldest}))
+		Expect(backups()).To(HaveLen(2))
+		Expect(updated.Status.CompletedBackups).To(HaveLen(2))
+		Expect(updated.Status.CompletedBackups[0].BackupID).To(Equal(created.Add(150 * time.Minute).Unix()))
+	})
+})
@@ -0,0 +1,243 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// scheduleLabel is set on the backups of a schedule to find them again
+const scheduleLabel = "camunda-cloud.io.camunda/backup-schedule"
+
+// Clock knows how to get the current time. It can be used to fake out timing
+// for testing.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// ZeebeBackupScheduleReconciler reconciles a ZeebeBackupSchedule object
+type ZeebeBackupScheduleReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Management ManagementClient
+	Clock
+}
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackupschedules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackupschedules/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeebebackupschedules/finalizers,verbs=update
+
+// Reconcile creates a ZeebeBackup whenever the schedule is due and applies the
+// retention policy to the backups of the schedule. Only one backup of a
+// schedule is in progress at a time, runs which are due meanwhile are skipped.
+func (r *ZeebeBackupScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var schedule camundacloudv1.ZeebeBackupSchedule
+	if err := r.Get(ctx, req.NamespacedName, &schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var backupList camundacloudv1.ZeebeBackupList
+	if err := r.List(ctx, &backupList, client.InNamespace(schedule.Namespace), client.MatchingLabels{scheduleLabel: schedule.Name}); err != nil {
+		logger.Error(err, "unable to list backups of schedule")
+		return ctrl.Result{}, err
+	}
+
+	var active, completed, failed []*camundacloudv1.ZeebeBackup
+	for i := range backupList.Items {
+		backup := &backupList.Items[i]
+		switch backup.Status.Phase {
+		case camundacloudv1.BackupPhaseCompleted:
+			completed = append(completed, backup)
+		case camundacloudv1.BackupPhaseFailed:
+			failed = append(failed, backup)
+		default:
+			active = append(active, backup)
+		}
+	}
+	sortBackups(completed)
+	sortBackups(failed)
+
+	completed, err := r.applyRetention(ctx, &schedule, completed, schedule.Spec.Retention.KeepLast)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if _, err := r.applyRetention(ctx, &schedule, failed, schedule.Spec.Retention.KeepFailed); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	status := &schedule.Status
+	status.Active = ""
+	if len(active) > 0 {
+		status.Active = active[0].Name
+	}
+	status.CompletedBackups = make([]camundacloudv1.CompletedBackup, 0, len(completed))
+	for _, backup := range completed {
+		status.CompletedBackups = append(status.CompletedBackups, camundacloudv1.CompletedBackup{
+			Name:           backup.Name,
+			BackupID:       backup.Status.BackupID,
+			CompletionTime: backup.Status.CompletionTime,
+		})
+	}
+
+	cronSchedule, err := cron.ParseStandard(schedule.Spec.Schedule)
+	if err != nil {
+		// don't bother requeuing until we get a change to the spec
+		status.Message = fmt.Sprintf("Invalid schedule %q: %s", schedule.Spec.Schedule, err)
+		status.NextScheduleTime = nil
+		return ctrl.Result{}, r.Status().Update(ctx, &schedule)
+	}
+	status.Message = ""
+
+	now := r.Now()
+	missedRun, nextRun := nextBackupRuns(&schedule, cronSchedule, now)
+	nextScheduleTime := metav1.NewTime(nextRun)
+	status.NextScheduleTime = &nextScheduleTime
+
+	if !missedRun.IsZero() && !schedule.Spec.Suspend {
+		if status.Active != "" {
+			logger.V(1).Info("skipping backup, previous backup still in progress", "active", status.Active)
+		} else {
+			backup, err := r.createScheduledBackup(ctx, &schedule, missedRun)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			status.Active = backup.Name
+			logger.Info("scheduled backup", "backup", backup.Name)
+		}
+		lastScheduleTime := metav1.NewTime(missedRun)
+		status.LastScheduleTime = &lastScheduleTime
+	}
+
+	if err := r.Status().Update(ctx, &schedule); err != nil {
+		logger.Error(err, "unable to update status of backup schedule")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: nextRun.Sub(now)}, nil
+}
+
+// nextBackupRuns returns the latest run which is due but was not started yet,
+// or the zero time if there is none, and the time of the next run
+func nextBackupRuns(schedule *camundacloudv1.ZeebeBackupSchedule, cronSchedule cron.Schedule, now time.Time) (time.Time, time.Time) {
+	earliest := schedule.CreationTimestamp.Time
+	if schedule.Status.LastScheduleTime != nil {
+		earliest = schedule.Status.LastScheduleTime.Time
+	}
+
+	var missedRun time.Time
+	for run := cronSchedule.Next(earliest); !run.After(now); run = cronSchedule.Next(run) {
+		missedRun = run
+	}
+	return missedRun, cronSchedule.Next(now)
+}
+
+// createScheduledBackup creates the ZeebeBackup for a run of the schedule. The
+// backup id is the time of the run, so backups of a schedule get increasing ids.
+func (r *ZeebeBackupScheduleReconciler) createScheduledBackup(ctx context.Context, schedule *camundacloudv1.ZeebeBackupSchedule, run time.Time) (*camundacloudv1.ZeebeBackup, error) {
+	backupID := run.Unix()
+	backup := &camundacloudv1.ZeebeBackup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", schedule.Name, backupID),
+			Namespace: schedule.Namespace,
+			Labels: map[string]string{
+				scheduleLabel: schedule.Name,
+			},
+		},
+		Spec: camundacloudv1.ZeebeBackupSpec{
+			ZeebeRef: schedule.Spec.ZeebeRef,
+			BackupID: &backupID,
+		},
+	}
+	if err := ctrl.SetControllerReference(schedule, backup, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, backup); err != nil && !errors.IsAlreadyExists(err) {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// applyRetention deletes all but the given number of most recent backups, both
+// from the backup store and as ZeebeBackup, and returns the backups to keep.
+func (r *ZeebeBackupScheduleReconciler) applyRetention(ctx context.Context, schedule *camundacloudv1.ZeebeBackupSchedule, backups []*camundacloudv1.ZeebeBackup, keep *int32) ([]*camundacloudv1.ZeebeBackup, error) {
+	logger := log.FromContext(ctx)
+	if keep == nil || len(backups) <= int(*keep) {
+		return backups, nil
+	}
+
+	var zeebe camundacloudv1.Zeebe
+	if err := r.Get(ctx, client.ObjectKey{Namespace: schedule.Namespace, Name: schedule.Spec.ZeebeRef}, &zeebe); err != nil {
+		// without the cluster the backups cannot be removed from the store
+		logger.Error(err, "unable to fetch Zeebe cluster to delete old backups", "zeebe", schedule.Spec.ZeebeRef)
+		return backups, client.IgnoreNotFound(err)
+	}
+
+	for _, backup := range backups[*keep:] {
+		if backup.Status.BackupID != 0 {
+			err := r.Management.DeleteBackup(ctx, &zeebe, backup.Status.BackupID)
+			if err != nil && !isNotFound(err) {
+				logger.Error(err, "unable to delete backup from the backup store", "backup", backup.Status.BackupID)
+				return backups, err
+			}
+		}
+		if err := r.Delete(ctx, backup); client.IgnoreNotFound(err) != nil {
+			return backups, err
+		}
+		logger.Info("deleted old backup", "backup", backup.Name)
+	}
+	return backups[:*keep], nil
+}
+
+// sortBackups sorts backups by id, the most recent first
+func sortBackups(backups []*camundacloudv1.ZeebeBackup) {
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Status.BackupID > backups[j].Status.BackupID
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ZeebeBackupScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Clock == nil {
+		r.Clock = realClock{}
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.ZeebeBackupSchedule{}).
+		Owns(&camundacloudv1.ZeebeBackup{}).
+		Complete(r)
+}
@@ -0,0 +1,402 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+// restoreLabel is set on the restore Jobs to find the ZeebeRestore they belong to
+const restoreLabel = "camunda-cloud.io.camunda/restore"
+
+// restoreScript empties the data volume of a broker, which the restore requires,
+// and restores the partitions of the broker from the backup with the given id
+const restoreScript = "" +
+	"set -eux -o pipefail\n" +
+	"find /usr/local/zeebe/data -mindepth 1 -delete\n" +
+	"exec /usr/local/zeebe/bin/restore --backupId=%d"
+
+// ZeebeRestoreReconciler reconciles a ZeebeRestore object
+type ZeebeRestoreReconciler struct {
+	client.Client
+	Scheme     *runtime.Scheme
+	Management ManagementClient
+}
+
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeeberestores,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeeberestores/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=camunda-cloud.io.camunda,resources=zeeberestores/finalizers,verbs=update
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch
+
+// Reconcile restores the brokers of a Zeebe cluster from a backup. Once the
+// backup was checked against the cluster, the Zeebe controller stops all
+// brokers, a Job per broker restores its data volume, and the Zeebe controller
+// bootstraps the cluster again. The Jobs are kept until the ZeebeRestore is
+// deleted, so their logs can be inspected.
+func (r *ZeebeRestoreReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var restore camundacloudv1.ZeebeRestore
+	if err := r.Get(ctx, req.NamespacedName, &restore); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	status := &restore.Status
+	if status.Phase == camundacloudv1.RestorePhaseCompleted || status.Phase == camundacloudv1.RestorePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	if status.Phase == "" {
+		status.Phase = camundacloudv1.RestorePhasePending
+	}
+
+	var zeebe camundacloudv1.Zeebe
+	err := r.Get(ctx, client.ObjectKey{Namespace: restore.Namespace, Name: restore.Spec.ZeebeRef}, &zeebe)
+	if errors.IsNotFound(err) {
+		if status.Phase != camundacloudv1.RestorePhasePending {
+			failRestore(&restore, fmt.Sprintf("Zeebe cluster %s was deleted", restore.Spec.ZeebeRef))
+			return ctrl.Result{}, r.Status().Update(ctx, &restore)
+		}
+		status.FailureReason = fmt.Sprintf("Zeebe cluster %s not found", restore.Spec.ZeebeRef)
+		return ctrl.Result{RequeueAfter: requeueInterval}, r.Status().Update(ctx, &restore)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	switch status.Phase {
+	case camundacloudv1.RestorePhasePending:
+		if err := r.checkBackup(ctx, &restore, &zeebe); err != nil {
+			return ctrl.Result{}, err
+		}
+		if status.Phase == camundacloudv1.RestorePhaseStoppingBrokers {
+			logger.Info("stopping brokers to restore backup", "backup", restore.Spec.BackupID, "zeebe", zeebe.Name)
+		}
+
+	case camundacloudv1.RestorePhaseStoppingBrokers:
+		stopped, err := r.brokersStopped(ctx, &zeebe)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if stopped {
+			status.Phase = camundacloudv1.RestorePhaseRestoring
+		}
+
+	case camundacloudv1.RestorePhaseRestoring:
+		if err := r.restoreBrokers(ctx, &restore, &zeebe); err != nil {
+			return ctrl.Result{}, err
+		}
+		if status.Phase == camundacloudv1.RestorePhaseStartingBrokers {
+			logger.Info("restored brokers, starting them again", "backup", restore.Spec.BackupID, "zeebe", zeebe.Name)
+		}
+
+	case camundacloudv1.RestorePhaseStartingBrokers:
+		var statefulSet v1.StatefulSet
+		if err := r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerName(&zeebe)}, &statefulSet); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		if statefulSet.Status.ReadyReplicas >= status.Brokers {
+			logger.Info("completed restore", "backup", restore.Spec.BackupID, "zeebe", zeebe.Name)
+			now := metav1.Now()
+			status.Phase = camundacloudv1.RestorePhaseCompleted
+			status.CompletionTime = &now
+		}
+	}
+
+	if err := r.Status().Update(ctx, &restore); err != nil {
+		logger.Error(err, "unable to update status of restore")
+		return ctrl.Result{}, err
+	}
+	if status.Phase == camundacloudv1.RestorePhaseCompleted || status.Phase == camundacloudv1.RestorePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: requeueInterval}, nil
+}
+
+// checkBackup refuses to restore a backup which was taken with a different
+// partition count or number of brokers than the cluster is configured with,
+// since the brokers would not find the partitions they own. The partitions are
+// taken from the ZeebeBackup which took the backup if there is one, otherwise
+// from the backup store through the management API of the cluster.
+func (r *ZeebeRestoreReconciler) checkBackup(ctx context.Context, restore *camundacloudv1.ZeebeRestore, zeebe *camundacloudv1.Zeebe) error {
+	status := &restore.Status
+	backupID := restore.Spec.BackupID
+
+	if zeebe.Spec.Broker.Backup == nil {
+		failRestore(restore, fmt.Sprintf("Zeebe cluster %s has no backup store configured", zeebe.Name))
+		return nil
+	}
+	if err := zeebe.ValidateSpec(); err != nil {
+		failRestore(restore, fmt.Sprintf("Zeebe cluster %s is invalid: %s", zeebe.Name, err))
+		return nil
+	}
+	if zeebe.Status.Scaling != nil || zeebe.Status.Upgrade != nil {
+		status.FailureReason = fmt.Sprintf("Waiting for Zeebe cluster %s to finish scaling or upgrading", zeebe.Name)
+		return nil
+	}
+
+	var restoreList camundacloudv1.ZeebeRestoreList
+	if err := r.List(ctx, &restoreList, client.InNamespace(restore.Namespace)); err != nil {
+		return err
+	}
+	for _, other := range restoreList.Items {
+		if other.Name == restore.Name || other.Spec.ZeebeRef != zeebe.Name {
+			continue
+		}
+		if other.Status.Phase == camundacloudv1.RestorePhaseFailed && other.Active() {
+			status.FailureReason = fmt.Sprintf("Waiting for the failed restore %s to be deleted", other.Name)
+			return nil
+		}
+		if other.Active() || other.Status.Phase == camundacloudv1.RestorePhaseStartingBrokers {
+			status.FailureReason = fmt.Sprintf("Waiting for restore %s to complete", other.Name)
+			return nil
+		}
+	}
+
+	partitions, brokers, err := r.backupTopology(ctx, zeebe, backupID)
+	if err != nil {
+		if isRejected(err) {
+			failRestore(restore, err.Error())
+			return nil
+		}
+		return err
+	}
+
+	desiredBrokers := *zeebe.Spec.Broker.Backend.Replicas
+	partitionCount := *zeebe.Spec.Broker.Partitions.Count
+	switch {
+	case partitions != partitionCount:
+		failRestore(restore, fmt.Sprintf("Backup %d has %d partitions, but the cluster has %d", backupID, partitions, partitionCount))
+	case brokers != 0 && brokers != desiredBrokers:
+		failRestore(restore, fmt.Sprintf("Backup %d was taken with %d brokers, but the cluster has %d", backupID, brokers, desiredBrokers))
+	default:
+		now := metav1.Now()
+		status.Phase = camundacloudv1.RestorePhaseStoppingBrokers
+		status.Brokers = desiredBrokers
+		status.StartTime = &now
+		status.FailureReason = ""
+	}
+	return nil
+}
+
+// backupTopology returns the partition count of a completed backup and the
+// number of brokers it was taken with, or 0 if the number of brokers is not
+// known. It fails with a rejected error if the backup did not complete.
+func (r *ZeebeRestoreReconciler) backupTopology(ctx context.Context, zeebe *camundacloudv1.Zeebe, backupID int64) (int32, int32, error) {
+	var backupList camundacloudv1.ZeebeBackupList
+	if err := r.List(ctx, &backupList, client.InNamespace(zeebe.Namespace)); err != nil {
+		return 0, 0, err
+	}
+	for _, backup := range backupList.Items {
+		if backup.Spec.ZeebeRef == zeebe.Name && backup.Status.BackupID == backupID &&
+			backup.Status.Phase == camundacloudv1.BackupPhaseCompleted {
+			return int32(len(backup.Status.Partitions)), backup.Status.Brokers, nil
+		}
+	}
+
+	info, err := r.Management.Backup(ctx, zeebe, backupID)
+	if err != nil {
+		return 0, 0, err
+	}
+	if info.State != backupCompleted {
+		return 0, 0, &managementError{statusCode: 409, message: fmt.Sprintf("Backup %d is %s", backupID, info.State)}
+	}
+	return int32(len(info.Details)), 0, nil
+}
+
+// brokersStopped returns whether the Zeebe controller scaled the brokers down
+// for the restore and all broker pods are gone
+func (r *ZeebeRestoreReconciler) brokersStopped(ctx context.Context, zeebe *camundacloudv1.Zeebe) (bool, error) {
+	var statefulSet v1.StatefulSet
+	err := r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerName(zeebe)}, &statefulSet)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return statefulSet.Spec.Replicas != nil && *statefulSet.Spec.Replicas == 0 && statefulSet.Status.Replicas == 0, nil
+}
+
+// restoreBrokers runs a restore Job against the data volume of every broker
+// and advances the restore once all of them succeeded. A single failed Job or a
+// missing data volume fails the restore, the brokers are not started again in
+// that case.
+func (r *ZeebeRestoreReconciler) restoreBrokers(ctx context.Context, restore *camundacloudv1.ZeebeRestore, zeebe *camundacloudv1.Zeebe) error {
+	logger := log.FromContext(ctx)
+	status := &restore.Status
+
+	var restored int32
+	for nodeID := int32(0); nodeID < status.Brokers; nodeID++ {
+		var job batchv1.Job
+		err := r.Get(ctx, client.ObjectKey{Namespace: restore.Namespace, Name: restoreJobName(restore, nodeID)}, &job)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+
+		if errors.IsNotFound(err) {
+			claim := brokerVolumeName(zeebe, nodeID)
+			if err := r.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: claim}, &v12.PersistentVolumeClaim{}); err != nil {
+				if errors.IsNotFound(err) {
+					failRestore(restore, fmt.Sprintf("Broker %d has no data volume %s", nodeID, claim))
+					return nil
+				}
+				return err
+			}
+
+			restoreJob := createRestoreJob(zeebe, restore, nodeID)
+			if err := ctrl.SetControllerReference(restore, restoreJob, r.Scheme); err != nil {
+				return err
+			}
+			if err := r.Create(ctx, restoreJob); err != nil && !errors.IsAlreadyExists(err) {
+				logger.Error(err, "unable to create restore job", "job", restoreJob.Name)
+				return err
+			}
+			logger.Info("restoring broker", "broker", nodeID, "job", restoreJob.Name)
+			continue
+		}
+
+		switch {
+		case job.Status.Succeeded > 0:
+			restored++
+		case job.Status.Failed > 0:
+			failRestore(restore, fmt.Sprintf("Restoring broker %d failed, see the logs of job %s", nodeID, job.Name))
+			return nil
+		}
+	}
+
+	status.RestoredBrokers = restored
+	if restored == status.Brokers {
+		status.Phase = camundacloudv1.RestorePhaseStartingBrokers
+	}
+	return nil
+}
+
+// createRestoreJob returns the Job which restores the data volume of a broker.
+// It runs the broker image with the configuration of the broker, so the
+// restore finds the backup store and knows which partitions the broker owns.
+func createRestoreJob(zeebe *camundacloudv1.Zeebe, restore *camundacloudv1.ZeebeRestore, nodeID int32) *batchv1.Job {
+	labels := restoreLabels(zeebe, restore)
+	template := createPodSpecTemplate(zeebe, labels, "")
+	template.Annotations = nil
+	// the restore does not load exporters
+	template.Spec.InitContainers = nil
+	template.Spec.RestartPolicy = v12.RestartPolicyNever
+	template.Spec.Volumes = append(template.Spec.Volumes, v12.Volume{
+		Name: dataVolumeName,
+		VolumeSource: v12.VolumeSource{
+			PersistentVolumeClaim: &v12.PersistentVolumeClaimVolumeSource{
+				ClaimName: brokerVolumeName(zeebe, nodeID),
+			},
+		},
+	})
+
+	container := &template.Spec.Containers[0]
+	container.Command = []string{"bash", "-c", fmt.Sprintf(restoreScript, restore.Spec.BackupID)}
+	container.Ports = nil
+	container.ReadinessProbe = nil
+	for i := range container.Env {
+		// the cluster is bootstrapped again with all brokers after the restore
+		if container.Env[i].Name == "ZEEBE_BROKER_CLUSTER_CLUSTERSIZE" {
+			container.Env[i].Value = fmt.Sprintf("%d", restore.Status.Brokers)
+		}
+	}
+	container.Env = append(container.Env, v12.EnvVar{
+		Name:  "ZEEBE_BROKER_CLUSTER_NODEID",
+		Value: fmt.Sprintf("%d", nodeID),
+	})
+
+	backoffLimit := int32(0)
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      restoreJobName(restore, nodeID),
+			Namespace: restore.Namespace,
+			Labels:    labels,
+		},
+		Spec: batchv1.JobSpec{
+			// the data volume was emptied, so retrying cannot do better
+			BackoffLimit: &backoffLimit,
+			Template:     template,
+		},
+	}
+}
+
+func restoreJobName(restore *camundacloudv1.ZeebeRestore, nodeID int32) string {
+	return fmt.Sprintf("%s-%d", restore.Name, nodeID)
+}
+
+// brokerVolumeName returns the name of the data volume claim the broker
+// StatefulSet creates for the broker with the given node id
+func brokerVolumeName(zeebe *camundacloudv1.Zeebe, nodeID int32) string {
+	return fmt.Sprintf("%s-%s", dataVolumeName, brokerPodName(zeebe, nodeID))
+}
+
+func restoreLabels(zeebe *camundacloudv1.Zeebe, restore *camundacloudv1.ZeebeRestore) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/managed-by": "Operator",
+		"app.kubernetes.io/name":       "zeebe-cluster",
+		"app.kubernetes.io/instance":   zeebe.Name,
+		"app.kubernetes.io/app":        app_name,
+		"app.kubernetes.io/component":  "restore",
+		"app":                          app_name,
+		restoreLabel:                   restore.Name,
+	}
+}
+
+func failRestore(restore *camundacloudv1.ZeebeRestore, reason string) {
+	now := metav1.Now()
+	restore.Status.Phase = camundacloudv1.RestorePhaseFailed
+	restore.Status.CompletionTime = &now
+	restore.Status.FailureReason = reason
+}
+
+// activeRestore returns the restore which holds the brokers of the cluster
+// stopped, if any
+func activeRestore(ctx context.Context, c client.Reader, zeebe *camundacloudv1.Zeebe) (*camundacloudv1.ZeebeRestore, error) {
+	var restoreList camundacloudv1.ZeebeRestoreList
+	if err := c.List(ctx, &restoreList, client.InNamespace(zeebe.Namespace)); err != nil {
+		return nil, err
+	}
+	for i := range restoreList.Items {
+		restore := &restoreList.Items[i]
+		if restore.Spec.ZeebeRef == zeebe.Name && restore.Active() {
+			return restore, nil
+		}
+	}
+	return nil, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ZeebeRestoreReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&camundacloudv1.ZeebeRestore{}).
+		Owns(&batchv1.Job{}).
+		Complete(r)
+}
@@ -0,0 +1,287 @@
+/*
+Copyright 2021.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	camundacloudv1 "io.camnda/operator/api/v1"
+)
+
+var _ = Describe("ZeebeRestore", func() {
+	var (
+		ctx        context.Context
+		zeebe      *camundacloudv1.Zeebe
+		restore    *camundacloudv1.ZeebeRestore
+		management *fakeManagement
+		reconciler *ZeebeRestoreReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		zeebe = testZeebeWithBackupStore()
+		zeebe.Status.InitialClusterSize = 3
+		restore = &camundacloudv1.ZeebeRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.ZeebeRestoreSpec{ZeebeRef: zeebe.Name, BackupID: 100},
+		}
+		management = &fakeManagement{backups: map[int64]*BackupInfo{
+			100: {BackupID: 100, State: backupCompleted, Details: []PartitionBackupInfo{
+				{PartitionID: 1}, {PartitionID: 2}, {PartitionID: 3},
+			}},
+		}}
+
+		objects := []client.Object{zeebe, restore}
+		for nodeID := int32(0); nodeID < 3; nodeID++ {
+			objects = append(objects, &v12.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: brokerVolumeName(zeebe, nodeID), Namespace: zeebe.Namespace},
+			})
+		}
+		statefulSet := brokerStatefulSet(3, 3)
+		statefulSet.ObjectMeta = metav1.ObjectMeta{Name: brokerName(zeebe), Namespace: zeebe.Namespace}
+		objects = append(objects, statefulSet)
+
+		s := testScheme()
+		reconciler = &ZeebeRestoreReconciler{
+			Client:     fake.NewClientBuilder().WithScheme(s).WithObjects(objects...).Build(),
+			Scheme:     s,
+			Management: management,
+		}
+	})
+
+	reconcile := func() *camundacloudv1.ZeebeRestore {
+		_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(restore)})
+		Expect(err).NotTo(HaveOccurred())
+		var updated camundacloudv1.ZeebeRestore
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(restore), &updated)).To(Succeed())
+		return &updated
+	}
+
+	setBrokers := func(replicas, ready int32) {
+		var statefulSet v1.StatefulSet
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerName(zeebe)}, &statefulSet)).To(Succeed())
+		statefulSet.Spec.Replicas = &replicas
+		statefulSet.Status.Replicas = replicas
+		statefulSet.Status.ReadyReplicas = ready
+		Expect(reconciler.Update(ctx, &statefulSet)).To(Succeed())
+	}
+
+	finishJob := func(nodeID int32, succeeded bool) {
+		var job batchv1.Job
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: restoreJobName(restore, nodeID)}, &job)).To(Succeed())
+		if succeeded {
+			job.Status.Succeeded = 1
+		} else {
+			job.Status.Failed = 1
+		}
+		Expect(reconciler.Update(ctx, &job)).To(Succeed())
+	}
+
+	It("stops the brokers, restores their data and starts them again", func() {
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseStoppingBrokers))
+		Expect(updated.Status.Brokers).To(Equal(int32(3)))
+
+		active, err := activeRestore(ctx, reconciler, zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active.Name).To(Equal(restore.Name))
+
+		By("waiting for the brokers to stop")
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.RestorePhaseStoppingBrokers))
+		setBrokers(0, 0)
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.RestorePhaseRestoring))
+
+		By("restoring every broker")
+		Expect(reconcile().Status.RestoredBrokers).To(BeZero())
+		finishJob(0, true)
+		finishJob(1, true)
+		Expect(reconcile().Status.RestoredBrokers).To(Equal(int32(2)))
+		finishJob(2, true)
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.RestorePhaseStartingBrokers))
+
+		active, err = activeRestore(ctx, reconciler, zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeNil())
+
+		By("waiting for the brokers to start")
+		setBrokers(3, 2)
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.RestorePhaseStartingBrokers))
+		setBrokers(3, 3)
+		updated = reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseCompleted))
+		Expect(updated.Status.CompletionTime).NotTo(BeNil())
+	})
+
+	It("restores the data volume of a broker with its node id", func() {
+		restore.Status.Brokers = 3
+		job := createRestoreJob(zeebe, restore, 2)
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.Volumes).To(ContainElement(v12.Volume{
+			Name: dataVolumeName,
+			VolumeSource: v12.VolumeSource{
+				PersistentVolumeClaim: &v12.PersistentVolumeClaimVolumeSource{ClaimName: "data-cluster-1-broker-2"},
+			},
+		}))
+		container := podSpec.Containers[0]
+		Expect(container.Command[2]).To(ContainSubstring("restore --backupId=100"))
+		Expect(findEnv(container.Env, "ZEEBE_BROKER_CLUSTER_NODEID").Value).To(Equal("2"))
+		Expect(findEnv(container.Env, "ZEEBE_BROKER_DATA_BACKUP_S3_ACCESSKEY")).NotTo(BeNil())
+		Expect(job.Labels).NotTo(Equal(brokerLabels(zeebe)))
+	})
+
+	It("fails when restoring a broker fails", func() {
+		reconcile()
+		setBrokers(0, 0)
+		reconcile()
+		reconcile()
+		finishJob(1, false)
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseFailed))
+		Expect(updated.Status.FailureReason).To(ContainSubstring("Restoring broker 1 failed"))
+
+		By("keeping the brokers stopped until the restore is deleted")
+		active, err := activeRestore(ctx, reconciler, zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).NotTo(BeNil())
+		Expect(active.Name).To(Equal(restore.Name))
+
+		retry := &camundacloudv1.ZeebeRestore{
+			ObjectMeta: metav1.ObjectMeta{Name: "restore-2", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.ZeebeRestoreSpec{ZeebeRef: zeebe.Name, BackupID: 100},
+		}
+		Expect(reconciler.Create(ctx, retry)).To(Succeed())
+		_, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(retry)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(retry), retry)).To(Succeed())
+		Expect(retry.Status.Phase).To(Equal(camundacloudv1.RestorePhasePending))
+		Expect(retry.Status.FailureReason).To(Equal("Waiting for the failed restore restore-1 to be deleted"))
+
+		Expect(reconciler.Delete(ctx, updated)).To(Succeed())
+		active, err = activeRestore(ctx, reconciler, zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeNil())
+	})
+
+	It("does not hold the brokers if the backup is refused", func() {
+		management.backups[100].State = backupFailed
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.RestorePhaseFailed))
+
+		active, err := activeRestore(ctx, reconciler, zeebe)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(active).To(BeNil())
+	})
+
+	It("refuses a backup with a different partition count", func() {
+		management.backups[100].Details = management.backups[100].Details[:2]
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseFailed))
+		Expect(updated.Status.FailureReason).To(Equal("Backup 100 has 2 partitions, but the cluster has 3"))
+	})
+
+	It("refuses a backup taken with a different number of brokers", func() {
+		Expect(reconciler.Create(ctx, &camundacloudv1.ZeebeBackup{
+			ObjectMeta: metav1.ObjectMeta{Name: "backup-1", Namespace: zeebe.Namespace},
+			Spec:       camundacloudv1.ZeebeBackupSpec{ZeebeRef: zeebe.Name},
+			Status: camundacloudv1.ZeebeBackupStatus{
+				BackupID: 100,
+				Brokers:  5,
+				Phase:    camundacloudv1.BackupPhaseCompleted,
+				Partitions: []camundacloudv1.PartitionBackupStatus{
+					{PartitionID: 1}, {PartitionID: 2}, {PartitionID: 3},
+				},
+			},
+		})).To(Succeed())
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseFailed))
+		Expect(updated.Status.FailureReason).To(Equal("Backup 100 was taken with 5 brokers, but the cluster has 3"))
+	})
+
+	It("refuses to restore a cluster which was not defaulted", func() {
+		zeebe.Spec.Broker.Partitions.Count = nil
+		Expect(reconciler.Update(ctx, zeebe)).To(Succeed())
+
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseFailed))
+		Expect(updated.Status.FailureReason).To(ContainSubstring("Zeebe cluster cluster-1 is invalid"))
+		Expect(updated.Status.Brokers).To(BeZero())
+	})
+
+	It("refuses a backup which did not complete", func() {
+		management.backups[100].State = backupFailed
+
+		Expect(reconcile().Status.Phase).To(Equal(camundacloudv1.RestorePhaseFailed))
+	})
+
+	It("bootstraps the cluster again after the brokers were stopped", func() {
+		replicas, err := (&ZeebeReconciler{}).scaleBrokers(ctx, zeebe, brokerStatefulSet(0, 0))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replicas).To(Equal(int32(3)))
+		Expect(zeebe.Status.Scaling).To(BeNil())
+	})
+
+	It("stops the brokers of a running cluster once the restore is created", func() {
+		Expect(reconciler.Delete(ctx, restore)).To(Succeed())
+		zeebe.Status.Phase = camundacloudv1.ZeebePhaseRunning
+		Expect(reconciler.Status().Update(ctx, zeebe)).To(Succeed())
+		zeebeReconciler := &ZeebeReconciler{
+			Client:     &upsertClient{Client: reconciler.Client},
+			Scheme:     reconciler.Scheme,
+			Management: management,
+		}
+
+		Expect(reconciler.Create(ctx, &v12.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "s3", Namespace: zeebe.Namespace},
+			Data:       map[string][]byte{"accessKey": []byte("key"), "secretKey": []byte("secret")},
+		})).To(Succeed())
+
+		restore = restore.DeepCopy()
+		restore.ResourceVersion = ""
+		Expect(reconciler.Create(ctx, restore)).To(Succeed())
+		updated := reconcile()
+		Expect(updated.Status.Phase).To(Equal(camundacloudv1.RestorePhaseStoppingBrokers))
+		requests := zeebeReconciler.zeebeForRestore(updated)
+		Expect(requests).To(ConsistOf(ctrl.Request{NamespacedName: client.ObjectKeyFromObject(zeebe)}))
+
+		_, err := zeebeReconciler.Reconcile(ctx, requests[0])
+		Expect(err).NotTo(HaveOccurred())
+		var statefulSet v1.StatefulSet
+		Expect(reconciler.Get(ctx, client.ObjectKey{Namespace: zeebe.Namespace, Name: brokerName(zeebe)}, &statefulSet)).To(Succeed())
+		Expect(*statefulSet.Spec.Replicas).To(BeZero())
+		Expect(reconciler.Get(ctx, client.ObjectKeyFromObject(zeebe), zeebe)).To(Succeed())
+		Expect(zeebe.Status.Restore).To(Equal(restore.Name))
+	})
+
+	It("reports the restore in the status of the cluster", func() {
+		zeebe.Status.Restore = restore.Name
+		setZeebeStatus(zeebe, brokerStatefulSet(0, 0), nil)
+		Expect(zeebe.Status.Phase).To(Equal(camundacloudv1.ZeebePhaseRestoring))
+	})
+})